@@ -1,30 +1,184 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/guicybercode/systui/internal/api"
+	"github.com/guicybercode/systui/internal/config"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
 	"github.com/guicybercode/systui/internal/tui"
+	"github.com/guicybercode/systui/internal/tui/fleet"
 )
 
+// flagKeys maps command-line flags to the config settings they override.
+var flagKeys = map[string]string{
+	"port":              "server.port",
+	"bind":              "server.bind",
+	"unix-socket":       "server.unix_socket",
+	"socket-mode":       "server.socket_mode",
+	"tls-cert":          "server.tls_cert",
+	"tls-key":           "server.tls_key",
+	"tls-client-ca":     "server.client_ca",
+	"token-file":        "server.token_file",
+	"allow-units":       "server.allow_units",
+	"allow-signals":     "server.allow_signals",
+	"remote":            "remote.url",
+	"remote-token-file": "remote.token_file",
+	"remote-ca":         "remote.ca",
+	"remote-cert":       "remote.cert",
+	"remote-key":        "remote.key",
+}
+
 func main() {
+	defaults := config.Default()
+
+	var configPath = flag.String("config", "", "Config file (default $SYSTUI_CONFIG or the first systui/config.{toml,yaml,yml} in the XDG config directories)")
 	var headless = flag.Bool("headless", false, "Run in headless API mode")
-	var port = flag.Int("port", 8080, "API server port")
+	flag.Int("port", defaults.Server.Port, "API server port")
+	flag.String("bind", "", "API server bind address (default all interfaces)")
+	flag.String("unix-socket", "", "Serve the API on a Unix domain socket instead of TCP")
+	flag.String("socket-mode", defaults.Server.SocketMode, "File permissions of the Unix domain socket")
+	flag.String("tls-cert", "", "TLS certificate file for the API server")
+	flag.String("tls-key", "", "TLS private key file for the API server")
+	flag.String("tls-client-ca", "", "CA bundle used to require and verify client certificates (mTLS)")
+	flag.String("token-file", "", "File with accepted API bearer tokens, one \"<token> [viewer|operator|admin]\" per line")
+	flag.String("allow-units", "", "Comma-separated unit name patterns the API may control")
+	flag.String("allow-signals", strings.Join(defaults.Server.AllowSignals, ","), "Comma-separated signals the API may send")
+	flag.String("remote", "", "Read from a systui headless server at this URL instead of the local machine")
+	flag.String("remote-token-file", "", "File containing the bearer token for -remote (or set SYSTUI_REMOTE_TOKEN)")
+	flag.String("remote-ca", "", "CA bundle used to verify the -remote server")
+	flag.String("remote-cert", "", "Client certificate for -remote mTLS")
+	flag.String("remote-key", "", "Client key for -remote mTLS")
 	flag.Parse()
 
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			if err := cfg.Set(key, f.Value.String()); err != nil {
+				log.Fatalf("-%s: %v", f.Name, err)
+			}
+		}
+	})
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	endpoints := make([]fleet.Endpoint, 0, len(cfg.Fleet))
+	for _, host := range cfg.Fleet {
+		token, err := readToken(host.Token, host.TokenFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		endpoint := fleet.Endpoint{Name: host.Name}
+		endpoint.RemoteOptions = datasource.RemoteOptions{
+			URL:      host.URL,
+			Token:    token,
+			CAFile:   host.CA,
+			CertFile: host.Cert,
+			KeyFile:  host.Key,
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	if cfg.Remote.URL != "" {
+		token, err := readToken(cfg.Remote.Token, cfg.Remote.TokenFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		source, err := datasource.NewRemote(datasource.RemoteOptions{
+			URL:      cfg.Remote.URL,
+			Token:    token,
+			CAFile:   cfg.Remote.CA,
+			CertFile: cfg.Remote.Cert,
+			KeyFile:  cfg.Remote.Key,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		source.Start(ctx)
+		runTUI(source, cfg, endpoints)
+		return
+	}
+
+	collector := system.NewCollector(cfg.CollectorConfig())
+	collector.Start(ctx)
+
 	if *headless {
-		server := api.NewServer(*port)
+		mode, _ := cfg.Server.Mode()
+
+		var tokens []api.Token
+		if cfg.Server.TokenFile != "" {
+			tokens, err = api.LoadTokens(cfg.Server.TokenFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if env := os.Getenv("SYSTUI_API_TOKEN"); env != "" {
+			token, err := api.ParseToken(env)
+			if err != nil {
+				log.Fatalf("invalid SYSTUI_API_TOKEN: %v", err)
+			}
+			tokens = append(tokens, token)
+		}
+
+		signals := []syscall.Signal{}
+		for _, name := range cfg.Server.AllowSignals {
+			sig, _ := system.ParseSignal(name)
+			signals = append(signals, sig)
+		}
+
+		server := api.NewServer(api.Options{
+			Address:    cfg.Server.Bind,
+			Port:       cfg.Server.Port,
+			UnixSocket: cfg.Server.UnixSocket,
+			SocketMode: os.FileMode(mode),
+			TLSCert:    cfg.Server.TLSCert,
+			TLSKey:     cfg.Server.TLSKey,
+			ClientCA:   cfg.Server.ClientCA,
+			Tokens:     tokens,
+
+			AllowedUnits:   cfg.Server.AllowUnits,
+			AllowedSignals: signals,
+			LogSources:     cfg.Logs.Sources,
+		}, collector)
 		if err := server.Start(); err != nil {
 			log.Fatal(err)
 		}
 		return
 	}
 
-	if _, err := tea.NewProgram(tui.NewApp(), tea.WithAltScreen()).Run(); err != nil {
+	runTUI(datasource.NewLocal(collector), cfg, endpoints)
+}
+
+// readToken returns token, or the trimmed contents of file when it is set.
+func readToken(token, file string) (string, error) {
+	if file == "" {
+		return token, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func runTUI(source datasource.Source, cfg *config.Config, endpoints []fleet.Endpoint) {
+	if _, err := tea.NewProgram(tui.NewApp(source, cfg, endpoints), tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
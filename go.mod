@@ -1,13 +1,19 @@
 module github.com/guicybercode/systui
 
-go 1.21
+go 1.22
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/charmbracelet/bubbletea v0.25.0
 	github.com/charmbracelet/lipgloss v0.9.1
 	github.com/coreos/go-systemd/v22 v22.5.0
+	github.com/gorilla/websocket v1.5.0
+	github.com/klauspost/compress v1.18.0
+	github.com/muesli/termenv v0.15.2
 	github.com/shirou/gopsutil/v3 v3.23.12
 	github.com/tetratelabs/wazero v1.6.0
+	golang.org/x/sys v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -23,7 +29,6 @@ require (
 	github.com/muesli/ansi v0.0.0-20211018074035-2e021307bc4b // indirect
 	github.com/muesli/cancelreader v0.2.2 // indirect
 	github.com/muesli/reflow v0.3.0 // indirect
-	github.com/muesli/termenv v0.15.2 // indirect
 	github.com/power-devops/perfstat v0.0.0-20210106213030-5aafc221ea8c // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/shoenig/go-m1cpu v0.1.6 // indirect
@@ -31,7 +36,6 @@ require (
 	github.com/tklauser/numcpus v0.6.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.3 // indirect
 	golang.org/x/sync v0.1.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
 	golang.org/x/term v0.6.0 // indirect
 	golang.org/x/text v0.3.8 // indirect
 )
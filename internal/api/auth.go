@@ -0,0 +1,211 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+func ParseRole(name string) (Role, error) {
+	switch strings.ToLower(name) {
+	case "viewer":
+		return RoleViewer, nil
+	case "operator":
+		return RoleOperator, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("unknown role %q", name)
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleOperator:
+		return "operator"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+type Token struct {
+	Value string
+	Role  Role
+}
+
+// ParseToken parses "<token> [role]". Tokens without a role are viewers.
+func ParseToken(line string) (Token, error) {
+	fields := strings.Fields(line)
+	switch len(fields) {
+	case 1:
+		return Token{Value: fields[0], Role: RoleViewer}, nil
+	case 2:
+		role, err := ParseRole(fields[1])
+		if err != nil {
+			return Token{}, err
+		}
+		return Token{Value: fields[0], Role: role}, nil
+	}
+	return Token{}, errors.New("expected \"<token> [role]\"")
+}
+
+// LoadTokens reads bearer tokens from path, one "<token> [role]" per line.
+// Blank lines and lines starting with # are ignored.
+func LoadTokens(path string) ([]Token, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tokens []Token
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		token, err := ParseToken(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		tokens = append(tokens, token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no tokens in %s", path)
+	}
+	return tokens, nil
+}
+
+type tokenAuth struct {
+	hashes [][sha256.Size]byte
+	roles  []Role
+}
+
+func newTokenAuth(tokens []Token) *tokenAuth {
+	a := &tokenAuth{}
+	for _, t := range tokens {
+		a.hashes = append(a.hashes, sha256.Sum256([]byte(t.Value)))
+		a.roles = append(a.roles, t.Role)
+	}
+	return a
+}
+
+func (a *tokenAuth) role(token string) Role {
+	if token == "" {
+		return RoleNone
+	}
+	sum := sha256.Sum256([]byte(token))
+	role := RoleNone
+	for i, h := range a.hashes {
+		if subtle.ConstantTimeCompare(sum[:], h[:]) == 1 && a.roles[i] > role {
+			role = a.roles[i]
+		}
+	}
+	return role
+}
+
+// requestToken returns the bearer token from the Authorization header, or
+// from the access_token query parameter for clients such as EventSource that
+// cannot set headers.
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, token, found := strings.Cut(auth, " ")
+		if found && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+type roleKey struct{}
+
+func requestRole(r *http.Request) Role {
+	role, _ := r.Context().Value(roleKey{}).(Role)
+	return role
+}
+
+// authenticate attaches the caller's role to the request. Without configured
+// tokens every caller is a viewer, so control endpoints stay unreachable.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role := RoleViewer
+		if s.auth != nil {
+			role = s.auth.role(requestToken(r))
+			if role == RoleNone {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="systui"`)
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, role)))
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request, required Role) bool {
+	if s.auth == nil || requestRole(r) < required {
+		http.Error(w, "forbidden: requires "+required.String()+" role", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (o Options) tlsConfig() (*tls.Config, error) {
+	if o.TLSCert == "" && o.TLSKey == "" {
+		if o.ClientCA != "" {
+			return nil, errors.New("client CA requires a TLS certificate and key")
+		}
+		return nil, nil
+	}
+	if o.TLSCert == "" || o.TLSKey == "" {
+		return nil, errors.New("both TLS certificate and key are required")
+	}
+
+	cert, err := tls.LoadX509KeyPair(o.TLSCert, o.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.ClientCA != "" {
+		pem, err := os.ReadFile(o.ClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.ClientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
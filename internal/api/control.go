@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+var defaultAllowedSignals = []syscall.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP}
+
+type signalRequest struct {
+	Signal string `json:"signal"`
+}
+
+type reniceRequest struct {
+	Nice *int `json:"nice"`
+}
+
+type ioniceRequest struct {
+	Class system.IOClass `json:"class"`
+	Level int            `json:"level"`
+}
+
+type affinityRequest struct {
+	CPUs []int `json:"cpus"`
+}
+
+func (s *Server) signalAllowed(sig syscall.Signal) bool {
+	allowed := s.options.AllowedSignals
+	if allowed == nil {
+		allowed = defaultAllowedSignals
+	}
+	for _, a := range allowed {
+		if a == sig {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) unitAllowed(name string) bool {
+	for _, pattern := range s.options.AllowedUnits {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// handleProcessAction serves GET /processes/{pid}/{priority,details} and
+// POST /processes/{pid}/{signal,renice,ionice,affinity}.
+func (s *Server) handleProcessAction(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/processes/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	method := http.MethodPost
+	if parts[1] == "priority" || parts[1] == "details" {
+		method = http.MethodGet
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// init may be inspected but not acted on.
+	pid, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil || pid < 1 || pid == 1 && method == http.MethodPost {
+		http.Error(w, "invalid pid", http.StatusBadRequest)
+		return
+	}
+
+	switch parts[1] {
+	case "signal":
+		if !s.authorize(w, r, RoleOperator) {
+			return
+		}
+
+		var req signalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		sig, err := system.ParseSignal(req.Signal)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.signalAllowed(sig) {
+			http.Error(w, "signal "+system.SignalName(sig)+" is not allowed", http.StatusForbidden)
+			return
+		}
+
+		s.audit(r, "signal %s pid %d", system.SignalName(sig), pid)
+		s.respondAction(w, system.KillProcess(int32(pid), sig))
+		s.collector.Refresh(system.GroupProcesses)
+
+	case "renice":
+		if !s.authorize(w, r, RoleAdmin) {
+			return
+		}
+
+		var req reniceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Nice == nil || *req.Nice < -20 || *req.Nice > 19 {
+			http.Error(w, "nice must be between -20 and 19", http.StatusBadRequest)
+			return
+		}
+
+		s.audit(r, "renice pid %d to %d", pid, *req.Nice)
+		s.respondAction(w, system.ReniceProcess(int32(pid), *req.Nice))
+		s.collector.Refresh(system.GroupProcesses)
+
+	case "ionice":
+		if !s.authorize(w, r, RoleAdmin) {
+			return
+		}
+
+		var req ioniceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Level < 0 || req.Level > 7 {
+			http.Error(w, "level must be between 0 and 7", http.StatusBadRequest)
+			return
+		}
+
+		s.audit(r, "ionice pid %d to %s/%d", pid, req.Class, req.Level)
+		s.respondAction(w, system.SetIOPriority(int32(pid), req.Class, req.Level))
+
+	case "affinity":
+		if !s.authorize(w, r, RoleAdmin) {
+			return
+		}
+
+		var req affinityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.CPUs) == 0 {
+			http.Error(w, "cpus must list at least one CPU", http.StatusBadRequest)
+			return
+		}
+
+		s.audit(r, "set affinity of pid %d to %v", pid, req.CPUs)
+		s.respondAction(w, system.SetAffinity(int32(pid), req.CPUs))
+
+	case "details":
+		// Descriptors, maps and sockets reveal a lot about any process,
+		// root's included, so operators and admins may read them. The
+		// environment often carries secrets and is only included for
+		// admins.
+		if !s.authorize(w, r, RoleOperator) {
+			return
+		}
+		withEnv := s.auth != nil && requestRole(r) >= RoleAdmin
+		details, err := system.InspectProcess(int32(pid), withEnv)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(details)
+
+	case "priority":
+		priority, err := system.GetPriority(int32(pid))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(priority)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleServiceAction serves POST /services/{unit}/{action} where action is
+// start, stop, restart, enable or disable.
+func (s *Server) handleServiceAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/services/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	unit, action := parts[0], parts[1]
+
+	var required Role
+	var run func(string) error
+	switch action {
+	case "start":
+		required, run = RoleOperator, system.StartService
+	case "stop":
+		required, run = RoleOperator, system.StopService
+	case "restart":
+		required, run = RoleOperator, system.RestartService
+	case "enable":
+		required, run = RoleAdmin, system.EnableService
+	case "disable":
+		required, run = RoleAdmin, system.DisableService
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if !s.authorize(w, r, required) {
+		return
+	}
+	if !s.unitAllowed(unit) {
+		http.Error(w, "unit "+unit+" is not allowed", http.StatusForbidden)
+		return
+	}
+
+	s.audit(r, "%s unit %s", action, unit)
+	s.respondAction(w, run(unit))
+	s.collector.Refresh(system.GroupServices)
+}
+
+func (s *Server) respondAction(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"status": "error", "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+func (s *Server) audit(r *http.Request, format string, args ...interface{}) {
+	log.Printf("action by %s (%s): %s", r.RemoteAddr, requestRole(r), fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/guicybercode/systui/internal/logparser"
+)
+
+const logRoot = "/var/log"
+
+// logPath resolves the requested log file and refuses anything outside
+// /var/log, including paths that escape it through symlinks.
+func logPath(requested string) (string, bool) {
+	if requested == "" {
+		requested = "/var/log/syslog"
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(requested))
+	if err != nil {
+		return "", false
+	}
+	if !strings.HasPrefix(resolved, logRoot+"/") {
+		return "", false
+	}
+	return resolved, true
+}
+
+// authorizeLog lets any caller read the configured log sources and
+// requires the operator role for every other log, path being resolved by
+// logPath or logparser.JournalSource.
+func (s *Server) authorizeLog(w http.ResponseWriter, r *http.Request, path string) bool {
+	for _, source := range s.options.LogSources {
+		if source == path {
+			return true
+		}
+		if resolved, err := filepath.EvalSymlinks(source); err == nil && resolved == path {
+			return true
+		}
+	}
+	return s.authorize(w, r, RoleOperator)
+}
+
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("path") == logparser.JournalSource {
+		s.handleJournal(w, r)
+		return
+	}
+
+	path, ok := logPath(q.Get("path"))
+	if !ok {
+		http.Error(w, "log path must be an existing file under "+logRoot, http.StatusForbidden)
+		return
+	}
+	if !s.authorizeLog(w, r, path) {
+		return
+	}
+
+	fq := logparser.FileQuery{
+		Pattern:  q.Get("pattern"),
+		Start:    q.Get("start"),
+		End:      q.Get("end"),
+		Severity: q.Get("severity"),
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		fq.Limit = n
+	}
+	if before := q.Get("before"); before != "" {
+		n, err := strconv.ParseInt(before, 10, 64)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid before", http.StatusBadRequest)
+			return
+		}
+		fq.Before = n
+	}
+
+	// The request context ends the read when the client goes away.
+	entries, err := logparser.ReadLog(r.Context(), path, fq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// handleLogPattern serves GET /logs/pattern?path=&pattern=, which checks
+// that pattern compiles in the regex engine filtering path on this server.
+func (s *Server) handleLogPattern(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if err := logparser.CheckPattern(q.Get("path"), q.Get("pattern")); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+// handleJournal serves GET /logs?path=journal with the unit (comma-separated),
+// priority, boot, pid, after (a cursor) and limit filters on top of those
+// for log files.
+func (s *Server) handleJournal(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizeLog(w, r, logparser.JournalSource) {
+		return
+	}
+	jq, err := journalQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	entries, err := logparser.ReadJournal(r.Context(), jq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func journalQuery(q url.Values) (logparser.JournalQuery, error) {
+	jq := logparser.JournalQuery{
+		After:    q.Get("after"),
+		Priority: q.Get("priority"),
+		BootID:   q.Get("boot"),
+		Severity: q.Get("severity"),
+		Pattern:  q.Get("pattern"),
+	}
+	if units := q.Get("unit"); units != "" {
+		jq.Units = strings.Split(units, ",")
+	}
+	var err error
+	if jq.Since, err = logparser.ParseTime(q.Get("start")); err != nil {
+		return jq, err
+	}
+	if jq.Until, err = logparser.ParseTime(q.Get("end")); err != nil {
+		return jq, err
+	}
+	if pid := q.Get("pid"); pid != "" {
+		n, err := strconv.ParseInt(pid, 10, 32)
+		if err != nil || n <= 0 {
+			return jq, errors.New("invalid pid")
+		}
+		jq.PID = int32(n)
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return jq, errors.New("invalid limit")
+		}
+		jq.Limit = n
+	}
+	return jq, nil
+}
+
+// handleLogTail serves GET /logs/tail, which takes the parameters of /logs
+// plus the position returned by the previous call: inode and offset for a
+// file, after for the journal. Without a position it returns the current
+// end of the log.
+func (s *Server) handleLogTail(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	var tail *logparser.Tail
+	var err error
+	if q.Get("path") == logparser.JournalSource {
+		if !s.authorizeLog(w, r, logparser.JournalSource) {
+			return
+		}
+		var jq logparser.JournalQuery
+		jq, err = journalQuery(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tail, err = logparser.TailJournal(jq); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		path, ok := logPath(q.Get("path"))
+		if !ok {
+			http.Error(w, "log path must be an existing file under "+logRoot, http.StatusForbidden)
+			return
+		}
+		if !s.authorizeLog(w, r, path) {
+			return
+		}
+		var pos logparser.Position
+		if inode := q.Get("inode"); inode != "" {
+			pos.Inode, err = strconv.ParseUint(inode, 10, 64)
+			if err != nil {
+				http.Error(w, "invalid inode", http.StatusBadRequest)
+				return
+			}
+			pos.Offset, err = strconv.ParseInt(q.Get("offset"), 10, 64)
+			if err != nil || pos.Offset < 0 {
+				http.Error(w, "invalid offset", http.StatusBadRequest)
+				return
+			}
+		}
+		if tail, err = logparser.TailFile(path, pos, q.Get("pattern"), q.Get("start"), q.Get("end"), q.Get("severity")); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tail)
+}
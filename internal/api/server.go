@@ -4,92 +4,226 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/guicybercode/systui/internal/exports"
 	"github.com/guicybercode/systui/internal/system"
 )
 
+type Options struct {
+	Address    string
+	Port       int
+	UnixSocket string
+	SocketMode os.FileMode
+	TLSCert    string
+	TLSKey     string
+	ClientCA   string
+	Tokens     []Token
+
+	AllowedUnits   []string
+	AllowedSignals []syscall.Signal
+	// LogSources are the log files, or logparser.JournalSource, that any
+	// caller may read. Other logs require the operator role.
+	LogSources []string
+}
+
 type Server struct {
-	port int
+	options   Options
+	collector *system.Collector
+	auth      *tokenAuth
+}
+
+func NewServer(options Options, collector *system.Collector) *Server {
+	s := &Server{options: options, collector: collector}
+	if len(options.Tokens) > 0 {
+		s.auth = newTokenAuth(options.Tokens)
+	}
+	return s
 }
 
-func NewServer(port int) *Server {
-	return &Server{port: port}
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/metrics/prometheus", s.handlePrometheus)
+	mux.HandleFunc("/processes", s.handleProcesses)
+	mux.HandleFunc("/processes/", s.handleProcessAction)
+	mux.HandleFunc("/services", s.handleServices)
+	mux.HandleFunc("/services/", s.handleServiceAction)
+	mux.HandleFunc("/network", s.handleNetwork)
+	mux.HandleFunc("/report", s.handleReport)
+	mux.HandleFunc("/logs", s.handleLogs)
+	mux.HandleFunc("/logs/tail", s.handleLogTail)
+	mux.HandleFunc("/logs/pattern", s.handleLogPattern)
+	mux.HandleFunc("/packages", s.handlePackages)
+	mux.HandleFunc("/stream", s.handleSSE)
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	return s.authenticate(mux)
 }
 
 func (s *Server) Start() error {
-	http.HandleFunc("/metrics", s.handleMetrics)
-	http.HandleFunc("/processes", s.handleProcesses)
-	http.HandleFunc("/services", s.handleServices)
-	http.HandleFunc("/network", s.handleNetwork)
-	http.HandleFunc("/report", s.handleReport)
-
-	addr := fmt.Sprintf(":%d", s.port)
-	log.Printf("API server starting on %s", addr)
-	return http.ListenAndServe(addr, nil)
+	tlsConfig, err := s.options.tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	if s.options.UnixSocket == "" && s.auth == nil && (tlsConfig == nil || tlsConfig.ClientCAs == nil) {
+		log.Printf("warning: API server has no authentication configured")
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		TLSConfig:         tlsConfig,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	if tlsConfig != nil {
+		log.Printf("API server starting on %s (TLS)", listener.Addr())
+		return server.ServeTLS(listener, "", "")
+	}
+	log.Printf("API server starting on %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if s.options.UnixSocket == "" {
+		return net.Listen("tcp", net.JoinHostPort(s.options.Address, strconv.Itoa(s.options.Port)))
+	}
+
+	path := s.options.UnixSocket
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// The socket is created owner-only and opened up to the configured
+	// mode below, so it is never more open than that. The umask is per
+	// process, but nothing else creates files while the server starts.
+	oldMask := syscall.Umask(0177)
+	listener, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := s.options.SocketMode
+	if mode == 0 {
+		mode = 0660
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+
+	return listener, nil
 }
 
 func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	switch negotiate(r.Header.Get("Accept"), "application/json", openMetricsType, "text/plain") {
+	case openMetricsType, "text/plain":
+		s.handlePrometheus(w, r)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
-	cpu, _ := system.GetCPUMetrics()
-	mem, _ := system.GetMemoryMetrics()
-	disk, _ := system.GetDiskMetrics()
+	snap := s.collector.Latest()
 
 	metrics := map[string]interface{}{
-		"timestamp": time.Now(),
-		"cpu":       cpu,
-		"memory":    mem,
-		"disk":      disk,
+		"timestamp": snap.Timestamp,
+		"cpu":       snap.CPU,
+		"memory":    snap.Memory,
+		"disk":      snap.Disk,
 	}
 
 	json.NewEncoder(w).Encode(metrics)
 }
 
+func (s *Server) handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	openMetrics := negotiate(r.Header.Get("Accept"), "text/plain", openMetricsType) == openMetricsType ||
+		r.URL.Query().Get("format") == "openmetrics"
+
+	contentType := exports.PrometheusContentType
+	if openMetrics {
+		contentType = exports.OpenMetricsContentType
+	}
+	w.Header().Set("Content-Type", contentType)
+
+	if err := exports.ExportPrometheus(w, s.collector.Latest(), openMetrics); err != nil {
+		log.Printf("prometheus export: %v", err)
+	}
+}
+
 func (s *Server) handleProcesses(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	processes, err := system.GetProcesses()
-	if err != nil {
+	snap := s.collector.Latest()
+	if err := snap.Err(system.GroupProcesses); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(processes)
+	json.NewEncoder(w).Encode(snap.Processes)
 }
 
 func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	services, err := system.GetServices()
-	if err != nil {
+	snap := s.collector.Latest()
+	if err := snap.Err(system.GroupServices); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(services)
+	json.NewEncoder(w).Encode(snap.Services)
 }
 
-func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handlePackages(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	stats, err := system.GetNetworkStats()
-	if err != nil {
+	snap := s.collector.Latest()
+	if err := snap.Err(system.GroupPackages); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	connections, err := system.GetNetworkConnections()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	packages := map[string]interface{}{
+		"manager":  snap.PackageManager,
+		"packages": snap.Packages,
+	}
+
+	json.NewEncoder(w).Encode(packages)
+}
+
+func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	snap := s.collector.Latest()
+	// The interface stats are sampled with the metrics.
+	for _, g := range []system.Group{system.GroupMetrics, system.GroupConnections} {
+		if err := snap.Err(g); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	network := map[string]interface{}{
-		"stats":       stats,
-		"connections": connections,
+		"stats":       snap.Network,
+		"connections": snap.Connections,
 	}
 
 	json.NewEncoder(w).Encode(network)
@@ -98,11 +232,55 @@ func (s *Server) handleNetwork(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleReport(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	report, err := exports.GenerateReport()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	report := exports.NewReport(s.collector.Latest())
 
 	json.NewEncoder(w).Encode(report)
 }
+
+const openMetricsType = "application/openmetrics-text"
+
+// negotiate returns the offered media type the Accept header prefers, or
+// "" when it accepts none of them. Each offer takes the q-value of the
+// most specific media range matching it; ties go to the earlier offer. An
+// empty header accepts the first offer.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			params := strings.Split(part, ";")
+			mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+			rangeQ := 1.0
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(strings.TrimSpace(name), "q") {
+					if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+						rangeQ = v
+					}
+				}
+			}
+
+			n := -1
+			offerType, _, _ := strings.Cut(offer, "/")
+			switch mediaRange {
+			case offer:
+				n = 2
+			case offerType + "/*":
+				n = 1
+			case "*/*":
+				n = 0
+			}
+			if n > specificity {
+				q, specificity = rangeQ, n
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
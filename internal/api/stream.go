@@ -0,0 +1,302 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/guicybercode/systui/internal/system"
+)
+
+const (
+	defaultStreamInterval = time.Second
+	minStreamInterval     = 250 * time.Millisecond
+	keepAliveInterval     = 15 * time.Second
+)
+
+var streamTopics = []string{"cpu", "memory", "disk", "partitions", "network", "connections", "processes", "services", "packages"}
+
+type streamOptions struct {
+	Topics   []string `json:"topics"`
+	Interval string   `json:"interval"`
+	Mode     string   `json:"mode"`
+
+	interval time.Duration
+	diff     bool
+}
+
+type streamMessage struct {
+	Timestamp time.Time                  `json:"timestamp"`
+	Full      bool                       `json:"full"`
+	Topics    map[string]json.RawMessage `json:"topics"`
+}
+
+func parseStreamOptions(q url.Values) (streamOptions, error) {
+	opts := streamOptions{
+		Interval: q.Get("interval"),
+		Mode:     q.Get("mode"),
+	}
+	if topics := q.Get("topics"); topics != "" {
+		opts.Topics = strings.Split(topics, ",")
+	}
+	return opts, opts.validate()
+}
+
+func (o *streamOptions) validate() error {
+	if len(o.Topics) == 0 {
+		o.Topics = streamTopics
+	}
+	for _, topic := range o.Topics {
+		if !validTopic(topic) {
+			return fmt.Errorf("unknown topic %q", topic)
+		}
+	}
+
+	o.interval = defaultStreamInterval
+	if o.Interval != "" {
+		d, err := time.ParseDuration(o.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid interval %q: %w", o.Interval, err)
+		}
+		o.interval = d
+	}
+	if o.interval < minStreamInterval {
+		o.interval = minStreamInterval
+	}
+
+	switch o.Mode {
+	case "", "full":
+		o.diff = false
+	case "diff":
+		o.diff = true
+	default:
+		return fmt.Errorf("unknown mode %q", o.Mode)
+	}
+	return nil
+}
+
+func validTopic(topic string) bool {
+	for _, t := range streamTopics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+func topicValue(snap *system.Snapshot, topic string) interface{} {
+	switch topic {
+	case "cpu":
+		return snap.CPU
+	case "memory":
+		return snap.Memory
+	case "disk":
+		return snap.Disk
+	case "partitions":
+		return snap.Partitions
+	case "network":
+		return snap.Network
+	case "connections":
+		return snap.Connections
+	case "processes":
+		return snap.Processes
+	case "services":
+		return snap.Services
+	case "packages":
+		return map[string]interface{}{
+			"manager":  snap.PackageManager,
+			"packages": snap.Packages,
+		}
+	}
+	return nil
+}
+
+// streamEncoder turns snapshots into stream messages. In diff mode only the
+// topics whose encoding changed since the previous message are included.
+type streamEncoder struct {
+	opts streamOptions
+	last map[string]json.RawMessage
+	seen time.Time
+}
+
+func newStreamEncoder(opts streamOptions) *streamEncoder {
+	return &streamEncoder{opts: opts, last: make(map[string]json.RawMessage)}
+}
+
+func (e *streamEncoder) reset(opts streamOptions) {
+	e.opts = opts
+	e.last = make(map[string]json.RawMessage)
+	e.seen = time.Time{}
+}
+
+func (e *streamEncoder) next(snap *system.Snapshot) ([]byte, error) {
+	if snap.Timestamp.IsZero() || snap.Timestamp.Equal(e.seen) {
+		return nil, nil
+	}
+	e.seen = snap.Timestamp
+
+	msg := streamMessage{
+		Timestamp: snap.Timestamp,
+		Full:      !e.opts.diff || len(e.last) == 0,
+		Topics:    make(map[string]json.RawMessage),
+	}
+
+	for _, topic := range e.opts.Topics {
+		data, err := json.Marshal(topicValue(snap, topic))
+		if err != nil {
+			return nil, err
+		}
+		if !msg.Full && bytes.Equal(e.last[topic], data) {
+			continue
+		}
+		e.last[topic] = data
+		msg.Topics[topic] = data
+	}
+
+	if len(msg.Topics) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(msg)
+}
+
+func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseStreamOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	enc := newStreamEncoder(opts)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	lastWrite := time.Now()
+
+	for {
+		data, err := enc.next(s.collector.Latest())
+		if err != nil {
+			log.Printf("sse encode: %v", err)
+			return
+		}
+
+		switch {
+		case data != nil:
+			fmt.Fprintf(w, "event: snapshot\ndata: %s\n\n", data)
+			lastWrite = time.Now()
+			flusher.Flush()
+		case time.Since(lastWrite) >= keepAliveInterval:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			lastWrite = time.Now()
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// handleWebSocket streams snapshots like handleSSE. Clients may send a JSON
+// object with topics, interval and mode at any time to change the
+// subscription.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseStreamOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	updates := make(chan streamOptions)
+	rejects := make(chan error)
+	done := make(chan struct{})
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		defer close(done)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var next streamOptions
+			if err = json.Unmarshal(data, &next); err == nil {
+				err = next.validate()
+			}
+
+			if err != nil {
+				select {
+				case rejects <- err:
+				case <-quit:
+					return
+				}
+				continue
+			}
+			select {
+			case updates <- next:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	enc := newStreamEncoder(opts)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := enc.next(s.collector.Latest())
+		if err != nil {
+			log.Printf("websocket encode: %v", err)
+			return
+		}
+		if data != nil {
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case next := <-updates:
+			enc.reset(next)
+			ticker.Reset(next.interval)
+		case err := <-rejects:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(map[string]string{"error": err.Error()}); err != nil {
+				return
+			}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+// Duration is a time.Duration written as a Go duration string such as
+// "500ms" or "2m" in config files and environment variables.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+type Config struct {
+	Intervals Intervals              `toml:"intervals" yaml:"intervals"`
+	History   History                `toml:"history" yaml:"history"`
+	Views     Views                  `toml:"views" yaml:"views"`
+	Keys      Keymap                 `toml:"keys" yaml:"keys"`
+	Theme     Theme                  `toml:"theme" yaml:"theme"`
+	Themes    map[string]CustomTheme `toml:"themes" yaml:"themes"`
+	Processes Processes              `toml:"processes" yaml:"processes"`
+	Logs      Logs                   `toml:"logs" yaml:"logs"`
+	Server    Server                 `toml:"server" yaml:"server"`
+	Remote    Remote                 `toml:"remote" yaml:"remote"`
+	Fleet     []FleetHost            `toml:"fleet" yaml:"fleet"`
+}
+
+type Intervals struct {
+	Metrics     Duration `toml:"metrics" yaml:"metrics"`
+	Processes   Duration `toml:"processes" yaml:"processes"`
+	Services    Duration `toml:"services" yaml:"services"`
+	Connections Duration `toml:"connections" yaml:"connections"`
+	Packages    Duration `toml:"packages" yaml:"packages"`
+	Fleet       Duration `toml:"fleet" yaml:"fleet"`
+}
+
+type History struct {
+	Length int `toml:"length" yaml:"length"`
+}
+
+type Views struct {
+	Enabled []string `toml:"enabled" yaml:"enabled"`
+	Start   string   `toml:"start" yaml:"start"`
+}
+
+// Keymap binds actions to the key names reported by Bubble Tea, for example
+// "q", "ctrl+c" or "f5".
+type Keymap map[string][]string
+
+// Matches reports whether key is bound to action.
+func (k Keymap) Matches(action, key string) bool {
+	for _, bound := range k[action] {
+		if bound == key {
+			return true
+		}
+	}
+	return false
+}
+
+type Theme struct {
+	Name   string `toml:"name" yaml:"name"`
+	Colors Colors `toml:"colors" yaml:"colors"`
+}
+
+// Colors overrides theme colour slots. Empty slots keep the theme's colour.
+// Values are ANSI colour numbers or #rrggbb.
+type Colors struct {
+	Accent      string `toml:"accent" yaml:"accent"`
+	SelectionFg string `toml:"selection_fg" yaml:"selection_fg"`
+	SelectionBg string `toml:"selection_bg" yaml:"selection_bg"`
+	Border      string `toml:"border" yaml:"border"`
+	Muted       string `toml:"muted" yaml:"muted"`
+	Info        string `toml:"info" yaml:"info"`
+	Warning     string `toml:"warning" yaml:"warning"`
+	Critical    string `toml:"critical" yaml:"critical"`
+	OK          string `toml:"ok" yaml:"ok"`
+}
+
+// CustomTheme is a user-defined theme derived from a built-in one.
+type CustomTheme struct {
+	Base   string `toml:"base" yaml:"base"`
+	Colors Colors `toml:"colors" yaml:"colors"`
+}
+
+type Processes struct {
+	// KillGrace is how long a process has to exit after TERM before the
+	// escalating kill sends KILL.
+	KillGrace Duration `toml:"kill_grace" yaml:"kill_grace"`
+}
+
+type Logs struct {
+	// Sources are log files or "journal" for the systemd journal.
+	Sources []string `toml:"sources" yaml:"sources"`
+}
+
+type Server struct {
+	Bind         string   `toml:"bind" yaml:"bind"`
+	Port         int      `toml:"port" yaml:"port"`
+	UnixSocket   string   `toml:"unix_socket" yaml:"unix_socket"`
+	SocketMode   string   `toml:"socket_mode" yaml:"socket_mode"`
+	TLSCert      string   `toml:"tls_cert" yaml:"tls_cert"`
+	TLSKey       string   `toml:"tls_key" yaml:"tls_key"`
+	ClientCA     string   `toml:"client_ca" yaml:"client_ca"`
+	TokenFile    string   `toml:"token_file" yaml:"token_file"`
+	AllowUnits   []string `toml:"allow_units" yaml:"allow_units"`
+	AllowSignals []string `toml:"allow_signals" yaml:"allow_signals"`
+}
+
+type Remote struct {
+	URL       string `toml:"url" yaml:"url"`
+	Token     string `toml:"token" yaml:"token"`
+	TokenFile string `toml:"token_file" yaml:"token_file"`
+	CA        string `toml:"ca" yaml:"ca"`
+	Cert      string `toml:"cert" yaml:"cert"`
+	Key       string `toml:"key" yaml:"key"`
+}
+
+type FleetHost struct {
+	Name      string `toml:"name" yaml:"name"`
+	URL       string `toml:"url" yaml:"url"`
+	Token     string `toml:"token" yaml:"token"`
+	TokenFile string `toml:"token_file" yaml:"token_file"`
+	CA        string `toml:"ca" yaml:"ca"`
+	Cert      string `toml:"cert" yaml:"cert"`
+	Key       string `toml:"key" yaml:"key"`
+}
+
+// ViewNames lists every view in menu order.
+var ViewNames = []string{"dashboard", "processes", "services", "network", "packages", "logs", "fleet"}
+
+func Default() *Config {
+	collector := system.DefaultCollectorConfig()
+	return &Config{
+		Intervals: Intervals{
+			Metrics:     Duration(collector.MetricsInterval),
+			Processes:   Duration(collector.ProcessInterval),
+			Services:    Duration(collector.ServiceInterval),
+			Connections: Duration(collector.ConnectionInterval),
+			Packages:    Duration(collector.PackageInterval),
+			Fleet:       Duration(5 * time.Second),
+		},
+		History: History{Length: collector.HistoryLength},
+		Views: Views{
+			Enabled: append([]string(nil), ViewNames...),
+			Start:   "dashboard",
+		},
+		Keys: Keymap{
+			"quit":      {"q", "ctrl+c"},
+			"dashboard": {"1"},
+			"processes": {"2"},
+			"services":  {"3"},
+			"network":   {"4"},
+			"packages":  {"5"},
+			"logs":      {"6"},
+			"fleet":     {"7"},
+		},
+		Theme:     Theme{Name: "default"},
+		Processes: Processes{KillGrace: Duration(5 * time.Second)},
+		Logs:      Logs{Sources: []string{"journal", "/var/log/syslog"}},
+		Server: Server{
+			Port:         8080,
+			SocketMode:   "0660",
+			AllowSignals: []string{"TERM", "INT", "HUP"},
+		},
+	}
+}
+
+func (c *Config) CollectorConfig() system.CollectorConfig {
+	return system.CollectorConfig{
+		MetricsInterval:    time.Duration(c.Intervals.Metrics),
+		ProcessInterval:    time.Duration(c.Intervals.Processes),
+		ServiceInterval:    time.Duration(c.Intervals.Services),
+		ConnectionInterval: time.Duration(c.Intervals.Connections),
+		PackageInterval:    time.Duration(c.Intervals.Packages),
+		HistoryLength:      c.History.Length,
+	}
+}
+
+// ViewEnabled reports whether the named view is listed in views.enabled.
+func (c *Config) ViewEnabled(name string) bool {
+	return contains(c.Views.Enabled, name)
+}
+
+// Error reports a problem with a single setting.
+type Error struct {
+	Source string
+	Key    string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%s: %v", e.Key, e.Err)
+	if e.Source != "" {
+		msg = e.Source + ": " + msg
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
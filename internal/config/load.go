@@ -0,0 +1,319 @@
+package config
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var fileNames = []string{"config.toml", "config.yaml", "config.yml"}
+
+// Paths returns the locations searched for a config file, in order:
+// $XDG_CONFIG_HOME/systui (default ~/.config/systui) followed by each
+// directory in $XDG_CONFIG_DIRS (default /etc/xdg).
+func Paths() []string {
+	var dirs []string
+	home := os.Getenv("XDG_CONFIG_HOME")
+	if home == "" {
+		if dir, err := os.UserHomeDir(); err == nil {
+			home = filepath.Join(dir, ".config")
+		}
+	}
+	if home != "" {
+		dirs = append(dirs, home)
+	}
+
+	system := os.Getenv("XDG_CONFIG_DIRS")
+	if system == "" {
+		system = "/etc/xdg"
+	}
+	dirs = append(dirs, filepath.SplitList(system)...)
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, name := range fileNames {
+			paths = append(paths, filepath.Join(dir, "systui", name))
+		}
+	}
+	return paths
+}
+
+// StatePath returns the path of a file systui keeps between runs, in
+// $XDG_STATE_HOME/systui (default ~/.local/state/systui), or "" when there
+// is no home directory.
+func StatePath(name string) string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "systui", name)
+}
+
+// Load builds the configuration from the defaults, the config file and
+// SYSTUI_* environment variables, in increasing order of precedence. The
+// file is path, $SYSTUI_CONFIG or the first file found in Paths. Only an
+// explicitly named file has to exist. Load does not validate the result so
+// that callers can apply flag overrides first.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		path = os.Getenv("SYSTUI_CONFIG")
+	}
+	if path == "" {
+		for _, candidate := range Paths() {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+
+	if path != "" {
+		if err := cfg.load(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	switch filepath.Ext(path) {
+	case ".toml":
+		if err := toml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := checkKeys(raw, reflect.TypeOf(*c), ""); err != nil {
+			return withSource(err, path)
+		}
+		if _, err := toml.Decode(string(data), c); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := checkKeys(raw, reflect.TypeOf(*c), ""); err != nil {
+			return withSource(err, path)
+		}
+		if err := yaml.Unmarshal(data, c); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported config format, use .toml, .yaml or .yml", path)
+	}
+	return nil
+}
+
+// checkKeys rejects settings in raw that have no matching field in t, so
+// that typos are reported instead of silently ignored.
+func checkKeys(raw interface{}, t reflect.Type, prefix string) error {
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			field, ok := fieldByTag(t, k)
+			if !ok {
+				return &Error{Key: joinKey(prefix, k), Err: errors.New("unknown setting")}
+			}
+			if err := checkKeys(m[k], field.Type, joinKey(prefix, k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := checkKeys(m[k], t.Elem(), joinKey(prefix, k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		var items []interface{}
+		switch v := raw.(type) {
+		case []interface{}:
+			items = v
+		case []map[string]interface{}:
+			for _, item := range v {
+				items = append(items, item)
+			}
+		}
+		for i, item := range items {
+			if err := checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fieldByTag(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("toml") == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func withSource(err error, source string) error {
+	var cfgErr *Error
+	if errors.As(err, &cfgErr) && cfgErr.Source == "" {
+		cfgErr.Source = source
+	}
+	return err
+}
+
+// EnvName returns the environment variable that overrides key, for example
+// SYSTUI_SERVER_PORT for server.port.
+func EnvName(key string) string {
+	return "SYSTUI_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func (c *Config) applyEnv() error {
+	for _, key := range scalarKeys(reflect.TypeOf(*c), "") {
+		name := EnvName(key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		if err := c.Set(key, value); err != nil {
+			return withSource(err, name)
+		}
+	}
+	return nil
+}
+
+// scalarKeys lists the settings that can be assigned from a single string.
+func scalarKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := joinKey(prefix, field.Tag.Get("toml"))
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			keys = append(keys, scalarKeys(field.Type, key)...)
+		case field.Type.Kind() == reflect.Map:
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() != reflect.String:
+		default:
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Set assigns value to the setting named by key, a dotted path such as
+// "server.port" or "keys.quit". Lists are comma-separated.
+func (c *Config) Set(key, value string) error {
+	v := reflect.ValueOf(c).Elem()
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if v.Kind() == reflect.Map && i == len(parts)-1 {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setValue(elem, value); err != nil {
+				return &Error{Key: key, Err: err}
+			}
+			v.SetMapIndex(reflect.ValueOf(part), elem)
+			return nil
+		}
+		if v.Kind() != reflect.Struct {
+			return &Error{Key: key, Err: errors.New("unknown setting")}
+		}
+		field, ok := fieldByTag(v.Type(), part)
+		if !ok {
+			return &Error{Key: key, Err: errors.New("unknown setting")}
+		}
+		v = v.FieldByIndex(field.Index)
+	}
+
+	if err := setValue(v, value); err != nil {
+		return &Error{Key: key, Err: err}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return errors.New("cannot be set from a string")
+		}
+		items := []string{}
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return errors.New("cannot be set from a string")
+	}
+	return nil
+}
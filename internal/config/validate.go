@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+const minInterval = 100 * time.Millisecond
+
+// Themes lists the built-in theme names.
+var Themes = []string{"default", "dark", "light", "high-contrast"}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks every setting and reports the first invalid one.
+func (c *Config) Validate() error {
+	intervals := []struct {
+		key   string
+		value Duration
+	}{
+		{"intervals.metrics", c.Intervals.Metrics},
+		{"intervals.processes", c.Intervals.Processes},
+		{"intervals.services", c.Intervals.Services},
+		{"intervals.connections", c.Intervals.Connections},
+		{"intervals.packages", c.Intervals.Packages},
+		{"intervals.fleet", c.Intervals.Fleet},
+	}
+	for _, interval := range intervals {
+		if time.Duration(interval.value) < minInterval {
+			return invalid(interval.key, "must be at least %s", minInterval)
+		}
+	}
+
+	if c.History.Length < 1 || c.History.Length > 86400 {
+		return invalid("history.length", "must be between 1 and 86400")
+	}
+
+	if len(c.Views.Enabled) == 0 {
+		return invalid("views.enabled", "must list at least one view")
+	}
+	seen := make(map[string]bool)
+	for _, view := range c.Views.Enabled {
+		if !contains(ViewNames, view) {
+			return invalid("views.enabled", "unknown view %q", view)
+		}
+		if seen[view] {
+			return invalid("views.enabled", "view %q listed twice", view)
+		}
+		seen[view] = true
+	}
+	if !seen[c.Views.Start] {
+		return invalid("views.start", "view %q is not enabled", c.Views.Start)
+	}
+
+	bound := make(map[string]string)
+	for _, action := range sortedActions(c.Keys) {
+		key := "keys." + action
+		if action != "quit" && !contains(ViewNames, action) {
+			return invalid(key, "unknown action")
+		}
+		if len(c.Keys[action]) == 0 {
+			return invalid(key, "must bind at least one key")
+		}
+		for _, k := range c.Keys[action] {
+			if other, ok := bound[k]; ok {
+				return invalid(key, "%q is already bound to %s", k, other)
+			}
+			bound[k] = action
+		}
+	}
+
+	if _, custom := c.Themes[c.Theme.Name]; !custom && !contains(Themes, c.Theme.Name) {
+		return invalid("theme.name", "unknown theme %q", c.Theme.Name)
+	}
+	if err := validColors("theme.colors", c.Theme.Colors); err != nil {
+		return err
+	}
+	for _, name := range sortedNames(c.Themes) {
+		prefix := "themes." + name
+		if contains(Themes, name) {
+			return invalid(prefix, "redefines the built-in theme %q", name)
+		}
+		if !contains(Themes, c.Themes[name].Base) {
+			return invalid(prefix+".base", "unknown built-in theme %q", c.Themes[name].Base)
+		}
+		if err := validColors(prefix+".colors", c.Themes[name].Colors); err != nil {
+			return err
+		}
+	}
+
+	if c.Processes.KillGrace <= 0 {
+		return invalid("processes.kill_grace", "must be positive")
+	}
+
+	if len(c.Logs.Sources) == 0 {
+		return invalid("logs.sources", "must list at least one log file or \"journal\"")
+	}
+	for _, source := range c.Logs.Sources {
+		if source != "journal" && !filepath.IsAbs(source) {
+			return invalid("logs.sources", "%q is neither \"journal\" nor an absolute path", source)
+		}
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		return invalid("server.port", "must be between 1 and 65535")
+	}
+	if _, err := c.Server.Mode(); err != nil {
+		return invalid("server.socket_mode", "%v", err)
+	}
+	if (c.Server.TLSCert == "") != (c.Server.TLSKey == "") {
+		return invalid("server.tls_key", "tls_cert and tls_key must be set together")
+	}
+	if c.Server.ClientCA != "" && c.Server.TLSCert == "" {
+		return invalid("server.client_ca", "requires tls_cert and tls_key")
+	}
+	for _, unit := range c.Server.AllowUnits {
+		if _, err := path.Match(unit, ""); err != nil {
+			return invalid("server.allow_units", "bad pattern %q", unit)
+		}
+	}
+	for _, name := range c.Server.AllowSignals {
+		if _, err := system.ParseSignal(name); err != nil {
+			return invalid("server.allow_signals", "%v", err)
+		}
+	}
+
+	if c.Remote.URL != "" {
+		if err := validURL(c.Remote.URL); err != nil {
+			return invalid("remote.url", "%v", err)
+		}
+	}
+	if (c.Remote.Cert == "") != (c.Remote.Key == "") {
+		return invalid("remote.key", "cert and key must be set together")
+	}
+
+	names := make(map[string]bool)
+	for i, host := range c.Fleet {
+		prefix := fmt.Sprintf("fleet[%d]", i)
+		if host.Name == "" {
+			return invalid(prefix+".name", "must not be empty")
+		}
+		if names[host.Name] {
+			return invalid(prefix+".name", "host %q listed twice", host.Name)
+		}
+		names[host.Name] = true
+		if err := validURL(host.URL); err != nil {
+			return invalid(prefix+".url", "%v", err)
+		}
+		if (host.Cert == "") != (host.Key == "") {
+			return invalid(prefix+".key", "cert and key must be set together")
+		}
+	}
+
+	return nil
+}
+
+// Mode parses socket_mode as an octal file mode.
+func (s Server) Mode() (uint32, error) {
+	mode, err := strconv.ParseUint(s.SocketMode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal file mode", s.SocketMode)
+	}
+	return uint32(mode), nil
+}
+
+func invalid(key, format string, args ...interface{}) error {
+	return &Error{Key: key, Err: fmt.Errorf(format, args...)}
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+	if u.Host == "" {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+func validColors(prefix string, colors Colors) error {
+	v := reflect.ValueOf(colors)
+	for i := 0; i < v.NumField(); i++ {
+		color := v.Field(i).String()
+		if color != "" && !validColor(color) {
+			key := prefix + "." + v.Type().Field(i).Tag.Get("toml")
+			return invalid(key, "%q is neither an ANSI colour number nor #rrggbb", color)
+		}
+	}
+	return nil
+}
+
+func validColor(color string) bool {
+	if hexColor.MatchString(color) {
+		return true
+	}
+	n, err := strconv.Atoi(color)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedActions(k Keymap) []string {
+	actions := make([]string, 0, len(k))
+	for action := range k {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+func sortedNames(themes map[string]CustomTheme) []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
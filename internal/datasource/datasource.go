@@ -0,0 +1,191 @@
+package datasource
+
+import (
+	"context"
+	"syscall"
+
+	"github.com/guicybercode/systui/internal/logparser"
+	"github.com/guicybercode/systui/internal/system"
+)
+
+// LogQuery selects log entries. Path is a log file or
+// logparser.JournalSource; the unit, priority, boot, PID and cursor
+// filters only apply to the journal, Before only to log files.
+type LogQuery struct {
+	Path     string
+	Pattern  string
+	Start    string
+	End      string
+	Severity string
+	Units    []string
+	Priority string
+	Boot     string
+	PID      int32
+	After    string
+	Before   int64
+	Limit    int
+}
+
+// Journal converts the query for logparser.ReadJournal.
+func (q LogQuery) Journal() (logparser.JournalQuery, error) {
+	since, err := logparser.ParseTime(q.Start)
+	if err != nil {
+		return logparser.JournalQuery{}, err
+	}
+	until, err := logparser.ParseTime(q.End)
+	if err != nil {
+		return logparser.JournalQuery{}, err
+	}
+	return logparser.JournalQuery{
+		After:    q.After,
+		Units:    q.Units,
+		Priority: q.Priority,
+		BootID:   q.Boot,
+		PID:      q.PID,
+		Since:    since,
+		Until:    until,
+		Severity: q.Severity,
+		Pattern:  q.Pattern,
+		Limit:    q.Limit,
+	}, nil
+}
+
+// File converts the query for logparser.ReadLog.
+func (q LogQuery) File() logparser.FileQuery {
+	return logparser.FileQuery{
+		Pattern:  q.Pattern,
+		Start:    q.Start,
+		End:      q.End,
+		Severity: q.Severity,
+		Limit:    q.Limit,
+		Before:   q.Before,
+	}
+}
+
+// Source is where the TUI views get their data from and send their actions
+// to: either the local machine or a remote systui headless server.
+type Source interface {
+	Name() string
+	Subscribe() (<-chan *system.Snapshot, func())
+	History() *system.History
+	Refresh(groups ...system.Group)
+
+	SignalProcess(pid int32, sig syscall.Signal) error
+	ReniceProcess(pid int32, nice int) error
+	ProcessPriority(pid int32) (system.Priority, error)
+	SetIOPriority(pid int32, class system.IOClass, level int) error
+	SetAffinity(pid int32, cpus []int) error
+	InspectProcess(pid int32) (*system.ProcessDetails, error)
+	StartService(name string) error
+	StopService(name string) error
+	RestartService(name string) error
+
+	// Logs returns the newest matching entries, oldest first. Reading a
+	// large file or the journal stops early when ctx is cancelled.
+	Logs(ctx context.Context, query LogQuery) ([]logparser.LogEntry, error)
+	// TailLogs returns what was written to the log since pos; the zero
+	// Position yields no entries and the current end. WatchLogs signals
+	// when the log may have grown.
+	TailLogs(query LogQuery, pos logparser.Position) (*logparser.Tail, error)
+	WatchLogs(path string) (<-chan struct{}, func(), error)
+	// CheckPattern reports whether pattern compiles in the regex engine
+	// that runs queries of path, which for a remote source is the server's.
+	CheckPattern(path, pattern string) error
+}
+
+type Local struct {
+	collector *system.Collector
+}
+
+func NewLocal(collector *system.Collector) *Local {
+	return &Local{collector: collector}
+}
+
+func (l *Local) Name() string {
+	return "local"
+}
+
+func (l *Local) Subscribe() (<-chan *system.Snapshot, func()) {
+	return l.collector.Subscribe()
+}
+
+func (l *Local) History() *system.History {
+	return l.collector.History()
+}
+
+func (l *Local) Refresh(groups ...system.Group) {
+	l.collector.Refresh(groups...)
+}
+
+func (l *Local) SignalProcess(pid int32, sig syscall.Signal) error {
+	return system.KillProcess(pid, sig)
+}
+
+// SignalProcessStartedAt sends sig to pid only if it is still the process
+// started at createTime. Remote sources cannot check this in one request.
+func (l *Local) SignalProcessStartedAt(pid int32, sig syscall.Signal, createTime int64) error {
+	return system.SignalProcessStartedAt(pid, sig, createTime)
+}
+
+func (l *Local) ReniceProcess(pid int32, nice int) error {
+	return system.ReniceProcess(pid, nice)
+}
+
+func (l *Local) ProcessPriority(pid int32) (system.Priority, error) {
+	return system.GetPriority(pid)
+}
+
+func (l *Local) SetIOPriority(pid int32, class system.IOClass, level int) error {
+	return system.SetIOPriority(pid, class, level)
+}
+
+func (l *Local) SetAffinity(pid int32, cpus []int) error {
+	return system.SetAffinity(pid, cpus)
+}
+
+func (l *Local) InspectProcess(pid int32) (*system.ProcessDetails, error) {
+	return system.InspectProcess(pid, true)
+}
+
+func (l *Local) StartService(name string) error {
+	return system.StartService(name)
+}
+
+func (l *Local) StopService(name string) error {
+	return system.StopService(name)
+}
+
+func (l *Local) RestartService(name string) error {
+	return system.RestartService(name)
+}
+
+func (l *Local) Logs(ctx context.Context, query LogQuery) ([]logparser.LogEntry, error) {
+	if query.Path == logparser.JournalSource {
+		jq, err := query.Journal()
+		if err != nil {
+			return nil, err
+		}
+		return logparser.ReadJournal(ctx, jq)
+	}
+	return logparser.ReadLog(ctx, query.Path, query.File())
+}
+
+func (l *Local) TailLogs(query LogQuery, pos logparser.Position) (*logparser.Tail, error) {
+	if query.Path == logparser.JournalSource {
+		jq, err := query.Journal()
+		if err != nil {
+			return nil, err
+		}
+		jq.After = pos.Cursor
+		return logparser.TailJournal(jq)
+	}
+	return logparser.TailFile(query.Path, pos, query.Pattern, query.Start, query.End, query.Severity)
+}
+
+func (l *Local) CheckPattern(path, pattern string) error {
+	return logparser.CheckPattern(path, pattern)
+}
+
+func (l *Local) WatchLogs(path string) (<-chan struct{}, func(), error) {
+	return logparser.Watch(path)
+}
@@ -0,0 +1,533 @@
+package datasource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/guicybercode/systui/internal/logparser"
+	"github.com/guicybercode/systui/internal/system"
+)
+
+type RemoteOptions struct {
+	URL      string
+	Token    string
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	Interval time.Duration
+}
+
+// Remote reads snapshots from a systui headless server over its /stream
+// endpoint and forwards actions to its control endpoints.
+type Remote struct {
+	base    *url.URL
+	token   string
+	options RemoteOptions
+
+	client  *http.Client
+	stream  *http.Client
+	history *system.History
+
+	mu        sync.Mutex
+	latest    *system.Snapshot
+	publisher system.Publisher
+}
+
+func NewRemote(options RemoteOptions) (*Remote, error) {
+	base, err := url.Parse(strings.TrimSuffix(options.URL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid remote URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid remote URL %q: scheme must be http or https", options.URL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options.CAFile != "" || options.CertFile != "" {
+		config, err := clientTLSConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+	}
+
+	if options.Interval <= 0 {
+		options.Interval = time.Second
+	}
+
+	return &Remote{
+		base:    base,
+		token:   options.Token,
+		options: options,
+		client:  &http.Client{Transport: transport, Timeout: 30 * time.Second},
+		stream:  &http.Client{Transport: transport},
+		history: system.NewHistory(0),
+		latest:  &system.Snapshot{},
+	}, nil
+}
+
+func clientTLSConfig(options RemoteOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read remote CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", options.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if options.CertFile != "" || options.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+func (r *Remote) Name() string {
+	return r.base.Host
+}
+
+// Start follows the remote stream until ctx is cancelled, reconnecting with
+// backoff when the connection drops.
+func (r *Remote) Start(ctx context.Context) {
+	go func() {
+		backoff := time.Second
+		for {
+			err := r.follow(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				backoff = time.Second
+				continue
+			}
+			r.fail(err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+func (r *Remote) Subscribe() (<-chan *system.Snapshot, func()) {
+	return r.publisher.Subscribe()
+}
+
+func (r *Remote) History() *system.History {
+	return r.history
+}
+
+func (r *Remote) Refresh(groups ...system.Group) {}
+
+func (r *Remote) follow(ctx context.Context) error {
+	q := url.Values{}
+	q.Set("interval", r.options.Interval.String())
+	q.Set("mode", "diff")
+
+	req, err := r.newRequest(ctx, http.MethodGet, "/stream", q, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := r.stream.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				if err := r.apply(data.Bytes()); err != nil {
+					return err
+				}
+				data.Reset()
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("remote stream closed")
+}
+
+type streamMessage struct {
+	Timestamp time.Time                  `json:"timestamp"`
+	Topics    map[string]json.RawMessage `json:"topics"`
+}
+
+var topicGroups = map[string]system.Group{
+	"cpu":         system.GroupMetrics,
+	"memory":      system.GroupMetrics,
+	"disk":        system.GroupMetrics,
+	"partitions":  system.GroupMetrics,
+	"network":     system.GroupMetrics,
+	"connections": system.GroupConnections,
+	"processes":   system.GroupProcesses,
+	"services":    system.GroupServices,
+	"packages":    system.GroupPackages,
+}
+
+func (r *Remote) apply(data []byte) error {
+	var msg streamMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return fmt.Errorf("invalid stream message: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := *r.latest
+	next.Timestamp = msg.Timestamp
+	next.Errors = make(map[system.Group]error)
+	next.Updated = make(map[system.Group]time.Time, len(r.latest.Updated))
+	for k, v := range r.latest.Updated {
+		next.Updated[k] = v
+	}
+
+	metrics := false
+	for topic, raw := range msg.Topics {
+		var err error
+		switch topic {
+		case "cpu":
+			next.CPU = nil
+			err = json.Unmarshal(raw, &next.CPU)
+		case "memory":
+			next.Memory = nil
+			err = json.Unmarshal(raw, &next.Memory)
+		case "disk":
+			next.Disk = nil
+			err = json.Unmarshal(raw, &next.Disk)
+		case "partitions":
+			next.Partitions = nil
+			err = json.Unmarshal(raw, &next.Partitions)
+		case "network":
+			next.Network = nil
+			err = json.Unmarshal(raw, &next.Network)
+		case "connections":
+			next.Connections = nil
+			err = json.Unmarshal(raw, &next.Connections)
+		case "processes":
+			next.Processes = nil
+			err = json.Unmarshal(raw, &next.Processes)
+		case "services":
+			next.Services = nil
+			err = json.Unmarshal(raw, &next.Services)
+		case "packages":
+			var payload struct {
+				Manager  system.PackageManager `json:"manager"`
+				Packages []system.PackageInfo  `json:"packages"`
+			}
+			err = json.Unmarshal(raw, &payload)
+			next.PackageManager = payload.Manager
+			next.Packages = payload.Packages
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s payload: %w", topic, err)
+		}
+		if topicGroups[topic] == system.GroupMetrics {
+			metrics = true
+		}
+		next.Updated[topicGroups[topic]] = msg.Timestamp
+	}
+
+	r.latest = &next
+	if metrics {
+		r.history.Observe(&next)
+	}
+	r.publisher.Publish(&next)
+	return nil
+}
+
+func (r *Remote) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := *r.latest
+	next.Timestamp = time.Now()
+	next.Errors = make(map[system.Group]error)
+	next.Updated = make(map[system.Group]time.Time)
+	for _, g := range topicGroups {
+		next.Errors[g] = fmt.Errorf("remote %s: %w", r.base.Host, err)
+		next.Updated[g] = next.Timestamp
+	}
+
+	r.latest = &next
+	r.publisher.Publish(&next)
+}
+
+func (r *Remote) newRequest(ctx context.Context, method, path string, q url.Values, body interface{}) (*http.Request, error) {
+	u := *r.base
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	if q != nil {
+		u.RawQuery = q.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return req, nil
+}
+
+func (r *Remote) do(method, path string, q url.Values, body, out interface{}) error {
+	return r.doContext(context.Background(), method, path, q, body, out)
+}
+
+func (r *Remote) doContext(ctx context.Context, method, path string, q url.Values, body, out interface{}) error {
+	req, err := r.newRequest(ctx, method, path, q, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("remote %s: %w", r.base.Host, responseError(resp))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+		return errors.New(payload.Error)
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = resp.Status
+	}
+	return errors.New(msg)
+}
+
+// Poll fetches a one-off snapshot of the remote metrics and services
+// without following the stream.
+func (r *Remote) Poll() (*system.Snapshot, error) {
+	var metrics struct {
+		Timestamp time.Time             `json:"timestamp"`
+		CPU       *system.CPUMetrics    `json:"cpu"`
+		Memory    *system.MemoryMetrics `json:"memory"`
+		Disk      *system.DiskMetrics   `json:"disk"`
+	}
+	if err := r.do(http.MethodGet, "/metrics", nil, nil, &metrics); err != nil {
+		return nil, err
+	}
+
+	snap := &system.Snapshot{
+		Timestamp: metrics.Timestamp,
+		CPU:       metrics.CPU,
+		Memory:    metrics.Memory,
+		Disk:      metrics.Disk,
+		Errors:    make(map[system.Group]error),
+		Updated:   map[system.Group]time.Time{system.GroupMetrics: metrics.Timestamp},
+	}
+
+	if err := r.do(http.MethodGet, "/services", nil, nil, &snap.Services); err != nil {
+		snap.Errors[system.GroupServices] = err
+	}
+	snap.Updated[system.GroupServices] = metrics.Timestamp
+
+	return snap, nil
+}
+
+func (r *Remote) SignalProcess(pid int32, sig syscall.Signal) error {
+	body := map[string]string{"signal": system.SignalName(sig)}
+	return r.do(http.MethodPost, "/processes/"+strconv.Itoa(int(pid))+"/signal", nil, body, nil)
+}
+
+func (r *Remote) ReniceProcess(pid int32, nice int) error {
+	body := map[string]int{"nice": nice}
+	return r.do(http.MethodPost, "/processes/"+strconv.Itoa(int(pid))+"/renice", nil, body, nil)
+}
+
+func (r *Remote) ProcessPriority(pid int32) (system.Priority, error) {
+	var p system.Priority
+	err := r.do(http.MethodGet, "/processes/"+strconv.Itoa(int(pid))+"/priority", nil, nil, &p)
+	return p, err
+}
+
+func (r *Remote) SetIOPriority(pid int32, class system.IOClass, level int) error {
+	body := map[string]interface{}{"class": class.String(), "level": level}
+	return r.do(http.MethodPost, "/processes/"+strconv.Itoa(int(pid))+"/ionice", nil, body, nil)
+}
+
+func (r *Remote) SetAffinity(pid int32, cpus []int) error {
+	body := map[string][]int{"cpus": cpus}
+	return r.do(http.MethodPost, "/processes/"+strconv.Itoa(int(pid))+"/affinity", nil, body, nil)
+}
+
+// InspectProcess fetches the details of pid. The server leaves out the
+// environment unless the token has the admin role.
+func (r *Remote) InspectProcess(pid int32) (*system.ProcessDetails, error) {
+	var d system.ProcessDetails
+	if err := r.do(http.MethodGet, "/processes/"+strconv.Itoa(int(pid))+"/details", nil, nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *Remote) StartService(name string) error {
+	return r.serviceAction(name, "start")
+}
+
+func (r *Remote) StopService(name string) error {
+	return r.serviceAction(name, "stop")
+}
+
+func (r *Remote) RestartService(name string) error {
+	return r.serviceAction(name, "restart")
+}
+
+func (r *Remote) serviceAction(name, action string) error {
+	return r.do(http.MethodPost, "/services/"+url.PathEscape(name)+"/"+action, nil, nil, nil)
+}
+
+func (r *Remote) Logs(ctx context.Context, query LogQuery) ([]logparser.LogEntry, error) {
+	var entries []logparser.LogEntry
+	if err := r.doContext(ctx, http.MethodGet, "/logs", logValues(query), nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *Remote) TailLogs(query LogQuery, pos logparser.Position) (*logparser.Tail, error) {
+	q := logValues(query)
+	if pos.Inode != 0 {
+		q.Set("inode", strconv.FormatUint(pos.Inode, 10))
+		q.Set("offset", strconv.FormatInt(pos.Offset, 10))
+	}
+	q.Set("after", pos.Cursor)
+
+	var tail logparser.Tail
+	if err := r.do(http.MethodGet, "/logs/tail", q, nil, &tail); err != nil {
+		return nil, err
+	}
+	return &tail, nil
+}
+
+func (r *Remote) CheckPattern(path, pattern string) error {
+	return r.do(http.MethodGet, "/logs/pattern", url.Values{"path": {path}, "pattern": {pattern}}, nil, nil)
+}
+
+// WatchLogs polls at the refresh interval, as the server has no push
+// channel for logs.
+func (r *Remote) WatchLogs(path string) (<-chan struct{}, func(), error) {
+	ch := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(r.options.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() { once.Do(func() { close(done) }) }, nil
+}
+
+func logValues(query LogQuery) url.Values {
+	q := url.Values{}
+	q.Set("path", query.Path)
+	q.Set("pattern", query.Pattern)
+	q.Set("start", query.Start)
+	q.Set("end", query.End)
+	q.Set("severity", query.Severity)
+	if query.Limit > 0 {
+		q.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Path != logparser.JournalSource {
+		if query.Before > 0 {
+			q.Set("before", strconv.FormatInt(query.Before, 10))
+		}
+		return q
+	}
+	q.Set("unit", strings.Join(query.Units, ","))
+	q.Set("priority", query.Priority)
+	q.Set("boot", query.Boot)
+	if query.PID > 0 {
+		q.Set("pid", strconv.Itoa(int(query.PID)))
+	}
+	q.Set("after", query.After)
+	return q
+}
@@ -28,6 +28,18 @@ func ExportJSON(filename string, report *SystemReport) error {
 	return os.WriteFile(filename, data, 0644)
 }
 
+func NewReport(snap *system.Snapshot) *SystemReport {
+	return &SystemReport{
+		Timestamp: snap.Timestamp,
+		CPU:       snap.CPU,
+		Memory:    snap.Memory,
+		Disk:      snap.Disk,
+		Processes: snap.Processes,
+		Services:  snap.Services,
+		Network:   snap.Network,
+	}
+}
+
 func GenerateReport() (*SystemReport, error) {
 	cpu, err := system.GetCPUMetrics()
 	if err != nil {
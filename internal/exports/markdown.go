@@ -18,6 +18,9 @@ func ExportMarkdown(filename string, report *SystemReport) error {
 
 	fmt.Fprintf(file, "## CPU Metrics\n\n")
 	fmt.Fprintf(file, "- **Usage**: %.2f%%\n", report.CPU.Usage)
+	if len(report.CPU.LoadAvg) == 3 {
+		fmt.Fprintf(file, "- **Load Average**: %.2f %.2f %.2f\n", report.CPU.LoadAvg[0], report.CPU.LoadAvg[1], report.CPU.LoadAvg[2])
+	}
 	fmt.Fprintf(file, "- **Cores**: %d\n", report.CPU.Cores)
 	fmt.Fprintf(file, "- **Model**: %s\n\n", report.CPU.Model)
 
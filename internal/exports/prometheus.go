@@ -0,0 +1,274 @@
+package exports
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+const (
+	PrometheusContentType  = "text/plain; version=0.0.4; charset=utf-8"
+	OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+type metricType string
+
+const (
+	gauge   metricType = "gauge"
+	counter metricType = "counter"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	labels []label
+	value  float64
+}
+
+type family struct {
+	name    string
+	help    string
+	typ     metricType
+	samples []sample
+}
+
+func (f *family) add(value float64, labels ...label) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+var unitStates = []string{"active", "activating", "deactivating", "inactive", "failed"}
+
+// ExportPrometheus writes the snapshot in the Prometheus text exposition
+// format, or in OpenMetrics when openMetrics is set.
+func ExportPrometheus(w io.Writer, snap *system.Snapshot, openMetrics bool) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range prometheusFamilies(snap) {
+		if len(f.samples) == 0 {
+			continue
+		}
+		writeFamily(bw, f, openMetrics)
+	}
+	if openMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func prometheusFamilies(snap *system.Snapshot) []*family {
+	var families []*family
+	newFamily := func(name, help string, typ metricType) *family {
+		f := &family{name: name, help: help, typ: typ}
+		families = append(families, f)
+		return f
+	}
+
+	if cpu := snap.CPU; cpu != nil {
+		usage := newFamily("systui_cpu_usage_ratio", "CPU busy time ratio over the last sample interval.", gauge)
+		usage.add(cpu.Usage/100, label{"cpu", "total"})
+		for i, pct := range cpu.PerCore {
+			usage.add(pct/100, label{"cpu", strconv.Itoa(i)})
+		}
+
+		mode := newFamily("systui_cpu_mode_ratio", "CPU time ratio per mode over the last sample interval.", gauge)
+		addBreakdown(mode, "total", cpu.Breakdown)
+		for i, b := range cpu.PerCoreBreakdown {
+			addBreakdown(mode, strconv.Itoa(i), b)
+		}
+
+		newFamily("systui_cpu_cores", "Number of logical CPU cores.", gauge).add(float64(cpu.Cores))
+		if len(cpu.LoadAvg) == 3 {
+			newFamily("systui_load1", "1-minute load average.", gauge).add(cpu.LoadAvg[0])
+			newFamily("systui_load5", "5-minute load average.", gauge).add(cpu.LoadAvg[1])
+			newFamily("systui_load15", "15-minute load average.", gauge).add(cpu.LoadAvg[2])
+		}
+	}
+
+	if mem := snap.Memory; mem != nil {
+		newFamily("systui_memory_total_bytes", "Total physical memory in bytes.", gauge).add(float64(mem.Total))
+		newFamily("systui_memory_used_bytes", "Used physical memory in bytes.", gauge).add(float64(mem.Used))
+		newFamily("systui_memory_available_bytes", "Memory available for new allocations in bytes.", gauge).add(float64(mem.Available))
+		newFamily("systui_memory_free_bytes", "Free physical memory in bytes.", gauge).add(float64(mem.Free))
+		newFamily("systui_memory_cached_bytes", "Page cache memory in bytes.", gauge).add(float64(mem.Cached))
+		newFamily("systui_memory_buffers_bytes", "Buffer memory in bytes.", gauge).add(float64(mem.Buffers))
+		newFamily("systui_swap_total_bytes", "Total swap space in bytes.", gauge).add(float64(mem.SwapTotal))
+		newFamily("systui_swap_used_bytes", "Used swap space in bytes.", gauge).add(float64(mem.SwapUsed))
+	}
+
+	if len(snap.Partitions) > 0 {
+		size := newFamily("systui_filesystem_size_bytes", "Filesystem size in bytes.", gauge)
+		used := newFamily("systui_filesystem_used_bytes", "Filesystem space used in bytes.", gauge)
+		free := newFamily("systui_filesystem_free_bytes", "Filesystem space free in bytes.", gauge)
+		for _, part := range snap.Partitions {
+			labels := []label{{"device", part.Device}, {"mountpoint", part.Mountpoint}, {"fstype", part.Fstype}}
+			size.add(float64(part.Total), labels...)
+			used.add(float64(part.Used), labels...)
+			free.add(float64(part.Free), labels...)
+		}
+	}
+
+	if len(snap.Network) > 0 {
+		rxBytes := newFamily("systui_network_receive_bytes_total", "Bytes received per interface.", counter)
+		txBytes := newFamily("systui_network_transmit_bytes_total", "Bytes transmitted per interface.", counter)
+		rxPackets := newFamily("systui_network_receive_packets_total", "Packets received per interface.", counter)
+		txPackets := newFamily("systui_network_transmit_packets_total", "Packets transmitted per interface.", counter)
+		rxErrs := newFamily("systui_network_receive_errs_total", "Receive errors per interface.", counter)
+		txErrs := newFamily("systui_network_transmit_errs_total", "Transmit errors per interface.", counter)
+		rxDrop := newFamily("systui_network_receive_drop_total", "Received packets dropped per interface.", counter)
+		txDrop := newFamily("systui_network_transmit_drop_total", "Transmitted packets dropped per interface.", counter)
+		for _, stat := range snap.Network {
+			iface := label{"interface", stat.Interface}
+			rxBytes.add(float64(stat.BytesRecv), iface)
+			txBytes.add(float64(stat.BytesSent), iface)
+			rxPackets.add(float64(stat.PacketsRecv), iface)
+			txPackets.add(float64(stat.PacketsSent), iface)
+			rxErrs.add(float64(stat.ErrorsIn), iface)
+			txErrs.add(float64(stat.ErrorsOut), iface)
+			rxDrop.add(float64(stat.DropIn), iface)
+			txDrop.add(float64(stat.DropOut), iface)
+		}
+	}
+
+	if snap.Has(system.GroupProcesses) {
+		states := make(map[string]int)
+		for _, proc := range snap.Processes {
+			states[proc.Status]++
+		}
+		procs := newFamily("systui_processes", "Number of processes by state.", gauge)
+		for _, state := range sortedKeys(states) {
+			procs.add(float64(states[state]), label{"state", state})
+		}
+	}
+
+	if snap.Has(system.GroupServices) {
+		units := newFamily("systui_systemd_unit_state", "Systemd unit state, 1 for the current state.", gauge)
+		for _, svc := range snap.Services {
+			for _, state := range unitStates {
+				value := 0.0
+				if svc.ActiveState == state {
+					value = 1
+				}
+				units.add(value, label{"name", svc.Name}, label{"state", state})
+			}
+		}
+	}
+
+	if snap.Has(system.GroupPackages) && snap.Err(system.GroupPackages) == nil {
+		newFamily("systui_packages_installed", "Number of installed packages.", gauge).
+			add(float64(len(snap.Packages)), label{"manager", string(snap.PackageManager)})
+	}
+
+	success := newFamily("systui_collector_success", "Whether the last sample of a collector group succeeded.", gauge)
+	updated := newFamily("systui_collector_last_update_timestamp_seconds", "Unix time of the last sample of a collector group.", gauge)
+	for _, g := range sortedGroups(snap.Updated) {
+		value := 1.0
+		if snap.Err(g) != nil {
+			value = 0
+		}
+		success.add(value, label{"group", string(g)})
+		updated.add(float64(snap.Updated[g].UnixNano())/1e9, label{"group", string(g)})
+	}
+
+	return families
+}
+
+func addBreakdown(f *family, cpu string, b system.CPUBreakdown) {
+	modes := []struct {
+		name  string
+		value float64
+	}{
+		{"user", b.User},
+		{"system", b.System},
+		{"iowait", b.IOWait},
+		{"steal", b.Steal},
+		{"irq", b.IRQ},
+		{"softirq", b.SoftIRQ},
+		{"idle", b.Idle},
+	}
+	for _, m := range modes {
+		f.add(m.value/100, label{"cpu", cpu}, label{"mode", m.name})
+	}
+}
+
+func writeFamily(w *bufio.Writer, f *family, openMetrics bool) {
+	name := f.name
+	if openMetrics && f.typ == counter {
+		name = strings.TrimSuffix(name, "_total")
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.help, openMetrics))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, f.typ)
+	for _, s := range f.samples {
+		w.WriteString(f.name)
+		if len(s.labels) > 0 {
+			w.WriteByte('{')
+			for i, l := range s.labels {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l.name, escapeLabel(l.value))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatValue(s.value))
+		w.WriteByte('\n')
+	}
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp escapes HELP text, which OpenMetrics escapes like a label
+// value.
+func escapeHelp(s string, openMetrics bool) string {
+	if openMetrics {
+		return labelEscaper.Replace(s)
+	}
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedGroups(m map[system.Group]time.Time) []system.Group {
+	keys := make([]system.Group, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,402 @@
+package logparser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// backends are the parsers the conformance tests run against. The Rust
+// parser is added when it is linked in.
+var backends = map[string]func(pattern, startDate, endDate, severityFilter string) (parser, error){
+	"go": newGoParser,
+}
+
+type conformanceCase struct {
+	name     string
+	input    string
+	pattern  string
+	start    string
+	end      string
+	severity string
+	want     []LogEntry
+}
+
+// exportTime is the Timestamp of a journal export entry written at micros.
+func exportTime(micros int64) string {
+	return time.UnixMicro(micros).Local().Format(TimeFormat)
+}
+
+// utcInLocal converts a UTC time in TimeFormat to local time.
+func utcInLocal(s string) string {
+	t, err := time.Parse(TimeFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Local().Format(TimeFormat)
+}
+
+// binaryField encodes a journal export field in the binary form used for
+// values that contain newlines.
+func binaryField(name, value string) string {
+	size := make([]byte, 8)
+	binary.LittleEndian.PutUint64(size, uint64(len(value)))
+	return name + "\n" + string(size) + value + "\n"
+}
+
+// conformanceCases pin down the rules every parser follows:
+//
+//   - A line is first parsed as syslog, "FIRST DATE HOST SERVICE: MESSAGE":
+//     FIRST is skipped, DATE becomes Timestamp as written and SERVICE runs
+//     up to the first ':'. Fields are separated by spaces and tabs, each
+//     ending at the next space, and whitespace after the ':' is skipped.
+//   - A line that is not syslog is parsed as journalctl output,
+//     "TIMESTAMP HOST NAME[SERVICE] MESSAGE", where SERVICE is what the
+//     brackets hold. Lines that are neither are skipped.
+//   - Severity is ERROR, WARN, INFO or DEBUG, the first whose pattern
+//     matches the message or service, case-insensitively: error|err|failed|
+//     failure, warn|warning, info|information, debug|trace. It is INFO when
+//     none matches.
+//   - In the journal export format (journalctl -o export) an entry is a run
+//     of NAME=value lines, or NAME lines followed by a 64-bit little-endian
+//     length, the value and a newline, ended by a blank or any other line.
+//     It needs a __REALTIME_TIMESTAMP before year 10000, which becomes
+//     Timestamp in local time as TimeFormat; otherwise its lines are parsed
+//     one by one. Severity comes from PRIORITY as for the journal, 6 when it
+//     is not a number, MESSAGE is the message with newlines as spaces, and
+//     SYSLOG_IDENTIFIER or _COMM the source and service, _PID or SYSLOG_PID
+//     the PID and _SYSTEMD_UNIT or UNIT the unit.
+//   - The pattern is a regular expression that must match the message or
+//     the service, in the syntax of the parser's regex engine: the two agree
+//     except that \w, \d, \s and \b are Unicode-aware in Rust and
+//     ASCII-only in Go. The severity filter is a comma-separated list of
+//     severities compared case-insensitively; an entry passes when it has
+//     any of them.
+//   - The date range only applies when both the start and the end date
+//     parse as "%Y-%m-%d %H:%M:%S" in chrono's lenient way. It then
+//     inclusively bounds the local time, to the second, of each line: an
+//     RFC 3339 first field, "YYYY-MM-DDTHH:MM:SS[.frac](Z|+HH:MM|+HHMM)",
+//     or a BSD syslog "Mon D HH:MM:SS" in the current year, or the previous
+//     one when the month is still to come. Export entries use
+//     __REALTIME_TIMESTAMP. Lines without a time are left out.
+//   - A trailing "\r" is removed and invalid UTF-8 is replaced by U+FFFD
+//     per maximal invalid subsequence. NUL bytes are dropped from the
+//     fields returned.
+var conformanceCases = []conformanceCase{
+	{
+		name:  "syslog",
+		input: "Mar  1 12:00:00 web1 sshd[812]: Accepted publickey for root\n",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "Accepted publickey for root"},
+		},
+	},
+	{
+		name:  "syslog service up to the first colon",
+		input: "a 2024 h sshd[1] extra: x: y\na \t b c svc:\t msg\na b c svc:\n",
+		want: []LogEntry{
+			{Timestamp: "2024", Severity: "INFO", Message: "x: y"},
+			{Timestamp: "b", Severity: "INFO", Message: "msg", Offset: 29},
+			{Timestamp: "b", Severity: "INFO", Message: "", Offset: 47},
+		},
+	},
+	{
+		name:  "rfc3339 falls back to journalctl",
+		input: "2024-03-01T12:00:00.123456+01:00 web1 sshd[812]: Accepted publickey for root\n",
+		want: []LogEntry{
+			{Timestamp: "2024-03-01T12:00:00.123456+01:00", Severity: "INFO", Message: ": Accepted publickey for root"},
+		},
+	},
+	{
+		name:  "journalctl brackets",
+		input: "2024-03-01T12:00:00+0100 web1 kernel [ERROR] disk failure\n2024-03-01T12:00:01+0100 web1 app[warn]   low disk\n",
+		want: []LogEntry{
+			{Timestamp: "2024-03-01T12:00:00+0100", Severity: "ERROR", Message: "disk failure"},
+			{Timestamp: "2024-03-01T12:00:01+0100", Severity: "WARN", Message: "low disk", Offset: 58},
+		},
+	},
+	{
+		name: "severity",
+		input: "x 1 h app: connection FAILED\n" +
+			"x 1 h app: Warning: disk almost full\n" +
+			"x 1 h app: debug: cache hit\n" +
+			"x 1 h app: call interrupted\n" +
+			"x 1 h tracer: started\n",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "ERROR", Message: "connection FAILED"},
+			{Timestamp: "1", Severity: "WARN", Message: "Warning: disk almost full", Offset: 29},
+			{Timestamp: "1", Severity: "DEBUG", Message: "debug: cache hit", Offset: 66},
+			{Timestamp: "1", Severity: "ERROR", Message: "call interrupted", Offset: 94},
+			{Timestamp: "1", Severity: "DEBUG", Message: "started", Offset: 122},
+		},
+	},
+	{
+		name: "skipped lines",
+		input: "not a log line\n" +
+			"\n" +
+			"   \n" +
+			"single\n" +
+			"a b c no colon or bracket\n" +
+			"x 1 h a: kept\n",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "kept", Offset: 53},
+		},
+	},
+	{
+		name:  "crlf and missing final newline",
+		input: "x 1 h a: one\r\nx 2 h a: two",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "one"},
+			{Timestamp: "2", Severity: "INFO", Message: "two", Offset: 14},
+		},
+	},
+	{
+		name:  "invalid utf-8 and nul",
+		input: "x 1 h a: bad \xe2\x82 byte \xff \xed\xa0\x80 \xf0\x9f\x98 end\nx 1\x002 h a: x\x00y\n",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "bad \ufffd byte \ufffd \ufffd\ufffd\ufffd \ufffd end"},
+			{Timestamp: "12", Severity: "INFO", Message: "xy", Offset: 35},
+		},
+	},
+	{
+		name:    "pattern on message or service",
+		input:   "x 1 h sshd: Accepted\nx 1 h cron: ran job\nx 1 h app: sshd restarted\n",
+		pattern: `^ssh`,
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "Accepted"},
+			{Timestamp: "1", Severity: "INFO", Message: "sshd restarted", Offset: 41},
+		},
+	},
+	{
+		name:     "severity filter ignores case",
+		input:    "x 1 h a: disk error\nx 1 h a: fine\n",
+		severity: "error",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "ERROR", Message: "disk error"},
+		},
+	},
+	{
+		name:     "severity filter lists several",
+		input:    "x 1 h a: disk error\nx 1 h a: fine\nx 1 h a: low warning\nx 1 h a: debug\n",
+		severity: " warn, ERROR ,",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "ERROR", Message: "disk error"},
+			{Timestamp: "1", Severity: "WARN", Message: "low warning", Offset: 34},
+		},
+	},
+	{
+		name: "date range bounds the rfc 3339 time of each line",
+		input: "2024-03-01T11:59:59Z h a[1] one\n" +
+			"2024-03-01T12:00:00.5Z h a[1] two\n" +
+			"2024-03-01T14:00:00+01:00 h a[1] three\n" +
+			"2024-03-01T07:00:00-0600 h a[1] four\n" +
+			"2024-03-01T13:00:01Z h a[1] five\n" +
+			"x 1 h a: no date\n" +
+			"2024-02-30T12:30:00Z h a[1] no such day\n",
+		start: utcInLocal("2024-03-01 12:00:00"),
+		end:   utcInLocal("2024-03-01 13:00:00"),
+		want: []LogEntry{
+			{Timestamp: "2024-03-01T12:00:00.5Z", Severity: "INFO", Message: "two", Offset: 32},
+			{Timestamp: "2024-03-01T14:00:00+01:00", Severity: "INFO", Message: "three", Offset: 66},
+			{Timestamp: "2024-03-01T07:00:00-0600", Severity: "INFO", Message: "four", Offset: 105},
+		},
+	},
+	{
+		name: "date range bounds the bsd syslog time of each line",
+		input: "Jan  1 10:00:00 h app: a\n" +
+			"Jan  1 12:00:00 h app: b\n" +
+			"Jan\t1 12:00:00 h app: c\n" +
+			"Jan 01 12:00:60 h app: leap\n",
+		start: fmt.Sprintf("%d-01-01 11:00:00", time.Now().Year()),
+		end:   "9999-12-31 23:59:59",
+		want: []LogEntry{
+			{Timestamp: "1", Severity: "INFO", Message: "b", Offset: 25},
+			{Timestamp: "12:00:00", Severity: "INFO", Message: "c", Offset: 50},
+		},
+	},
+	{
+		name:  "date range needs both ends",
+		input: "2024-03-01T11:59:59Z h a[1] one\nx 1 h a: no date\n",
+		start: "2024-03-01 12:00:00",
+		want: []LogEntry{
+			{Timestamp: "2024-03-01T11:59:59Z", Severity: "INFO", Message: "one"},
+			{Timestamp: "1", Severity: "INFO", Message: "no date", Offset: 32},
+		},
+	},
+	{
+		name: "export",
+		input: "__CURSOR=s=1;i=1\n__REALTIME_TIMESTAMP=1709294400000000\nPRIORITY=3\n_SYSTEMD_UNIT=ssh.service\n" +
+			"SYSLOG_IDENTIFIER=sshd\n_PID=812\nMESSAGE=Accepted publickey for root\n\n" +
+			"__REALTIME_TIMESTAMP=1709294401000000\n_COMM=cron\nSYSLOG_PID=99\nUNIT=cron.service\nMESSAGE=ran job\n\n",
+		want: []LogEntry{
+			{Timestamp: exportTime(1709294400000000), Severity: "ERROR", Message: "Accepted publickey for root", Source: "sshd", Unit: "ssh.service", PID: 812},
+			{Timestamp: exportTime(1709294401000000), Severity: "INFO", Message: "ran job", Source: "cron", Unit: "cron.service", PID: 99, Offset: 161},
+		},
+	},
+	{
+		name: "export priorities",
+		input: "__REALTIME_TIMESTAMP=1\nPRIORITY=4\nMESSAGE=a\n\n" +
+			"__REALTIME_TIMESTAMP=2\nPRIORITY=5\nMESSAGE=b\n\n" +
+			"__REALTIME_TIMESTAMP=3\nPRIORITY=7\nMESSAGE=c error\n\n" +
+			"__REALTIME_TIMESTAMP=4\nPRIORITY=-1\nMESSAGE=d\n\n" +
+			"__REALTIME_TIMESTAMP=5\nPRIORITY=99999999999999999999\nMESSAGE=e\n\n",
+		want: []LogEntry{
+			{Timestamp: exportTime(1), Severity: "WARN", Message: "a"},
+			{Timestamp: exportTime(2), Severity: "INFO", Message: "b", Offset: 45},
+			{Timestamp: exportTime(3), Severity: "DEBUG", Message: "c error", Offset: 90},
+			{Timestamp: exportTime(4), Severity: "INFO", Message: "d", Offset: 141},
+			{Timestamp: exportTime(5), Severity: "DEBUG", Message: "e", Offset: 187},
+		},
+	},
+	{
+		name: "export binary field",
+		input: "__REALTIME_TIMESTAMP=1709294400000000\n" + binaryField("MESSAGE", "first line\nsecond line\n") +
+			"_PID=12x\nSYSLOG_IDENTIFIER=app\n\n",
+		want: []LogEntry{
+			{Timestamp: exportTime(1709294400000000), Severity: "INFO", Message: "first line second line", Source: "app"},
+		},
+	},
+	{
+		name: "export ended by another line",
+		input: "__REALTIME_TIMESTAMP=1709294400000000\nMESSAGE=one\n" +
+			"x 1 h a: two\n" +
+			"__REALTIME_TIMESTAMP=1709294400000000\nMESSAGE=three",
+		want: []LogEntry{
+			{Timestamp: exportTime(1709294400000000), Severity: "INFO", Message: "one"},
+			{Timestamp: "1", Severity: "INFO", Message: "two", Offset: 50},
+			{Timestamp: exportTime(1709294400000000), Severity: "INFO", Message: "three", Offset: 63},
+		},
+	},
+	{
+		name: "fields without a realtime are lines",
+		input: "FOO=a b c d: kept\nMESSAGE=no time\n\n" +
+			"__REALTIME_TIMESTAMP=+1\nBAR=x 1 h a: also kept\n\n" +
+			"__REALTIME_TIMESTAMP=253402300800000000\nMESSAGE=year 10000\n\n" +
+			binaryField("MESSAGE", "truncated")[:12],
+		want: []LogEntry{
+			{Timestamp: "b", Severity: "INFO", Message: "kept"},
+			{Timestamp: "1", Severity: "INFO", Message: "also kept", Offset: 59},
+		},
+	},
+	{
+		name:     "export filters",
+		input:    "__REALTIME_TIMESTAMP=1\nPRIORITY=3\nSYSLOG_IDENTIFIER=sshd\nMESSAGE=a\n\n__REALTIME_TIMESTAMP=2\nPRIORITY=3\nMESSAGE=sshd b\n\n__REALTIME_TIMESTAMP=3\nPRIORITY=6\nMESSAGE=sshd c\n\n",
+		pattern:  "^ssh",
+		severity: "error",
+		want: []LogEntry{
+			{Timestamp: exportTime(1), Severity: "ERROR", Message: "a", Source: "sshd"},
+			{Timestamp: exportTime(2), Severity: "ERROR", Message: "sshd b", Offset: 68},
+		},
+	},
+	{
+		name:  "export date range",
+		input: "__REALTIME_TIMESTAMP=1709294399000000\nMESSAGE=1\n\n__REALTIME_TIMESTAMP=1709294400000000\nMESSAGE=2\n\n__REALTIME_TIMESTAMP=1709294401000000\nMESSAGE=3\n\n",
+		start: exportTime(1709294400000000),
+		end:   exportTime(1709294400000000),
+		want: []LogEntry{
+			{Timestamp: exportTime(1709294400000000), Severity: "INFO", Message: "2", Offset: 49},
+		},
+	},
+}
+
+func TestConformance(t *testing.T) {
+	for name, newBackend := range backends {
+		for _, tc := range conformanceCases {
+			t.Run(name+"/"+tc.name, func(t *testing.T) {
+				p, err := newBackend(tc.pattern, tc.start, tc.end, tc.severity)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer p.close()
+
+				got := (&Filter{parser: p}).Parse([]byte(tc.input), 0)
+				want := tc.want
+				if want == nil {
+					want = []LogEntry{}
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got  %+v\nwant %+v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestConformanceBase(t *testing.T) {
+	for name, newBackend := range backends {
+		p, err := newBackend("", "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := (&Filter{parser: p}).Parse([]byte("x 1 h a: x\nx 1 h a: y\n"), 1000)
+		p.close()
+		if len(got) != 2 || got[0].Offset != 1000 || got[1].Offset != 1011 {
+			t.Errorf("%s: offsets %+v", name, got)
+		}
+	}
+}
+
+func TestConformanceInvalidPattern(t *testing.T) {
+	for name, newBackend := range backends {
+		if p, err := newBackend("(unclosed", "", "", ""); err == nil {
+			p.close()
+			t.Errorf("%s: accepted an invalid pattern", name)
+		}
+	}
+}
+
+// FuzzConformance checks that all backends agree on arbitrary input.
+func FuzzConformance(f *testing.F) {
+	for _, tc := range conformanceCases {
+		f.Add(tc.input, tc.pattern, tc.start, tc.end)
+	}
+	f.Add("Dec 31 23:59:59 h a[1]: x\nJan  1 00:00:00 h b[y] z\n", "x|y", "", "")
+	f.Add("x \xc0\xaf \xf4\x90\x80\x80 \xef\xbf\xbd: z\n", "", "", "")
+	f.Add("x  +2024-03-01\v1:2:3 h a: 1\nx -1-1-1\u00a00:0:60 h a: 2\n", "", "-0001-01-01 00:00:00", "2024-3-1 1:2:3")
+	f.Add("2024-03-01T12:00:00.1+05:30 h a[1] x\nFeb 29 23:59:59 h a: y\nDec\t31 00:00:00 h a: z\n", "", "0-1-1 0:0:0", "9999-12-31 23:59:59")
+	f.Add("__REALTIME_TIMESTAMP=1\nMESSAGE\n\x02\x00\x00\x00\x00\x00\x00\x00a\n\n\nPRIORITY=x\n", "", "", "")
+
+	f.Fuzz(func(t *testing.T, input, pattern, start, end string) {
+		if len(backends) < 2 {
+			t.Skip("only one backend is built")
+		}
+		// The regular expression dialects differ at the edges: patterns
+		// one of them rejects, and the Unicode-aware classes of Rust
+		// against the ASCII ones of Go. Those inputs are compared without
+		// the pattern. Filter arguments are C strings and cannot hold NUL.
+		if strings.Contains(pattern, `\`) {
+			pattern = ""
+		}
+		pattern = strings.ReplaceAll(pattern, "\x00", "")
+		start = strings.ReplaceAll(start, "\x00", "")
+		end = strings.ReplaceAll(end, "\x00", "")
+		for _, newBackend := range backends {
+			p, err := newBackend(pattern, start, end, "")
+			if err != nil {
+				pattern = ""
+				break
+			}
+			p.close()
+		}
+
+		var first []LogEntry
+		var firstName string
+		for name, newBackend := range backends {
+			p, err := newBackend(pattern, start, end, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := (&Filter{parser: p}).Parse([]byte(input), 0)
+			p.close()
+			if first == nil {
+				first, firstName = got, name
+				continue
+			}
+			if !reflect.DeepEqual(got, first) {
+				t.Errorf("%s and %s disagree on %q (pattern %q, %q to %q):\n%+v\n%+v", firstName, name, input, pattern, start, end, first, got)
+			}
+		}
+	})
+}
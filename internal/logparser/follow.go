@@ -0,0 +1,187 @@
+package logparser
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// maxTailRead bounds how much of a followed file is read at once, so that a
+// burst of writes is parsed over several reads.
+const maxTailRead = 8 << 20
+
+// Position is where following a log left off: the inode of the file and
+// the offset of the first byte not yet read, or for the journal the cursor
+// of the last entry read. The zero Position stands for the current end.
+type Position struct {
+	Inode  uint64
+	Offset int64
+	Cursor string
+}
+
+// Tail holds the entries written to a log since a position and the position
+// to continue from.
+type Tail struct {
+	Entries  []LogEntry
+	Position Position
+}
+
+// TailFile parses the complete lines written to path since pos. When the
+// file was replaced (log rotation), what was written to the old file after
+// pos is read first, if it is found next to path under another name, and
+// then the new file from the start. A file that became shorter than pos
+// (truncation) is read again from the start.
+func TailFile(path string, pos Position, pattern, startDate, endDate, severityFilter string) (*Tail, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("%s: cannot follow this file", path)
+	}
+	size := info.Size()
+
+	tail := &Tail{Entries: []LogEntry{}, Position: Position{Inode: stat.Ino, Offset: pos.Offset}}
+	if pos.Inode == 0 {
+		tail.Position.Offset = size
+		return tail, nil
+	}
+
+	filter, err := NewFilter(pattern, startDate, endDate, severityFilter)
+	if err != nil {
+		return nil, err
+	}
+	defer filter.Close()
+
+	if pos.Inode != stat.Ino {
+		if old, oldSize := openRotated(path, uint64(stat.Dev), pos.Inode); old != nil {
+			defer old.Close()
+			// Nothing more is written to the old file, so its last line
+			// is complete even without a newline. A remainder larger
+			// than maxTailRead keeps the position in the old file.
+			entries, offset, err := readTail(old, pos.Offset, oldSize, true, filter)
+			if err != nil {
+				return nil, err
+			}
+			tail.Entries = entries
+			if offset < oldSize {
+				tail.Position = Position{Inode: pos.Inode, Offset: offset}
+				return tail, nil
+			}
+		}
+		tail.Position.Offset = 0
+	} else if size < pos.Offset {
+		tail.Position.Offset = 0
+	}
+
+	entries, offset, err := readTail(file, tail.Position.Offset, size, false, filter)
+	if err != nil {
+		return nil, err
+	}
+	tail.Entries = append(tail.Entries, entries...)
+	tail.Position.Offset = offset
+	return tail, nil
+}
+
+// readTail parses at most maxTailRead bytes of file from offset and
+// returns the entries and the offset to continue from. A line or journal
+// export entry still being written is left for the next read, unless it
+// alone fills the buffer or final is set and the read reaches size.
+func readTail(file *os.File, offset, size int64, final bool, filter *Filter) ([]LogEntry, int64, error) {
+	n := size - offset
+	if n <= 0 {
+		return nil, offset, nil
+	}
+	if n > maxTailRead {
+		n = maxTailRead
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(io.NewSectionReader(file, offset, n), buf); err != nil {
+		return nil, offset, err
+	}
+
+	if !final || offset+n < size {
+		if end := chunkEnd(buf); end > 0 {
+			buf = buf[:end]
+		} else if n < maxTailRead {
+			return nil, offset, nil
+		}
+	}
+	return filter.Parse(buf, offset), offset + int64(len(buf)), nil
+}
+
+// openRotated opens the file in the directory of path, such as path.1,
+// that has the given inode: where rotation moved the file that was at
+// path. It returns nil when there is none.
+func openRotated(path string, dev, ino uint64) (*os.File, int64) {
+	dir, base := filepath.Split(path)
+	entries, _ := os.ReadDir(filepath.Clean(dir))
+	for _, e := range entries {
+		if len(e.Name()) <= len(base) || !strings.HasPrefix(e.Name(), base) {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		info, err := file.Stat()
+		if err == nil {
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok && uint64(stat.Dev) == dev && stat.Ino == ino {
+				return file, info.Size()
+			}
+		}
+		file.Close()
+	}
+	return nil, 0
+}
+
+// TailJournal returns all the journal entries matching q after q.After,
+// read in pages of q.Limit. Without a cursor it returns no entries and the
+// position of the newest entry.
+func TailJournal(q JournalQuery) (*Tail, error) {
+	if q.After == "" {
+		latest, err := ReadJournal(context.Background(), JournalQuery{Dirs: q.Dirs, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+		tail := &Tail{Entries: []LogEntry{}}
+		if len(latest) > 0 {
+			tail.Position.Cursor = latest[0].Cursor
+		}
+		return tail, nil
+	}
+
+	// A page holds the oldest entries after the cursor, so a burst larger
+	// than the limit is read in full over several pages.
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultJournalLimit
+	}
+	q.Limit = limit
+	tail := &Tail{Entries: []LogEntry{}, Position: Position{Cursor: q.After}}
+	for {
+		page, err := readJournal(context.Background(), q, true)
+		if err != nil {
+			return nil, err
+		}
+		tail.Entries = append(tail.Entries, page...)
+		if len(page) > 0 {
+			q.After = page[len(page)-1].Cursor
+			tail.Position.Cursor = q.After
+		}
+		if len(page) < limit {
+			return tail, nil
+		}
+	}
+}
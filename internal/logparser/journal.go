@@ -0,0 +1,503 @@
+package logparser
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JournalSource names the systemd journal in place of a log file path.
+const JournalSource = "journal"
+
+// TimeFormat is the layout of LogEntry.Timestamp for journal entries and of
+// the start and end of a query.
+const TimeFormat = "2006-01-02 15:04:05"
+
+// JournalDirs are searched for journal files when a query names none:
+// the persistent journal first, then the volatile one.
+var JournalDirs = []string{"/var/log/journal", "/run/log/journal"}
+
+const defaultJournalLimit = 5000
+
+// JournalQuery selects journal entries. Matches on different fields must
+// all hold; listing several units or a priority range accepts any of them.
+type JournalQuery struct {
+	// Dirs holds the journal directories to read, JournalDirs when empty.
+	Dirs []string
+	// After is the cursor of the last entry already read. Only later
+	// entries are returned.
+	After string
+	Units []string
+	// Priority is the lowest priority to include, as a syslog level name
+	// such as "warning" or a number from 0 (emerg) to 7 (debug).
+	Priority string
+	// BootID is a boot ID or "current".
+	BootID string
+	PID    int32
+	Since  time.Time
+	Until  time.Time
+	// Severity keeps the entries whose priority maps to one of a
+	// comma-separated list of ERROR, WARN, INFO and DEBUG.
+	Severity string
+	// Pattern is a regular expression matched against the message and
+	// the source of each entry.
+	Pattern string
+	// Limit is the number of newest matching entries to return.
+	Limit int
+}
+
+var priorityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// ParsePriority accepts a syslog level name or number.
+func ParsePriority(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 7 {
+		return n, nil
+	}
+	for i, name := range priorityNames {
+		if s == name || (s == "error" && i == 3) || (s == "warn" && i == 4) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown priority %q", s)
+}
+
+// ParseTime parses the start or end of a query in local time. An empty
+// string gives the zero time, which leaves that end of the range open.
+func ParseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(TimeFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD HH:MM:SS", s)
+	}
+	return t, nil
+}
+
+// prioritySeverity maps a journal PRIORITY to the severities the log
+// parser assigns to log file lines.
+func prioritySeverity(priority int) string {
+	switch {
+	case priority <= 3:
+		return "ERROR"
+	case priority == 4:
+		return "WARN"
+	case priority <= 6:
+		return "INFO"
+	}
+	return "DEBUG"
+}
+
+// journalCursor is the position of an entry, in the format journalctl uses
+// so that cursors can be passed between the two.
+type journalCursor struct {
+	seqnumID [16]byte
+	seqnum   uint64
+	bootID   [16]byte
+	mono     uint64
+	realtime uint64
+	xorHash  uint64
+}
+
+func (c journalCursor) String() string {
+	return fmt.Sprintf("s=%x;i=%x;b=%x;m=%x;t=%x;x=%x",
+		c.seqnumID, c.seqnum, c.bootID, c.mono, c.realtime, c.xorHash)
+}
+
+func parseCursor(s string) (journalCursor, error) {
+	var c journalCursor
+	var hasSeqnum, hasTime bool
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return c, fmt.Errorf("invalid journal cursor %q", s)
+		}
+		var err error
+		switch key {
+		case "s":
+			err = parseID(value, &c.seqnumID)
+		case "i":
+			c.seqnum, err = strconv.ParseUint(value, 16, 64)
+			hasSeqnum = true
+		case "b":
+			err = parseID(value, &c.bootID)
+		case "m":
+			c.mono, err = strconv.ParseUint(value, 16, 64)
+		case "t":
+			c.realtime, err = strconv.ParseUint(value, 16, 64)
+			hasTime = true
+		case "x":
+			c.xorHash, err = strconv.ParseUint(value, 16, 64)
+		}
+		if err != nil {
+			return c, fmt.Errorf("invalid journal cursor %q", s)
+		}
+	}
+	if !hasSeqnum || !hasTime {
+		return c, fmt.Errorf("invalid journal cursor %q", s)
+	}
+	return c, nil
+}
+
+func parseID(s string, id *[16]byte) error {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != 16 {
+		return fmt.Errorf("invalid ID %q", s)
+	}
+	copy(id[:], b)
+	return nil
+}
+
+// after reports whether an entry comes after the cursor. Sequence numbers
+// only order entries written by the same journald instance.
+func (c journalCursor) after(seqnumID [16]byte, h journalEntryHeader) bool {
+	if seqnumID == c.seqnumID {
+		return h.seqnum > c.seqnum
+	}
+	return h.realtime > c.realtime
+}
+
+// journalMatch holds the clauses an entry must all satisfy, each as
+// "FIELD=value" payloads of which the entry must carry at least one.
+type journalMatch [][]string
+
+func (q JournalQuery) match() (journalMatch, error) {
+	var match journalMatch
+
+	if len(q.Units) > 0 {
+		var clause []string
+		for _, unit := range q.Units {
+			if !strings.Contains(unit, ".") {
+				unit += ".service"
+			}
+			// UNIT is set on systemd's own messages about the unit.
+			clause = append(clause, "_SYSTEMD_UNIT="+unit, "UNIT="+unit)
+		}
+		match = append(match, clause)
+	}
+
+	if q.Priority != "" {
+		max, err := ParsePriority(q.Priority)
+		if err != nil {
+			return nil, err
+		}
+		var clause []string
+		for p := 0; p <= max; p++ {
+			clause = append(clause, "PRIORITY="+strconv.Itoa(p))
+		}
+		match = append(match, clause)
+	}
+
+	if severities := severityList(q.Severity); severities != nil {
+		var clause []string
+		for _, severity := range severities {
+			found := false
+			for p := 0; p <= 7; p++ {
+				if strings.EqualFold(prioritySeverity(p), severity) {
+					clause = append(clause, "PRIORITY="+strconv.Itoa(p))
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown severity %q", severity)
+			}
+		}
+		match = append(match, clause)
+	}
+
+	if q.BootID != "" {
+		id := q.BootID
+		if id == "current" {
+			data, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+			if err != nil {
+				return nil, err
+			}
+			id = strings.TrimSpace(string(data))
+		}
+		var boot [16]byte
+		if err := parseID(id, &boot); err != nil {
+			return nil, fmt.Errorf("invalid boot ID %q", q.BootID)
+		}
+		match = append(match, []string{fmt.Sprintf("_BOOT_ID=%x", boot)})
+	}
+
+	if q.PID > 0 {
+		match = append(match, []string{"_PID=" + strconv.Itoa(int(q.PID))})
+	}
+	return match, nil
+}
+
+// ReadJournal returns the newest entries matching the query, oldest first.
+// The Cursor of the last one can be passed as After to read what follows.
+// Reading stops when ctx is done.
+func ReadJournal(ctx context.Context, q JournalQuery) ([]LogEntry, error) {
+	return readJournal(ctx, q, false)
+}
+
+// readJournal returns the newest entries matching the query, or with
+// oldest set the oldest ones, in both cases oldest first.
+func readJournal(ctx context.Context, q JournalQuery, oldest bool) ([]LogEntry, error) {
+	match, err := q.match()
+	if err != nil {
+		return nil, err
+	}
+	var pattern *regexp.Regexp
+	if q.Pattern != "" {
+		if pattern, err = compileJournalPattern(q.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	var after *journalCursor
+	if q.After != "" {
+		c, err := parseCursor(q.After)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultJournalLimit
+	}
+	dirs := q.Dirs
+	if len(dirs) == 0 {
+		dirs = JournalDirs
+	}
+
+	paths := journalFiles(dirs)
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no journal files in %s", strings.Join(dirs, " or "))
+	}
+
+	var found []journalHit
+	var firstErr error
+	opened := 0
+	for _, path := range paths {
+		f, err := openJournalFile(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		opened++
+		found = append(found, f.search(ctx, match, pattern, after, q.Since, q.Until, limit, oldest)...)
+		f.close()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if opened == 0 {
+		if errors.Is(firstErr, os.ErrPermission) {
+			return nil, errors.New("permission denied reading the journal; run as root or as a member of the systemd-journal or adm group")
+		}
+		return nil, firstErr
+	}
+
+	// Files cover overlapping time spans, so the newest entries overall are
+	// only known once all files are read.
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].realtime != found[j].realtime {
+			return found[i].realtime < found[j].realtime
+		}
+		return found[i].seqnum < found[j].seqnum
+	})
+	if len(found) > limit {
+		if oldest {
+			found = found[:limit]
+		} else {
+			found = found[len(found)-limit:]
+		}
+	}
+
+	entries := make([]LogEntry, len(found))
+	for i, hit := range found {
+		entries[i] = hit.entry
+	}
+	return entries, nil
+}
+
+// compileJournalPattern compiles the pattern of a journal query, which
+// unlike those of log files is always Go's regexp.
+func compileJournalPattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// journalFiles lists the journal files of this machine in dirs. Files
+// journald set aside as corrupt end in "~" and are skipped.
+func journalFiles(dirs []string) []string {
+	machine := ""
+	if data, err := os.ReadFile("/etc/machine-id"); err == nil {
+		machine = strings.TrimSpace(string(data))
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		sub := []string{dir}
+		if machine != "" {
+			sub = append(sub, filepath.Join(dir, machine))
+		} else if entries, err := os.ReadDir(dir); err == nil {
+			for _, e := range entries {
+				if e.IsDir() {
+					sub = append(sub, filepath.Join(dir, e.Name()))
+				}
+			}
+		}
+		for _, d := range sub {
+			matches, _ := filepath.Glob(filepath.Join(d, "*.journal"))
+			paths = append(paths, matches...)
+		}
+	}
+	return paths
+}
+
+type journalHit struct {
+	realtime uint64
+	seqnum   uint64
+	entry    LogEntry
+}
+
+// search walks the file from its newest entry back, stopping at the cursor,
+// at the start of the time range or once limit entries matched. With oldest
+// set it walks forward from the cursor instead, stopping at the end of the
+// time range or once limit entries matched. Both stop when ctx is done.
+func (f *journalFile) search(ctx context.Context, match journalMatch, pattern *regexp.Regexp, after *journalCursor, since, until time.Time, limit int, oldest bool) []journalHit {
+	if !since.IsZero() && f.tailRealtime() != 0 && f.tailRealtime() < uint64(since.UnixMicro()) {
+		return nil
+	}
+	if !until.IsZero() && f.headRealtime() > uint64(until.UnixMicro()) {
+		return nil
+	}
+
+	// Matching compares hashes first and payloads only when a hash agrees.
+	hashes := make([]map[uint64][]byte, len(match))
+	for i, clause := range match {
+		hashes[i] = make(map[uint64][]byte, len(clause))
+		for _, payload := range clause {
+			hashes[i][f.hash([]byte(payload))] = []byte(payload)
+		}
+	}
+
+	var hits []journalHit
+	each := f.eachEntry
+	if oldest {
+		each = f.eachEntryForward
+	}
+	each(func(offset uint64) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		h, ok := f.entryHeader(offset)
+		if !ok {
+			return true
+		}
+		if after != nil && !after.after(f.seqnumID, h) {
+			return oldest
+		}
+		if !since.IsZero() && h.realtime < uint64(since.UnixMicro()) {
+			return oldest
+		}
+		if !until.IsZero() && h.realtime > uint64(until.UnixMicro()) {
+			return !oldest
+		}
+		if !f.matches(offset, hashes) {
+			return true
+		}
+		entry := f.logEntry(h)
+		if pattern != nil && !pattern.MatchString(entry.Message) && !pattern.MatchString(entry.Source) {
+			return true
+		}
+		hits = append(hits, journalHit{realtime: h.realtime, seqnum: h.seqnum, entry: entry})
+		return len(hits) < limit
+	})
+	return hits
+}
+
+func (f *journalFile) matches(offset uint64, hashes []map[uint64][]byte) bool {
+	if len(hashes) == 0 {
+		return true
+	}
+	satisfied := make([]bool, len(hashes))
+	f.entryItems(offset, func(data, hash uint64) {
+		for i, clause := range hashes {
+			if want, ok := clause[hash]; ok && !satisfied[i] {
+				if payload, err := f.payload(data); err == nil && bytes.Equal(payload, want) {
+					satisfied[i] = true
+				}
+			}
+		}
+	})
+	for _, ok := range satisfied {
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *journalFile) logEntry(h journalEntryHeader) LogEntry {
+	fields := make(map[string]string)
+	f.entryItems(h.offset, func(data, _ uint64) {
+		payload, err := f.payload(data)
+		if err != nil {
+			return
+		}
+		name, value, ok := bytes.Cut(payload, []byte("="))
+		if !ok {
+			return
+		}
+		switch key := string(name); key {
+		case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER", "_COMM", "_PID", "SYSLOG_PID", "_SYSTEMD_UNIT", "UNIT":
+			fields[key] = string(value)
+		}
+	})
+
+	priority := 6
+	if p, err := strconv.Atoi(fields["PRIORITY"]); err == nil {
+		priority = p
+	}
+	source := fields["SYSLOG_IDENTIFIER"]
+	if source == "" {
+		source = fields["_COMM"]
+	}
+	pid := fields["_PID"]
+	if pid == "" {
+		pid = fields["SYSLOG_PID"]
+	}
+	unit := fields["_SYSTEMD_UNIT"]
+	if unit == "" {
+		unit = fields["UNIT"]
+	}
+	n, _ := strconv.ParseInt(pid, 10, 32)
+
+	return LogEntry{
+		Timestamp: time.UnixMicro(int64(h.realtime)).Local().Format(TimeFormat),
+		Severity:  prioritySeverity(priority),
+		Message:   strings.ReplaceAll(strings.TrimRight(fields["MESSAGE"], "\n"), "\n", " "),
+		Source:    source,
+		Unit:      unit,
+		PID:       int32(n),
+		Cursor: journalCursor{
+			seqnumID: f.seqnumID,
+			seqnum:   h.seqnum,
+			bootID:   h.bootID,
+			mono:     h.monotonic,
+			realtime: h.realtime,
+			xorHash:  h.xorHash,
+		}.String(),
+	}
+}
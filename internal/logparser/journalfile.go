@@ -0,0 +1,344 @@
+package logparser
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/sys/unix"
+)
+
+// The layout of journal files is described in
+// https://systemd.io/JOURNAL_FILE_FORMAT/. All integers are little-endian
+// and all objects are 8-byte aligned.
+
+const journalSignature = "LPKSHHRH"
+
+// Incompatible header flags.
+const (
+	journalCompressedXZ   = 1 << 0
+	journalCompressedLZ4  = 1 << 1
+	journalKeyedHash      = 1 << 2
+	journalCompressedZSTD = 1 << 3
+	journalCompact        = 1 << 4
+	journalKnownFlags     = 1<<5 - 1
+)
+
+// Object types and flags.
+const (
+	objectData       = 1
+	objectEntry      = 3
+	objectEntryArray = 6
+
+	objectCompressedXZ   = 1 << 0
+	objectCompressedLZ4  = 1 << 1
+	objectCompressedZSTD = 1 << 2
+)
+
+const (
+	objectHeaderSize = 16
+	// journalDataLimit bounds the size of a field after decompression, as
+	// sd-journal's default data threshold does.
+	journalDataLimit = 64 << 10
+)
+
+var errJournalCorrupt = errors.New("journal file is corrupt")
+
+// journalFile is a journal file mapped into memory. Journal files are
+// written in place by journald, so the mapping covers what existed when the
+// file was opened and every offset read from it is checked.
+type journalFile struct {
+	path     string
+	data     []byte
+	fileID   [16]byte
+	seqnumID [16]byte
+	compact  bool
+	keyed    bool
+}
+
+type journalEntryHeader struct {
+	offset    uint64
+	seqnum    uint64
+	realtime  uint64
+	monotonic uint64
+	bootID    [16]byte
+	xorHash   uint64
+}
+
+func openJournalFile(path string) (*journalFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < 272 {
+		return nil, fmt.Errorf("%s: not a journal file", path)
+	}
+
+	data, err := unix.Mmap(int(file.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	f := &journalFile{path: path, data: data}
+	if string(data[:8]) != journalSignature {
+		f.close()
+		return nil, fmt.Errorf("%s: not a journal file", path)
+	}
+	incompatible := binary.LittleEndian.Uint32(data[12:])
+	if incompatible&^journalKnownFlags != 0 {
+		f.close()
+		return nil, fmt.Errorf("%s: unsupported journal format (flags %#x)", path, incompatible)
+	}
+	f.compact = incompatible&journalCompact != 0
+	f.keyed = incompatible&journalKeyedHash != 0
+	copy(f.fileID[:], data[24:40])
+	copy(f.seqnumID[:], data[72:88])
+	return f, nil
+}
+
+func (f *journalFile) close() {
+	unix.Munmap(f.data)
+}
+
+func (f *journalFile) headerUint64(offset int) uint64 {
+	return binary.LittleEndian.Uint64(f.data[offset:])
+}
+
+func (f *journalFile) headRealtime() uint64 { return f.headerUint64(184) }
+func (f *journalFile) tailRealtime() uint64 { return f.headerUint64(192) }
+
+// hash hashes a "FIELD=value" payload as the file's data objects are.
+func (f *journalFile) hash(payload []byte) uint64 {
+	if f.keyed {
+		return sipHash24(f.fileID, payload)
+	}
+	return jenkinsHash64(payload)
+}
+
+// object returns the object of type typ at offset, or nil when there is no
+// such object within the mapping.
+func (f *journalFile) object(offset uint64, typ byte) []byte {
+	if offset == 0 || offset%8 != 0 || offset+objectHeaderSize > uint64(len(f.data)) {
+		return nil
+	}
+	size := binary.LittleEndian.Uint64(f.data[offset+8:])
+	if f.data[offset] != typ || size < objectHeaderSize || size > uint64(len(f.data))-offset {
+		return nil
+	}
+	return f.data[offset : offset+size]
+}
+
+// entryArrays lists the entry arrays of the file in order with the number
+// of items in each. Arrays grow geometrically, so there are few of them.
+func (f *journalFile) entryArrays() []journalArray {
+	var arrays []journalArray
+	itemSize := uint64(8)
+	if f.compact {
+		itemSize = 4
+	}
+	seen := make(map[uint64]bool)
+	for offset := f.headerUint64(176); offset != 0 && !seen[offset]; {
+		seen[offset] = true
+		obj := f.object(offset, objectEntryArray)
+		if obj == nil || len(obj) < 24 {
+			break
+		}
+		arrays = append(arrays, journalArray{items: obj[24:], itemSize: itemSize})
+		offset = binary.LittleEndian.Uint64(obj[16:])
+	}
+	return arrays
+}
+
+type journalArray struct {
+	items    []byte
+	itemSize uint64
+}
+
+func (a journalArray) len() int {
+	return len(a.items) / int(a.itemSize)
+}
+
+func (a journalArray) at(i int) uint64 {
+	if a.itemSize == 4 {
+		return uint64(binary.LittleEndian.Uint32(a.items[i*4:]))
+	}
+	return binary.LittleEndian.Uint64(a.items[i*8:])
+}
+
+// eachEntry calls fn with the offset of every entry from the newest to the
+// oldest until fn returns false.
+func (f *journalFile) eachEntry(fn func(offset uint64) bool) {
+	arrays := f.entryArrays()
+	for a := len(arrays) - 1; a >= 0; a-- {
+		for i := arrays[a].len() - 1; i >= 0; i-- {
+			// Unused items at the end of the last array are zero.
+			if offset := arrays[a].at(i); offset != 0 && !fn(offset) {
+				return
+			}
+		}
+	}
+}
+
+// eachEntryForward calls fn with the offset of every entry from the oldest
+// to the newest until fn returns false.
+func (f *journalFile) eachEntryForward(fn func(offset uint64) bool) {
+	for _, array := range f.entryArrays() {
+		for i := 0; i < array.len(); i++ {
+			if offset := array.at(i); offset != 0 && !fn(offset) {
+				return
+			}
+		}
+	}
+}
+
+func (f *journalFile) entryHeader(offset uint64) (journalEntryHeader, bool) {
+	obj := f.object(offset, objectEntry)
+	if len(obj) < 64 {
+		return journalEntryHeader{}, false
+	}
+	h := journalEntryHeader{
+		offset:    offset,
+		seqnum:    binary.LittleEndian.Uint64(obj[16:]),
+		realtime:  binary.LittleEndian.Uint64(obj[24:]),
+		monotonic: binary.LittleEndian.Uint64(obj[32:]),
+		xorHash:   binary.LittleEndian.Uint64(obj[56:]),
+	}
+	copy(h.bootID[:], obj[40:56])
+	return h, true
+}
+
+// entryItems calls fn with the offset and hash of each data object of the
+// entry at offset. Compact files keep the hash in the data object only.
+func (f *journalFile) entryItems(offset uint64, fn func(data, hash uint64)) {
+	obj := f.object(offset, objectEntry)
+	if len(obj) < 64 {
+		return
+	}
+	items := obj[64:]
+	if f.compact {
+		for ; len(items) >= 4; items = items[4:] {
+			data := uint64(binary.LittleEndian.Uint32(items))
+			if d := f.object(data, objectData); len(d) >= 24 {
+				fn(data, binary.LittleEndian.Uint64(d[16:]))
+			}
+		}
+		return
+	}
+	for ; len(items) >= 16; items = items[16:] {
+		fn(binary.LittleEndian.Uint64(items), binary.LittleEndian.Uint64(items[8:]))
+	}
+}
+
+// payload returns the "FIELD=value" payload of the data object at offset,
+// decompressing it if needed.
+func (f *journalFile) payload(offset uint64) ([]byte, error) {
+	obj := f.object(offset, objectData)
+	start := 64
+	if f.compact {
+		start = 72
+	}
+	if len(obj) < start {
+		return nil, errJournalCorrupt
+	}
+	payload := obj[start:]
+
+	switch flags := obj[1]; {
+	case flags&objectCompressedZSTD != 0:
+		data, err := journalZstd.DecodeAll(payload, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > journalDataLimit {
+			data = data[:journalDataLimit]
+		}
+		return data, nil
+	case flags&objectCompressedLZ4 != 0:
+		return decompressLZ4(payload)
+	case flags&objectCompressedXZ != 0:
+		return nil, errors.New("xz-compressed journal fields are not supported")
+	}
+	if len(payload) > journalDataLimit {
+		payload = payload[:journalDataLimit]
+	}
+	return payload, nil
+}
+
+// journalZstd decodes the zstd-compressed fields of every journal file. Its
+// DecodeAll may be called concurrently. Fields far above journalDataLimit
+// fail rather than being decompressed in full.
+var journalZstd, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(64<<20))
+
+// decompressLZ4 decodes systemd's LZ4 payloads: the uncompressed size as a
+// 64-bit integer followed by one LZ4 block.
+func decompressLZ4(src []byte) ([]byte, error) {
+	if len(src) < 8 {
+		return nil, errJournalCorrupt
+	}
+	size := binary.LittleEndian.Uint64(src)
+	if size > journalDataLimit {
+		size = journalDataLimit
+	}
+	src = src[8:]
+	dst := make([]byte, 0, size)
+
+	for len(src) > 0 && uint64(len(dst)) < size {
+		token := src[0]
+		src = src[1:]
+
+		literals, err := lz4Length(&src, int(token>>4))
+		if err != nil || literals > len(src) {
+			return nil, errJournalCorrupt
+		}
+		dst = append(dst, src[:literals]...)
+		src = src[literals:]
+		if len(src) == 0 {
+			break
+		}
+
+		if len(src) < 2 {
+			return nil, errJournalCorrupt
+		}
+		distance := int(binary.LittleEndian.Uint16(src))
+		src = src[2:]
+		length, err := lz4Length(&src, int(token&0xf))
+		if err != nil || distance == 0 || distance > len(dst) {
+			return nil, errJournalCorrupt
+		}
+		// Matches may overlap the bytes they produce, so copy one by one.
+		for i, from := 0, len(dst)-distance; i < length+4; i++ {
+			dst = append(dst, dst[from+i])
+		}
+	}
+
+	if uint64(len(dst)) > size {
+		dst = dst[:size]
+	}
+	return dst, nil
+}
+
+// lz4Length reads the extension bytes of an LZ4 length whose 4-bit part
+// is n.
+func lz4Length(src *[]byte, n int) (int, error) {
+	if n != 15 {
+		return n, nil
+	}
+	for {
+		if len(*src) == 0 {
+			return 0, errJournalCorrupt
+		}
+		b := (*src)[0]
+		*src = (*src)[1:]
+		n += int(b)
+		if b != 255 {
+			return n, nil
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package logparser
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Journal files hash each data object so that entries can be matched
+// without reading their payloads. Files created by systemd 246 and later
+// use SipHash-2-4 keyed with the file ID, older ones Jenkins' lookup3.
+
+func sipHash24(key [16]byte, data []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[0:])
+	k1 := binary.LittleEndian.Uint64(key[8:])
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13)
+		v1 ^= v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16)
+		v3 ^= v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21)
+		v3 ^= v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17)
+		v1 ^= v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+
+	n := len(data)
+	for ; len(data) >= 8; data = data[8:] {
+		m := binary.LittleEndian.Uint64(data)
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	var tail [8]byte
+	copy(tail[:], data)
+	m := binary.LittleEndian.Uint64(tail[:]) | uint64(n)<<56
+	v3 ^= m
+	round()
+	round()
+	v0 ^= m
+
+	v2 ^= 0xff
+	round()
+	round()
+	round()
+	round()
+	return v0 ^ v1 ^ v2 ^ v3
+}
+
+// jenkinsHash64 is systemd's jenkins_hash64: lookup3's hashlittle2 with
+// both seeds zero, the two results joined into one 64-bit value.
+func jenkinsHash64(data []byte) uint64 {
+	a := 0xdeadbeef + uint32(len(data))
+	b, c := a, a
+
+	rot := bits.RotateLeft32
+	for ; len(data) > 12; data = data[12:] {
+		a += binary.LittleEndian.Uint32(data[0:])
+		b += binary.LittleEndian.Uint32(data[4:])
+		c += binary.LittleEndian.Uint32(data[8:])
+
+		a -= c
+		a ^= rot(c, 4)
+		c += b
+		b -= a
+		b ^= rot(a, 6)
+		a += c
+		c -= b
+		c ^= rot(b, 8)
+		b += a
+		a -= c
+		a ^= rot(c, 16)
+		c += b
+		b -= a
+		b ^= rot(a, 19)
+		a += c
+		c -= b
+		c ^= rot(b, 4)
+		b += a
+	}
+
+	if len(data) == 0 {
+		return uint64(c)<<32 | uint64(b)
+	}
+
+	var tail [12]byte
+	copy(tail[:], data)
+	a += binary.LittleEndian.Uint32(tail[0:])
+	b += binary.LittleEndian.Uint32(tail[4:])
+	c += binary.LittleEndian.Uint32(tail[8:])
+
+	c ^= b
+	c -= rot(b, 14)
+	a ^= c
+	a -= rot(c, 11)
+	b ^= a
+	b -= rot(a, 25)
+	c ^= b
+	c -= rot(b, 16)
+	a ^= c
+	a -= rot(c, 4)
+	b ^= a
+	b -= rot(a, 14)
+	c ^= b
+	c -= rot(b, 24)
+	return uint64(c)<<32 | uint64(b)
+}
@@ -1,71 +1,88 @@
+// Package logparser reads log files and the systemd journal.
+//
+// Log files are parsed by the Rust library in rust/ or, when built with the
+// purego tag or without cgo, by the Go parser in this package. Both read
+// syslog and journalctl lines and journal export files by the rules listed
+// with the conformance tests, which run against each of them.
 package logparser
 
-/*
-#cgo LDFLAGS: -L${SRCDIR}/../../rust/target/release -llogparser -ldl
-#include <stdlib.h>
-#include <string.h>
-
-typedef struct {
-    char* timestamp;
-    char* severity;
-    char* message;
-} LogEntry;
-
-typedef struct {
-    LogEntry* entries;
-    int count;
-} LogResult;
-
-extern LogResult* parse_logs(const char* log_path, const char* pattern, const char* start_date, const char* end_date, const char* severity_filter);
-extern void free_log_result(LogResult* result);
-*/
-import "C"
-import (
-	"errors"
-	"unsafe"
-)
+import "strings"
 
+// LogEntry is one parsed log line or journal entry. Source, Unit and PID
+// are only set for journal entries and journal export entries, Cursor only
+// for journal entries, and Offset, the position of the line in the
+// decompressed file, only for log files.
 type LogEntry struct {
 	Timestamp string
 	Severity  string
 	Message   string
+	Source    string
+	Unit      string
+	PID       int32
+	Cursor    string
+	Offset    int64
 }
 
-func ParseLogs(logPath, pattern, startDate, endDate, severityFilter string) ([]LogEntry, error) {
-	cLogPath := C.CString(logPath)
-	cPattern := C.CString(pattern)
-	cStartDate := C.CString(startDate)
-	cEndDate := C.CString(endDate)
-	cSeverityFilter := C.CString(severityFilter)
+// Filter holds the compiled filters of a log file query. It must be
+// closed.
+type Filter struct {
+	parser parser
+}
 
-	defer C.free(unsafe.Pointer(cLogPath))
-	defer C.free(unsafe.Pointer(cPattern))
-	defer C.free(unsafe.Pointer(cStartDate))
-	defer C.free(unsafe.Pointer(cEndDate))
-	defer C.free(unsafe.Pointer(cSeverityFilter))
+type parser interface {
+	parse(chunk []byte, base int64) []LogEntry
+	close()
+}
 
-	result := C.parse_logs(cLogPath, cPattern, cStartDate, cEndDate, cSeverityFilter)
-	if result == nil {
-		return nil, errors.New("failed to parse logs")
+// NewFilter compiles a regular expression matched against the message and
+// service of each entry, a date range and a list of severities. Empty strings
+// match everything.
+func NewFilter(pattern, startDate, endDate, severityFilter string) (*Filter, error) {
+	p, err := newParser(pattern, startDate, endDate, severityFilter)
+	if err != nil {
+		return nil, err
 	}
-	defer C.free_log_result(result)
+	return &Filter{parser: p}, nil
+}
 
-	count := int(result.count)
-	if count == 0 {
-		return []LogEntry{}, nil
+func (f *Filter) Close() {
+	f.parser.close()
+}
+
+// Parse returns the entries of the lines in chunk that pass the filter.
+// base is the offset of chunk in the file and is added to the Offset of
+// each entry.
+func (f *Filter) Parse(chunk []byte, base int64) []LogEntry {
+	if len(chunk) == 0 {
+		return []LogEntry{}
 	}
+	return f.parser.parse(chunk, base)
+}
 
-	entries := make([]LogEntry, count)
-	entryPtr := (*[1 << 28]C.LogEntry)(unsafe.Pointer(result.entries))[:count:count]
+// CheckPattern reports whether pattern compiles in the regex engine that
+// filters path: Go's regexp for JournalSource and the log file parser's
+// for files.
+func CheckPattern(path, pattern string) error {
+	if path == JournalSource {
+		_, err := compileJournalPattern(pattern)
+		return err
+	}
+	f, err := NewFilter(pattern, "", "", "")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return nil
+}
 
-	for i := 0; i < count; i++ {
-		entry := entryPtr[i]
-		entries[i] = LogEntry{
-			Timestamp: C.GoString(entry.timestamp),
-			Severity:  C.GoString(entry.severity),
-			Message:   C.GoString(entry.message),
+// severityList splits a severity filter such as "error,warn" into its
+// severities, or nil when it lists none.
+func severityList(s string) []string {
+	var list []string
+	for _, severity := range strings.Split(s, ",") {
+		if severity = strings.TrimSpace(severity); severity != "" {
+			list = append(list, severity)
 		}
 	}
-
-	return entries, nil
+	return list
 }
@@ -0,0 +1,596 @@
+package logparser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// goParser is the pure-Go parser. It follows rust/src/lib.rs step by step;
+// a change to one must be made to the other.
+type goParser struct {
+	pattern  *regexp.Regexp
+	start    *dateTime
+	end      *dateTime
+	severity []string
+	// year and month are what BSD syslog dates are taken to be at most.
+	year, month int64
+}
+
+var severityPatterns = []struct {
+	severity string
+	re       *regexp.Regexp
+}{
+	{"ERROR", regexp.MustCompile(`(?i)error|err|failed|failure`)},
+	{"WARN", regexp.MustCompile(`(?i)warn|warning`)},
+	{"INFO", regexp.MustCompile(`(?i)info|information`)},
+	{"DEBUG", regexp.MustCompile(`(?i)debug|trace`)},
+}
+
+// maxRealtime is the first __REALTIME_TIMESTAMP not accepted, the start of
+// year 10000.
+const maxRealtime = 253402300800000000
+
+func newGoParser(pattern, startDate, endDate, severityFilter string) (parser, error) {
+	now := time.Now()
+	p := &goParser{severity: severityList(severityFilter), year: int64(now.Year()), month: int64(now.Month())}
+	if t, ok := parseDateTime(startDate); ok {
+		p.start = &t
+	}
+	if t, ok := parseDateTime(endDate); ok {
+		p.end = &t
+	}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+		p.pattern = re
+	}
+	return p, nil
+}
+
+func (p *goParser) close() {}
+
+// exportBlock collects the consecutive field lines of a journal export
+// entry.
+type exportBlock struct {
+	offsets []int
+	raw     []string
+	fields  map[string]string
+}
+
+func (p *goParser) parse(chunk []byte, base int64) []LogEntry {
+	entries := []LogEntry{}
+	emit := func(entry LogEntry, offset int) {
+		entry.Offset = base + int64(offset)
+		entries = append(entries, entry)
+	}
+	line := func(raw []byte, offset int) {
+		if entry, ok := p.parseLine(raw); ok {
+			emit(entry, offset)
+		}
+	}
+	var block exportBlock
+	flush := func() {
+		if len(block.offsets) == 0 {
+			return
+		}
+		entry, keep, ok := p.parseExport(block.fields)
+		switch {
+		case ok && keep:
+			emit(entry, block.offsets[0])
+		case !ok:
+			for i, raw := range block.raw {
+				line([]byte(raw), block.offsets[i])
+			}
+		}
+		block = exportBlock{}
+	}
+	add := func(name, value string, raw []byte, offset int) {
+		if block.fields == nil {
+			block.fields = make(map[string]string)
+		}
+		block.fields[name] = value
+		block.offsets = append(block.offsets, offset)
+		block.raw = append(block.raw, string(raw))
+	}
+
+	for pos := 0; pos < len(chunk); {
+		end := len(chunk)
+		if i := bytes.IndexByte(chunk[pos:], '\n'); i >= 0 {
+			end = pos + i
+		}
+		offset, raw := pos, chunk[pos:end]
+		pos = end + 1
+
+		// A field is NAME=value on one line, or NAME on its own line
+		// followed by the length of the value as 64-bit little endian, the
+		// value and a newline.
+		if eq := bytes.IndexByte(raw, '='); eq >= 0 {
+			if fieldName(raw[:eq]) {
+				add(lossyString(raw[:eq]), lossyString(raw[eq+1:]), raw, offset)
+				continue
+			}
+		} else if fieldName(raw) && end < len(chunk) && len(chunk)-(end+1) >= 8 {
+			size := binary.LittleEndian.Uint64(chunk[end+1:])
+			start := end + 9
+			if size < uint64(len(chunk)-start) && chunk[start+int(size)] == '\n' {
+				add(lossyString(raw), lossyString(chunk[start:start+int(size)]), raw, offset)
+				pos = start + int(size) + 1
+				continue
+			}
+		}
+
+		flush()
+		line(raw, offset)
+	}
+	flush()
+	return entries
+}
+
+// fieldName reports whether name is a journal field name: capital letters,
+// digits and underscores, not starting with a digit.
+func fieldName(name []byte) bool {
+	if len(name) == 0 || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// keep applies the pattern, severity and date filters to an entry whose
+// pattern and severity are matched against service and message and whose
+// time is read only when the date range applies.
+func (p *goParser) keep(when func() (dateTime, bool), severity, service, message string) bool {
+	if p.pattern != nil && !p.pattern.MatchString(message) && !p.pattern.MatchString(service) {
+		return false
+	}
+	if p.severity != nil && !hasSeverity(p.severity, severity) {
+		return false
+	}
+	if p.start != nil && p.end != nil {
+		if t, ok := when(); !ok || t.before(*p.start) || p.end.before(t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *goParser) parseLine(raw []byte) (LogEntry, bool) {
+	line := strings.TrimRight(lossyString(raw), "\r")
+	if strings.TrimSpace(line) == "" {
+		return LogEntry{}, false
+	}
+	timestamp, service, message, ok := parseSyslogLine(line)
+	if !ok {
+		if timestamp, service, message, ok = parseJournalctlLine(line); !ok {
+			return LogEntry{}, false
+		}
+	}
+
+	severity := "INFO"
+	for _, s := range severityPatterns {
+		if s.re.MatchString(message) || s.re.MatchString(service) {
+			severity = s.severity
+			break
+		}
+	}
+	if !p.keep(func() (dateTime, bool) { return p.lineTime(line) }, severity, service, message) {
+		return LogEntry{}, false
+	}
+
+	return LogEntry{
+		Timestamp: dropNUL(timestamp),
+		Severity:  severity,
+		Message:   dropNUL(message),
+	}, true
+}
+
+// parseExport builds the entry of a journal export block. ok is false when
+// the block has no valid __REALTIME_TIMESTAMP and so is not one, and keep
+// false when the entry does not pass the filter.
+func (p *goParser) parseExport(fields map[string]string) (entry LogEntry, keep, ok bool) {
+	realtime, found := fields["__REALTIME_TIMESTAMP"]
+	if !found || !digits(realtime) {
+		return LogEntry{}, false, false
+	}
+	micros, err := strconv.ParseInt(realtime, 10, 64)
+	if err != nil || micros >= maxRealtime {
+		return LogEntry{}, false, false
+	}
+	t := time.Unix(micros/1000000, 0).Local()
+	timestamp := t.Format(TimeFormat)
+
+	// The second field is used when the first is missing or empty.
+	field := func(first, second string) string {
+		if v := fields[first]; v != "" {
+			return v
+		}
+		return fields[second]
+	}
+
+	priority := int64(6)
+	if s := fields["PRIORITY"]; digits(s) {
+		if priority, err = strconv.ParseInt(s, 10, 64); err != nil {
+			priority = math.MaxInt64
+		}
+	}
+	severity := "DEBUG"
+	switch {
+	case priority <= 3:
+		severity = "ERROR"
+	case priority == 4:
+		severity = "WARN"
+	case priority <= 6:
+		severity = "INFO"
+	}
+	message := strings.ReplaceAll(strings.TrimRight(fields["MESSAGE"], "\n"), "\n", " ")
+	source := field("SYSLOG_IDENTIFIER", "_COMM")
+	var pid int32
+	if s := field("_PID", "SYSLOG_PID"); digits(s) {
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil {
+			pid = int32(n)
+		}
+	}
+
+	if !p.keep(func() (dateTime, bool) { return localDateTime(t), true }, severity, source, message) {
+		return LogEntry{}, false, true
+	}
+	return LogEntry{
+		Timestamp: timestamp,
+		Severity:  severity,
+		Message:   dropNUL(message),
+		Source:    dropNUL(source),
+		Unit:      dropNUL(field("_SYSTEMD_UNIT", "UNIT")),
+		PID:       pid,
+	}, true, true
+}
+
+var monthNames = [...]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// lineTime reads the time at the start of a line: an RFC 3339 timestamp as
+// the first field, or a BSD syslog date, "Mon D HH:MM:SS", in the current
+// year unless the month is still to come.
+func (p *goParser) lineTime(line string) (dateTime, bool) {
+	fields := strings.FieldsFunc(line, func(r rune) bool { return r == ' ' || r == '\t' })
+	if len(fields) == 0 {
+		return dateTime{}, false
+	}
+	if t, ok := parseRFC3339(fields[0]); ok {
+		return t, true
+	}
+	if len(fields) < 3 || len(fields[1]) > 2 || len(fields[2]) != 8 || fields[2][2] != ':' || fields[2][5] != ':' {
+		return dateTime{}, false
+	}
+	t := dateTime{year: p.year}
+	for i, name := range monthNames {
+		if name == fields[0] {
+			t.month = int64(i) + 1
+		}
+	}
+	if t.month > p.month {
+		t.year--
+	}
+	var ok [4]bool
+	t.day, ok[0] = number(fields[1])
+	t.hour, ok[1] = number(fields[2][0:2])
+	t.minute, ok[2] = number(fields[2][3:5])
+	t.second, ok[3] = number(fields[2][6:8])
+	return t, ok == [4]bool{true, true, true, true} && t.valid()
+}
+
+// parseRFC3339 parses an RFC 3339 timestamp such as rsyslog and journalctl
+// -o short-iso write, "YYYY-MM-DDTHH:MM:SS[.frac](Z|+HH:MM|+HHMM)", into
+// local time. The fraction is dropped.
+func parseRFC3339(s string) (dateTime, bool) {
+	if len(s) < 20 || s[4] != '-' || s[7] != '-' || s[10] != 'T' || s[13] != ':' || s[16] != ':' {
+		return dateTime{}, false
+	}
+	var t dateTime
+	var ok [6]bool
+	t.year, ok[0] = number(s[0:4])
+	t.month, ok[1] = number(s[5:7])
+	t.day, ok[2] = number(s[8:10])
+	t.hour, ok[3] = number(s[11:13])
+	t.minute, ok[4] = number(s[14:16])
+	t.second, ok[5] = number(s[17:19])
+	if ok != [6]bool{true, true, true, true, true, true} || !t.valid() {
+		return dateTime{}, false
+	}
+
+	rest := s[19:]
+	if rest[0] == '.' {
+		n := len(rest[1:]) - len(strings.TrimLeft(rest[1:], "0123456789"))
+		if n == 0 {
+			return dateTime{}, false
+		}
+		rest = rest[1+n:]
+	}
+	var offset int64
+	switch {
+	case rest == "Z":
+	case (len(rest) == 6 && rest[3] == ':' || len(rest) == 5) && (rest[0] == '+' || rest[0] == '-'):
+		hours, okHours := number(rest[1:3])
+		minutes, okMinutes := number(rest[len(rest)-2:])
+		if !okHours || !okMinutes || hours > 23 || minutes > 59 {
+			return dateTime{}, false
+		}
+		offset = hours*3600 + minutes*60
+		if rest[0] == '-' {
+			offset = -offset
+		}
+	default:
+		return dateTime{}, false
+	}
+	unix := time.Date(int(t.year), time.Month(t.month), int(t.day), int(t.hour), int(t.minute), int(t.second), 0, time.UTC).Unix()
+	return localDateTime(time.Unix(unix-offset, 0).Local()), true
+}
+
+// number reads a string of one or more ASCII digits.
+func number(s string) (int64, bool) {
+	if !digits(s) {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	return n, err == nil
+}
+
+func localDateTime(t time.Time) dateTime {
+	return dateTime{int64(t.Year()), int64(t.Month()), int64(t.Day()), int64(t.Hour()), int64(t.Minute()), int64(t.Second())}
+}
+
+func digits(s string) bool {
+	return s != "" && strings.Trim(s, "0123456789") == ""
+}
+
+func hasSeverity(list []string, severity string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+// takeUntil splits s before the first sep.
+func takeUntil(s, sep string) (string, string, bool) {
+	i := strings.Index(s, sep)
+	if i < 0 {
+		return "", "", false
+	}
+	return s[:i], s[i:], true
+}
+
+// space1 skips one or more spaces and tabs.
+func space1(s string) (string, bool) {
+	rest := strings.TrimLeft(s, " \t")
+	return rest, len(rest) < len(s)
+}
+
+func multispace0(s string) string {
+	return strings.TrimLeft(s, " \t\r\n")
+}
+
+// parseSyslogLine parses "FIRST DATE HOST SERVICE: MESSAGE": the first field
+// is skipped, DATE is the timestamp and SERVICE runs up to the first ':'.
+func parseSyslogLine(line string) (timestamp, service, message string, ok bool) {
+	_, rest, ok := takeUntil(line, " ")
+	if !ok {
+		return
+	}
+	if rest, ok = space1(rest); !ok {
+		return
+	}
+	if timestamp, rest, ok = takeUntil(rest, " "); !ok {
+		return
+	}
+	if rest, ok = space1(rest); !ok {
+		return
+	}
+	if _, rest, ok = takeUntil(rest, " "); !ok {
+		return
+	}
+	if rest, ok = space1(rest); !ok {
+		return
+	}
+	if service, rest, ok = takeUntil(rest, ":"); !ok {
+		return
+	}
+	return timestamp, service, multispace0(rest[1:]), true
+}
+
+// parseJournalctlLine parses "TIMESTAMP HOST NAME[SERVICE] MESSAGE", where
+// SERVICE is whatever the brackets hold.
+func parseJournalctlLine(line string) (timestamp, service, message string, ok bool) {
+	timestamp, rest, ok := takeUntil(line, " ")
+	if !ok {
+		return
+	}
+	if rest, ok = space1(rest); !ok {
+		return
+	}
+	if _, rest, ok = takeUntil(rest, " "); !ok {
+		return
+	}
+	if rest, ok = space1(rest); !ok {
+		return
+	}
+	if _, rest, ok = takeUntil(rest, "["); !ok {
+		return
+	}
+	if service, rest, ok = takeUntil(rest[1:], "]"); !ok {
+		return
+	}
+	return timestamp, service, multispace0(rest[1:]), true
+}
+
+// dateTime is a date and time as parsed from "%Y-%m-%d %H:%M:%S" by chrono.
+// Second is 60 for a leap second.
+type dateTime struct {
+	year, month, day, hour, minute, second int64
+}
+
+// valid reports whether t is a real date and a time of day without a leap
+// second.
+func (t dateTime) valid() bool {
+	return t.month >= 1 && t.month <= 12 && t.day >= 1 && t.day <= int64(daysIn(t.year, t.month)) &&
+		t.hour <= 23 && t.minute <= 59 && t.second <= 59
+}
+
+func (t dateTime) before(u dateTime) bool {
+	a := [...]int64{t.year, t.month, t.day, t.hour, t.minute, t.second}
+	b := [...]int64{u.year, u.month, u.day, u.hour, u.minute, u.second}
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// parseDateTime parses s as chrono's NaiveDateTime::parse_from_str with
+// "%Y-%m-%d %H:%M:%S" does: whitespace is skipped before each number and
+// the space matches any whitespace or none, numbers may have fewer digits
+// than their width, and the year may carry a sign and then any number of
+// digits.
+func parseDateTime(s string) (dateTime, bool) {
+	var t dateTime
+	var ok bool
+	if s, t.year, ok = chronoYear(s); !ok {
+		return t, false
+	}
+	fields := []struct {
+		sep string
+		v   *int64
+	}{{"-", &t.month}, {"-", &t.day}, {" ", &t.hour}, {":", &t.minute}, {":", &t.second}}
+	for _, f := range fields {
+		if f.sep == " " {
+			s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		} else if s, ok = strings.CutPrefix(s, f.sep); !ok {
+			return t, false
+		}
+		if s, *f.v, ok = chronoNumber(strings.TrimLeftFunc(s, unicode.IsSpace), 2); !ok {
+			return t, false
+		}
+	}
+	if s != "" {
+		return t, false
+	}
+
+	// chrono accepts years from -262144 to 262143.
+	if t.year < -262144 || t.year > 262143 || t.month < 1 || t.month > 12 ||
+		t.day < 1 || t.day > int64(daysIn(t.year, t.month)) ||
+		t.hour > 23 || t.minute > 59 || t.second > 60 {
+		return t, false
+	}
+	return t, true
+}
+
+func chronoYear(s string) (string, int64, bool) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		rest, v, ok := chronoNumber(s[1:], math.MaxInt)
+		return rest, -v, ok
+	case strings.HasPrefix(s, "+"):
+		return chronoNumber(s[1:], math.MaxInt)
+	}
+	return chronoNumber(s, 4)
+}
+
+// chronoNumber reads one to width ASCII digits.
+func chronoNumber(s string, width int) (string, int64, bool) {
+	var v int64
+	i := 0
+	for ; i < len(s) && i < width && s[i] >= '0' && s[i] <= '9'; i++ {
+		d := int64(s[i] - '0')
+		if v > (math.MaxInt64-d)/10 {
+			return s, 0, false
+		}
+		v = v*10 + d
+	}
+	return s[i:], v, i > 0
+}
+
+func daysIn(year, month int64) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
+
+func dropNUL(s string) string {
+	return strings.ReplaceAll(s, "\x00", "")
+}
+
+// lossyString converts b to a string, replacing each maximal invalid UTF-8
+// subsequence with U+FFFD as Rust's String::from_utf8_lossy does.
+func lossyString(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	var sb strings.Builder
+	for len(b) > 0 {
+		r, n := utf8.DecodeRune(b)
+		if r != utf8.RuneError || n > 1 {
+			sb.Write(b[:n])
+			b = b[n:]
+			continue
+		}
+		sb.WriteRune(utf8.RuneError)
+		b = b[invalidPrefix(b):]
+	}
+	return sb.String()
+}
+
+// invalidPrefix returns the length of the invalid sequence at the start of
+// b: the lead byte and the continuation bytes that could still have
+// completed it.
+func invalidPrefix(b []byte) int {
+	lo, hi := byte(0x80), byte(0xBF)
+	need := 0
+	switch c := b[0]; {
+	case c >= 0xC2 && c <= 0xDF:
+		need = 1
+	case c == 0xE0:
+		need, lo = 2, 0xA0
+	case c >= 0xE1 && c <= 0xEC, c == 0xEE, c == 0xEF:
+		need = 2
+	case c == 0xED:
+		need, hi = 2, 0x9F
+	case c == 0xF0:
+		need, lo = 3, 0x90
+	case c >= 0xF1 && c <= 0xF3:
+		need = 3
+	case c == 0xF4:
+		need, hi = 3, 0x8F
+	default:
+		return 1
+	}
+	i := 1
+	for ; i <= need && i < len(b); i++ {
+		if b[i] < lo || b[i] > hi {
+			break
+		}
+		lo, hi = 0x80, 0xBF
+	}
+	return i
+}
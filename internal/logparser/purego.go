@@ -0,0 +1,7 @@
+//go:build !cgo || purego
+
+package logparser
+
+func newParser(pattern, startDate, endDate, severityFilter string) (parser, error) {
+	return newGoParser(pattern, startDate, endDate, severityFilter)
+}
@@ -0,0 +1,328 @@
+package logparser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// chunkSize is how much of a log file is parsed at a time.
+	chunkSize = 1 << 20
+	// DefaultLogLimit is the number of newest entries a query returns
+	// when it sets no limit.
+	DefaultLogLimit = 5000
+)
+
+// FileQuery selects entries of a log file. The newest Limit matching
+// entries are returned, oldest first.
+type FileQuery struct {
+	Pattern  string
+	Start    string
+	End      string
+	Severity string
+	Limit    int
+	// Before only includes lines starting before this offset. Passing the
+	// Offset of the oldest entry returned by a query pages further back.
+	Before int64
+}
+
+// LogReader reads a log file forward in chunks of whole lines. Rotated
+// files compressed with gzip (.gz) or zstd (.zst) are decompressed as they
+// are read; offsets then count decompressed bytes.
+type LogReader struct {
+	file   *os.File
+	r      io.Reader
+	filter *Filter
+	offset int64
+	carry  []byte
+	done   bool
+}
+
+// NewLogReader opens path for reading from offset, which must be the start
+// of a line such as the Offset of a reader or of an entry.
+func NewLogReader(path string, offset int64, filter *Filter) (*LogReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, compressed, err := decompressor(path, file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if offset > 0 {
+		if compressed {
+			_, err = io.CopyN(io.Discard, r, offset)
+		} else {
+			_, err = file.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			reader := &LogReader{file: file, r: r}
+			reader.Close()
+			return nil, err
+		}
+	}
+	return &LogReader{file: file, r: r, filter: filter, offset: offset}, nil
+}
+
+func decompressor(path string, file *os.File) (io.Reader, bool, error) {
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		r, err := gzip.NewReader(bufio.NewReader(file))
+		return r, true, err
+	case strings.HasSuffix(path, ".zst"):
+		d, err := zstd.NewReader(bufio.NewReader(file), zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, true, err
+		}
+		return d.IOReadCloser(), true, nil
+	}
+	return file, false, nil
+}
+
+// Next returns the matching entries of the next chunk, which may be none,
+// or io.EOF once the whole file was read.
+func (r *LogReader) Next() ([]LogEntry, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	buf := make([]byte, len(r.carry)+chunkSize)
+	copy(buf, r.carry)
+	n, err := io.ReadFull(r.r, buf[len(r.carry):])
+	buf = buf[:len(r.carry)+n]
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		r.done = true
+	default:
+		return nil, err
+	}
+
+	// The last line is carried over to the next chunk unless the file
+	// ended, so that lines are never split.
+	end := len(buf)
+	if !r.done {
+		end = chunkEnd(buf)
+	}
+	r.carry = append(r.carry[:0], buf[end:]...)
+
+	entries := r.filter.Parse(buf[:end], r.offset)
+	r.offset += int64(end)
+	return entries, nil
+}
+
+// Offset is the offset up to which the file was parsed. A reader created
+// at it carries on from there.
+func (r *LogReader) Offset() int64 {
+	return r.offset
+}
+
+func (r *LogReader) Close() error {
+	if d, ok := r.r.(io.Closer); ok && r.r != io.Reader(r.file) {
+		d.Close()
+	}
+	return r.file.Close()
+}
+
+// ReadLog returns the newest entries of the log file at path that match
+// the query, oldest first. Uncompressed files are read backwards from the
+// end a chunk at a time until enough entries matched, so the cost does not
+// depend on the size of the file. Reading stops when ctx is done.
+func ReadLog(ctx context.Context, path string, q FileQuery) ([]LogEntry, error) {
+	filter, err := NewFilter(q.Pattern, q.Start, q.End, q.Severity)
+	if err != nil {
+		return nil, err
+	}
+	defer filter.Close()
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLogLimit
+	}
+
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".zst") {
+		return readForward(ctx, path, filter, q.Before, limit)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	if q.Before > 0 && q.Before < end {
+		if end, err = lineEnd(file, q.Before); err != nil {
+			return nil, err
+		}
+	}
+	return readBackward(ctx, file, end, filter, limit)
+}
+
+// lineEnd returns the offset just past the line that contains offset-1.
+func lineEnd(file *os.File, offset int64) (int64, error) {
+	buf := make([]byte, 4096)
+	for pos := offset - 1; ; pos += int64(len(buf)) {
+		n, err := file.ReadAt(buf, pos)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return pos + int64(i) + 1, nil
+		}
+		if err == io.EOF {
+			return pos + int64(n), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// readBackward parses the chunks of file before end from the last to the
+// first until limit entries matched.
+func readBackward(ctx context.Context, file *os.File, end int64, filter *Filter, limit int) ([]LogEntry, error) {
+	var chunks [][]LogEntry
+	found := 0
+	// head is the start of the first line of the chunk read last, which
+	// began in an earlier chunk.
+	var head []byte
+
+	for pos := end; pos > 0 && found < limit; {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		start := pos - chunkSize
+		if start < 0 {
+			start = 0
+		}
+		data := make([]byte, pos-start, pos-start+int64(len(head)))
+		if _, err := file.ReadAt(data, start); err != nil && err != io.EOF {
+			return nil, err
+		}
+		data = append(data, head...)
+		pos = start
+
+		base := start
+		if start > 0 {
+			i := chunkStart(data)
+			if i < 0 {
+				head = data
+				continue
+			}
+			head = data[:i]
+			data = data[i:]
+			base += int64(i)
+		}
+
+		entries := filter.Parse(data, base)
+		chunks = append(chunks, entries)
+		found += len(entries)
+	}
+
+	entries := make([]LogEntry, 0, found)
+	for i := len(chunks) - 1; i >= 0; i-- {
+		entries = append(entries, chunks[i]...)
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// chunkEnd returns the end of the whole lines in buf. A journal export
+// entry that has not ended with a blank line yet is left out as well,
+// unless it starts buf and buf holds a chunk. Such an entry is recognized by
+// its first field, which journalctl names with two underscores, so that
+// other logs of NAME=value lines are not held back.
+func chunkEnd(buf []byte) int {
+	end := bytes.LastIndexByte(buf, '\n') + 1
+	start := bytes.LastIndex(buf[:end], []byte("\n\n")) + 2
+	if start < 2 {
+		start = 0
+	}
+	if (start > 0 || len(buf) < chunkSize) && bytes.HasPrefix(buf[start:end], []byte("__")) && fieldLine(buf[start:end]) {
+		return start
+	}
+	return end
+}
+
+// chunkStart returns the start of the first whole line in data, which
+// begins within a line, or -1 when there is none. When that line is a
+// journal export field, which may belong to an entry begun before data, the
+// start is moved past the next blank line.
+func chunkStart(data []byte) int {
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return -1
+	}
+	if fieldLine(data[i+1:]) {
+		if j := bytes.Index(data[i:], []byte("\n\n")); j >= 0 {
+			return i + j + 2
+		}
+	}
+	return i + 1
+}
+
+// fieldLine reports whether the line at the start of data is a journal
+// export field.
+func fieldLine(data []byte) bool {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[:i]
+	}
+	if i := bytes.IndexByte(data, '='); i >= 0 {
+		data = data[:i]
+	}
+	return fieldName(data)
+}
+
+// readForward parses a compressed file, which can only be read from the
+// start, keeping the newest limit entries before the before offset.
+func readForward(ctx context.Context, path string, filter *Filter, before int64, limit int) ([]LogEntry, error) {
+	r, err := NewLogReader(path, 0, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var entries []LogEntry
+	for before <= 0 || r.Offset() < before {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		chunk, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range chunk {
+			if before <= 0 || entry.Offset < before {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) > 2*limit {
+			entries = append(entries[:0], entries[len(entries)-limit:]...)
+		}
+	}
+
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	if entries == nil {
+		entries = []LogEntry{}
+	}
+	return entries, nil
+}
@@ -0,0 +1,190 @@
+package logparser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestReadLogCompressed(t *testing.T) {
+	var log bytes.Buffer
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&log, "2024-03-01T12:%02d:%02dZ web1 app[%d]: request %d failed\n", i/60, i%60, 100+i, i)
+	}
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(log.Bytes())
+	w.Close()
+	enc, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zst := enc.EncodeAll(log.Bytes(), nil)
+	enc.Close()
+
+	dir := t.TempDir()
+	files := map[string][]byte{"app.log": log.Bytes(), "app.log.1.gz": gz.Bytes(), "app.log.1.zst": zst}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	queries := []FileQuery{
+		{},
+		{Limit: 7},
+		{Pattern: "request 1[0-9] ", Limit: 5},
+		{Before: 2000},
+	}
+	for _, q := range queries {
+		want, err := ReadLog(ctx, filepath.Join(dir, "app.log"), q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(want) == 0 {
+			t.Fatalf("%+v: no entries", q)
+		}
+		for _, name := range []string{"app.log.1.gz", "app.log.1.zst"} {
+			got, err := ReadLog(ctx, filepath.Join(dir, name), q)
+			if err != nil {
+				t.Errorf("%s %+v: %v", name, q, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %+v: got %d entries, want %d as in the plain file", name, q, len(got), len(want))
+			}
+		}
+	}
+}
+
+func TestReadLogExport(t *testing.T) {
+	// Enough entries to span several chunks, some with binary fields that
+	// hold newlines.
+	const n = 15000
+	var log bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&log, "__CURSOR=s=0;i=%x\n__REALTIME_TIMESTAMP=%d\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\n_PID=%d\n", i, 1709294400000000+int64(i)*1000000, 100+i)
+		if i%7 == 0 {
+			log.WriteString(binaryField("MESSAGE", fmt.Sprintf("entry %d\ncontinued", i)))
+		} else {
+			fmt.Fprintf(&log, "MESSAGE=entry %d continued\n", i)
+		}
+		log.WriteString("\n")
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(log.Bytes())
+	w.Close()
+
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{"export.log": log.Bytes(), "export.log.1.gz": gz.Bytes()} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	for _, name := range []string{"export.log", "export.log.1.gz"} {
+		got, err := ReadLog(ctx, filepath.Join(dir, name), FileQuery{Limit: n + 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != n {
+			t.Fatalf("%s: got %d entries, want %d", name, len(got), n)
+		}
+		for i, e := range got {
+			if want := fmt.Sprintf("entry %d continued", i); e.Message != want || e.PID != int32(100+i) {
+				t.Fatalf("%s: entry %d is %+v, want message %q", name, i, e, want)
+			}
+		}
+
+		older, err := ReadLog(ctx, filepath.Join(dir, name), FileQuery{Limit: 10, Before: got[n-100].Offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(older, got[n-110:n-100]) {
+			t.Errorf("%s: before entry %d got %d entries from %+v", name, n-100, len(older), older[0])
+		}
+	}
+}
+
+func TestTailFileExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "export.log")
+	entry := "__REALTIME_TIMESTAMP=1709294400000000\nMESSAGE=first\n\n"
+	if err := os.WriteFile(path, []byte(entry), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := TailFile(path, Position{}, "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry is only read once the blank line after it is written.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, part := range []string{"__REALTIME_TIMESTAMP=1709294401000000\n", "MESSAGE=second\n", "\n"} {
+		if _, err := f.WriteString(part); err != nil {
+			t.Fatal(err)
+		}
+		if tail, err = TailFile(path, tail.Position, "", "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+		if part != "\n" && len(tail.Entries) != 0 {
+			t.Errorf("after %q got %+v", part, tail.Entries)
+		}
+	}
+	if len(tail.Entries) != 1 || tail.Entries[0].Message != "second" || tail.Entries[0].Offset != int64(len(entry)) {
+		t.Errorf("got %+v, want the second entry at %d", tail.Entries, len(entry))
+	}
+}
+
+func TestTailFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "syslog")
+	if err := os.WriteFile(path, []byte("x 1 h app: first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tail, err := TailFile(path, Position{}, "", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Lines written just before rotation, the last without a newline, are
+	// read from the old file before the new one.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("x 2 h app: second\nx 3 h app: third"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x 4 h app: fourth\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if tail, err = TailFile(path, tail.Position, "", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := messages(tail.Entries), []string{"second", "third", "fourth"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if tail.Position.Offset != int64(len("x 4 h app: fourth\n")) {
+		t.Errorf("position %+v, want the end of the new file", tail.Position)
+	}
+}
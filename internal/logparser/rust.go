@@ -0,0 +1,98 @@
+//go:build cgo && !purego
+
+package logparser
+
+/*
+#cgo LDFLAGS: -L${SRCDIR}/../../rust/target/release -llogparser -ldl
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct {
+    char* timestamp;
+    char* severity;
+    char* message;
+    char* source;
+    char* unit;
+    int pid;
+    unsigned long long offset;
+} LogEntry;
+
+typedef struct {
+    LogEntry* entries;
+    int count;
+} LogResult;
+
+typedef struct LogFilter LogFilter;
+
+extern LogFilter* log_filter_new(const char* pattern, const char* start_date, const char* end_date, const char* severity_filter);
+extern void log_filter_free(LogFilter* filter);
+extern LogResult* parse_log_chunk(const LogFilter* filter, const char* data, size_t len);
+extern void free_log_result(LogResult* result);
+*/
+import "C"
+import (
+	"fmt"
+	"unsafe"
+)
+
+func newParser(pattern, startDate, endDate, severityFilter string) (parser, error) {
+	return newRustParser(pattern, startDate, endDate, severityFilter)
+}
+
+// rustParser parses with the Rust library.
+type rustParser struct {
+	ptr *C.LogFilter
+}
+
+func newRustParser(pattern, startDate, endDate, severityFilter string) (parser, error) {
+	cPattern := C.CString(pattern)
+	cStartDate := C.CString(startDate)
+	cEndDate := C.CString(endDate)
+	cSeverityFilter := C.CString(severityFilter)
+
+	defer C.free(unsafe.Pointer(cPattern))
+	defer C.free(unsafe.Pointer(cStartDate))
+	defer C.free(unsafe.Pointer(cEndDate))
+	defer C.free(unsafe.Pointer(cSeverityFilter))
+
+	ptr := C.log_filter_new(cPattern, cStartDate, cEndDate, cSeverityFilter)
+	if ptr == nil {
+		return nil, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	return &rustParser{ptr: ptr}, nil
+}
+
+func (p *rustParser) close() {
+	C.log_filter_free(p.ptr)
+}
+
+func (p *rustParser) parse(chunk []byte, base int64) []LogEntry {
+	result := C.parse_log_chunk(p.ptr, (*C.char)(unsafe.Pointer(&chunk[0])), C.size_t(len(chunk)))
+	if result == nil {
+		return []LogEntry{}
+	}
+	defer C.free_log_result(result)
+
+	count := int(result.count)
+	if count == 0 {
+		return []LogEntry{}
+	}
+
+	entries := make([]LogEntry, count)
+	entryPtr := (*[1 << 28]C.LogEntry)(unsafe.Pointer(result.entries))[:count:count]
+
+	for i := 0; i < count; i++ {
+		entry := entryPtr[i]
+		entries[i] = LogEntry{
+			Timestamp: C.GoString(entry.timestamp),
+			Severity:  C.GoString(entry.severity),
+			Message:   C.GoString(entry.message),
+			Source:    C.GoString(entry.source),
+			Unit:      C.GoString(entry.unit),
+			PID:       int32(entry.pid),
+			Offset:    base + int64(entry.offset),
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,7 @@
+//go:build cgo && !purego
+
+package logparser
+
+func init() {
+	backends["rust"] = newRustParser
+}
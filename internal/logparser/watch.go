@@ -0,0 +1,102 @@
+package logparser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Watch reports changes to the log file at path, or to the journal when
+// path is JournalSource, until stop is called. The directories are watched
+// rather than the files so that rotated files are picked up. Notifications
+// are coalesced: one that has not been received yet is not repeated.
+func Watch(path string) (changes <-chan struct{}, stop func(), err error) {
+	dirs, match, err := watchTargets(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fd, err := unix.InotifyInit1(unix.IN_NONBLOCK | unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, dir := range dirs {
+		if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_MODIFY|unix.IN_CREATE|unix.IN_MOVED_TO); err != nil {
+			unix.Close(fd)
+			return nil, nil, err
+		}
+	}
+
+	// The descriptor is non-blocking, so reads go through the runtime poller
+	// and closing the file ends a pending read.
+	file := os.NewFile(uintptr(fd), "inotify")
+	ch := make(chan struct{}, 1)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			if inotifyMatch(buf[:n], match) {
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return ch, func() { once.Do(func() { file.Close() }) }, nil
+}
+
+func watchTargets(path string) ([]string, func(name string) bool, error) {
+	if path == JournalSource {
+		seen := make(map[string]bool)
+		var dirs []string
+		for _, file := range journalFiles(JournalDirs) {
+			if dir := filepath.Dir(file); !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+		if len(dirs) == 0 {
+			return nil, nil, errors.New("no journal files to watch")
+		}
+		return dirs, func(name string) bool { return strings.HasSuffix(name, ".journal") }, nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	base := filepath.Base(resolved)
+	return []string{filepath.Dir(resolved)}, func(name string) bool { return name == base }, nil
+}
+
+// inotifyMatch reports whether any event in buf concerns a watched file.
+func inotifyMatch(buf []byte, match func(name string) bool) bool {
+	for len(buf) >= unix.SizeofInotifyEvent {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[0]))
+		end := unix.SizeofInotifyEvent + int(event.Len)
+		if end > len(buf) {
+			return false
+		}
+		if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+			return true
+		}
+		name := strings.TrimRight(string(buf[unix.SizeofInotifyEvent:end]), "\x00")
+		if match(name) {
+			return true
+		}
+		buf = buf[end:]
+	}
+	return false
+}
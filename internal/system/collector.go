@@ -0,0 +1,332 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Group string
+
+const (
+	GroupMetrics     Group = "metrics"
+	GroupProcesses   Group = "processes"
+	GroupServices    Group = "services"
+	GroupConnections Group = "connections"
+	GroupPackages    Group = "packages"
+)
+
+var groups = []Group{GroupMetrics, GroupProcesses, GroupServices, GroupConnections, GroupPackages}
+
+type CollectorConfig struct {
+	MetricsInterval    time.Duration
+	ProcessInterval    time.Duration
+	ServiceInterval    time.Duration
+	ConnectionInterval time.Duration
+	PackageInterval    time.Duration
+	HistoryLength      int
+}
+
+func DefaultCollectorConfig() CollectorConfig {
+	return CollectorConfig{
+		MetricsInterval:    time.Second,
+		ProcessInterval:    2 * time.Second,
+		ServiceInterval:    5 * time.Second,
+		ConnectionInterval: 2 * time.Second,
+		PackageInterval:    10 * time.Minute,
+		HistoryLength:      120,
+	}
+}
+
+func (c CollectorConfig) interval(g Group) time.Duration {
+	var d time.Duration
+	switch g {
+	case GroupMetrics:
+		d = c.MetricsInterval
+	case GroupProcesses:
+		d = c.ProcessInterval
+	case GroupServices:
+		d = c.ServiceInterval
+	case GroupConnections:
+		d = c.ConnectionInterval
+	case GroupPackages:
+		d = c.PackageInterval
+	}
+	if d <= 0 {
+		d = time.Second
+	}
+	return d
+}
+
+// Snapshot is an immutable view of the most recent samples. Subscribers must
+// not modify it or any of the slices it references.
+type Snapshot struct {
+	Timestamp      time.Time
+	CPU            *CPUMetrics
+	Memory         *MemoryMetrics
+	Disk           *DiskMetrics
+	Partitions     []DiskInfo
+	Network        []NetworkStats
+	Connections    []NetworkConnection
+	Processes      []ProcessInfo
+	Services       []ServiceInfo
+	PackageManager PackageManager
+	Packages       []PackageInfo
+	Errors         map[Group]error
+	Updated        map[Group]time.Time
+}
+
+func (s *Snapshot) Err(g Group) error {
+	if s == nil {
+		return nil
+	}
+	return s.Errors[g]
+}
+
+func (s *Snapshot) Has(g Group) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.Updated[g]
+	return ok
+}
+
+type Collector struct {
+	config  CollectorConfig
+	cpu     *CPUSampler
+	network *NetworkSampler
+	procs   *ProcessSampler
+	history *History
+
+	mu        sync.RWMutex
+	latest    *Snapshot
+	publisher Publisher
+
+	refresh map[Group]chan struct{}
+}
+
+func NewCollector(config CollectorConfig) *Collector {
+	c := &Collector{
+		config:  config,
+		cpu:     NewCPUSampler(),
+		network: NewNetworkSampler(),
+		procs:   NewProcessSampler(),
+		history: NewHistory(config.HistoryLength),
+		latest:  &Snapshot{},
+		refresh: make(map[Group]chan struct{}),
+	}
+	for _, g := range groups {
+		c.refresh[g] = make(chan struct{}, 1)
+	}
+	return c
+}
+
+// Start launches one sampling goroutine per group. They run until ctx is
+// cancelled.
+func (c *Collector) Start(ctx context.Context) {
+	for _, g := range groups {
+		go c.run(ctx, g)
+	}
+}
+
+func (c *Collector) History() *History {
+	return c.history
+}
+
+func (c *Collector) Latest() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.latest
+}
+
+func (c *Collector) Subscribe() (<-chan *Snapshot, func()) {
+	return c.publisher.Subscribe()
+}
+
+// Refresh asks the given groups, or all of them, to sample immediately.
+func (c *Collector) Refresh(gs ...Group) {
+	if len(gs) == 0 {
+		gs = groups
+	}
+	for _, g := range gs {
+		select {
+		case c.refresh[g] <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *Collector) run(ctx context.Context, g Group) {
+	ticker := time.NewTicker(c.config.interval(g))
+	defer ticker.Stop()
+
+	for {
+		c.sample(g)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.refresh[g]:
+		}
+	}
+}
+
+func (c *Collector) sample(g Group) {
+	var apply func(s *Snapshot)
+	var err error
+
+	switch g {
+	case GroupMetrics:
+		cpu, cpuErr := c.cpu.Sample()
+		mem, memErr := GetMemoryMetrics()
+		disk, diskErr := GetDiskMetrics()
+		partitions, partErr := GetDiskPartitions()
+		network, netErr := c.network.Sample()
+		err = firstError(cpuErr, memErr, diskErr, netErr)
+		// A metric that failed keeps its last good value; Err reports
+		// the failure.
+		apply = func(s *Snapshot) {
+			if cpuErr == nil {
+				s.CPU = cpu
+			}
+			if memErr == nil {
+				s.Memory = mem
+			}
+			if diskErr == nil {
+				s.Disk = disk
+			}
+			if partErr == nil {
+				s.Partitions = partitions
+			}
+			if netErr == nil {
+				s.Network = network
+			}
+		}
+	case GroupProcesses:
+		processes, procErr := c.procs.Sample()
+		err = procErr
+		apply = func(s *Snapshot) {
+			if procErr == nil {
+				s.Processes = processes
+			}
+		}
+	case GroupServices:
+		services, svcErr := GetServices()
+		err = svcErr
+		apply = func(s *Snapshot) {
+			if svcErr == nil {
+				s.Services = services
+			}
+		}
+	case GroupConnections:
+		connections, connErr := GetNetworkConnections()
+		err = connErr
+		apply = func(s *Snapshot) {
+			if connErr == nil {
+				s.Connections = connections
+			}
+		}
+	case GroupPackages:
+		pm := DetectPackageManager()
+		packages, pkgErr := ListPackages(pm)
+		err = pkgErr
+		apply = func(s *Snapshot) {
+			if pkgErr == nil {
+				s.PackageManager = pm
+				s.Packages = packages
+			}
+		}
+	}
+
+	c.publish(g, apply, err)
+}
+
+func (c *Collector) publish(g Group, apply func(s *Snapshot), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	next := *c.latest
+	next.Timestamp = now
+	next.Errors = make(map[Group]error, len(c.latest.Errors)+1)
+	for k, v := range c.latest.Errors {
+		next.Errors[k] = v
+	}
+	next.Updated = make(map[Group]time.Time, len(c.latest.Updated)+1)
+	for k, v := range c.latest.Updated {
+		next.Updated[k] = v
+	}
+
+	apply(&next)
+	if err != nil {
+		next.Errors[g] = err
+	} else {
+		delete(next.Errors, g)
+	}
+	next.Updated[g] = now
+
+	c.latest = &next
+	if g == GroupMetrics {
+		c.history.Observe(&next)
+	}
+	c.publisher.Publish(&next)
+}
+
+// Publisher fans snapshots out to subscribers. Slow readers only ever see
+// the most recent one. The zero value is ready to use.
+type Publisher struct {
+	mu          sync.Mutex
+	subscribers map[int]chan *Snapshot
+	nextID      int
+}
+
+// Subscribe returns a channel that receives every published snapshot and a
+// function that unsubscribes and closes the channel.
+func (p *Publisher) Subscribe() (<-chan *Snapshot, func()) {
+	ch := make(chan *Snapshot, 1)
+
+	p.mu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = make(map[int]chan *Snapshot)
+	}
+	id := p.nextID
+	p.nextID++
+	p.subscribers[id] = ch
+	p.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			p.mu.Lock()
+			delete(p.subscribers, id)
+			close(ch)
+			p.mu.Unlock()
+		})
+	}
+}
+
+func (p *Publisher) Publish(s *Snapshot) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ch := range p.subscribers {
+		select {
+		case ch <- s:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- s
+		}
+	}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,68 +2,149 @@ package system
 
 import (
 	"context"
-	"time"
+	"sync"
 
 	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
 )
 
-type CPUMetrics struct {
+type CPUBreakdown struct {
 	Usage   float64
-	PerCore []float64
-	Cores   int
-	Model   string
-	LoadAvg []float64
+	User    float64
+	System  float64
+	IOWait  float64
+	Steal   float64
+	IRQ     float64
+	SoftIRQ float64
+	Idle    float64
 }
 
-func GetCPUMetrics() (*CPUMetrics, error) {
+type CPUMetrics struct {
+	Usage            float64
+	PerCore          []float64
+	Breakdown        CPUBreakdown
+	PerCoreBreakdown []CPUBreakdown
+	Cores            int
+	Model            string
+	LoadAvg          []float64
+}
+
+// CPUSampler keeps the previous /proc/stat counters so that each call to
+// Sample reports usage over the interval since the last call without
+// sleeping. The first sample covers the time since boot.
+type CPUSampler struct {
+	mu        sync.Mutex
+	prevTotal cpu.TimesStat
+	prevCores []cpu.TimesStat
+	model     string
+	cores     int
+}
+
+func NewCPUSampler() *CPUSampler {
+	return &CPUSampler{}
+}
+
+func (s *CPUSampler) Sample() (*CPUMetrics, error) {
 	ctx := context.Background()
 
-	percent, err := cpu.PercentWithContext(ctx, time.Second, true)
-	if err != nil {
-		return nil, err
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.model == "" {
+		info, err := cpu.InfoWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := cpu.CountsWithContext(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+
+		s.model = "Unknown"
+		if len(info) > 0 {
+			s.model = info[0].ModelName
+		}
+		s.cores = count
 	}
 
-	avg, err := cpu.PercentWithContext(ctx, time.Second, false)
+	total, err := cpu.TimesWithContext(ctx, false)
 	if err != nil {
 		return nil, err
 	}
 
-	info, err := cpu.InfoWithContext(ctx)
+	perCore, err := cpu.TimesWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
 
-	count, err := cpu.CountsWithContext(ctx, true)
+	avg, err := load.AvgWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	model := "Unknown"
-	if len(info) > 0 {
-		model = info[0].ModelName
+	metrics := &CPUMetrics{
+		PerCore:          make([]float64, len(perCore)),
+		PerCoreBreakdown: make([]CPUBreakdown, len(perCore)),
+		Cores:            s.cores,
+		Model:            s.model,
+		LoadAvg:          []float64{avg.Load1, avg.Load5, avg.Load15},
+	}
+
+	if len(total) > 0 {
+		metrics.Breakdown = cpuDelta(s.prevTotal, total[0])
+		metrics.Usage = metrics.Breakdown.Usage
+		s.prevTotal = total[0]
 	}
 
-	loadAvg := []float64{}
-	if len(avg) > 0 {
-		loadAvg = []float64{avg[0]}
+	for i, t := range perCore {
+		var prev cpu.TimesStat
+		if i < len(s.prevCores) && s.prevCores[i].CPU == t.CPU {
+			prev = s.prevCores[i]
+		}
+		metrics.PerCoreBreakdown[i] = cpuDelta(prev, t)
+		metrics.PerCore[i] = metrics.PerCoreBreakdown[i].Usage
 	}
+	s.prevCores = perCore
+
+	return metrics, nil
+}
 
-	perCore := make([]float64, len(percent))
-	copy(perCore, percent)
+func cpuDelta(prev, cur cpu.TimesStat) CPUBreakdown {
+	user := (cur.User + cur.Nice) - (prev.User + prev.Nice)
+	sys := cur.System - prev.System
+	idle := cur.Idle - prev.Idle
+	iowait := cur.Iowait - prev.Iowait
+	irq := cur.Irq - prev.Irq
+	softirq := cur.Softirq - prev.Softirq
+	steal := cur.Steal - prev.Steal
 
-	var totalUsage float64
-	for _, p := range percent {
-		totalUsage += p
+	total := user + sys + idle + iowait + irq + softirq + steal
+	if total <= 0 {
+		return CPUBreakdown{Idle: 100}
 	}
-	if len(percent) > 0 {
-		totalUsage /= float64(len(percent))
+
+	pct := func(v float64) float64 {
+		if v < 0 {
+			return 0
+		}
+		return v / total * 100
+	}
+
+	return CPUBreakdown{
+		Usage:   pct(total - idle - iowait),
+		User:    pct(user),
+		System:  pct(sys),
+		IOWait:  pct(iowait),
+		Steal:   pct(steal),
+		IRQ:     pct(irq),
+		SoftIRQ: pct(softirq),
+		Idle:    pct(idle),
 	}
+}
+
+var defaultCPUSampler = NewCPUSampler()
 
-	return &CPUMetrics{
-		Usage:   totalUsage,
-		PerCore: perCore,
-		Cores:   count,
-		Model:   model,
-		LoadAvg: loadAvg,
-	}, nil
+func GetCPUMetrics() (*CPUMetrics, error) {
+	return defaultCPUSampler.Sample()
 }
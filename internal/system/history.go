@@ -0,0 +1,161 @@
+package system
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	HistoryCPU    = "cpu"
+	HistoryMemory = "memory"
+	HistorySwap   = "swap"
+	HistoryDisk   = "disk"
+)
+
+func HistoryCore(core int) string {
+	return fmt.Sprintf("cpu/%d", core)
+}
+
+func HistoryNetRecv(iface string) string {
+	return "net/" + iface + "/recv"
+}
+
+func HistoryNetSent(iface string) string {
+	return "net/" + iface + "/sent"
+}
+
+// Ring is a fixed-size buffer that overwrites its oldest value once full.
+type Ring struct {
+	data  []float64
+	start int
+	size  int
+}
+
+func NewRing(capacity int) *Ring {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Ring{data: make([]float64, capacity)}
+}
+
+func (r *Ring) Push(v float64) {
+	if r.size < len(r.data) {
+		r.data[(r.start+r.size)%len(r.data)] = v
+		r.size++
+		return
+	}
+	r.data[r.start] = v
+	r.start = (r.start + 1) % len(r.data)
+}
+
+func (r *Ring) Len() int {
+	return r.size
+}
+
+// Values returns a copy of the buffered values, oldest first.
+func (r *Ring) Values() []float64 {
+	values := make([]float64, r.size)
+	for i := 0; i < r.size; i++ {
+		values[i] = r.data[(r.start+i)%len(r.data)]
+	}
+	return values
+}
+
+type History struct {
+	mu     sync.RWMutex
+	length int
+	series map[string]*Ring
+}
+
+func NewHistory(length int) *History {
+	if length < 1 {
+		length = 120
+	}
+	return &History{
+		length: length,
+		series: make(map[string]*Ring),
+	}
+}
+
+func (h *History) Length() int {
+	return h.length
+}
+
+func (h *History) Record(key string, v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.record(key, v)
+}
+
+func (h *History) record(key string, v float64) {
+	r, ok := h.series[key]
+	if !ok {
+		r = NewRing(h.length)
+		h.series[key] = r
+	}
+	r.Push(v)
+}
+
+func (h *History) Series(key string) []float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	r, ok := h.series[key]
+	if !ok {
+		return nil
+	}
+	return r.Values()
+}
+
+func (h *History) Keys(prefix string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var keys []string
+	for k := range h.series {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Observe records the metrics carried by s.
+func (h *History) Observe(s *Snapshot) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if s.CPU != nil {
+		h.record(HistoryCPU, s.CPU.Usage)
+		for i, usage := range s.CPU.PerCore {
+			h.record(HistoryCore(i), usage)
+		}
+	}
+
+	if s.Memory != nil {
+		h.record(HistoryMemory, s.Memory.UsedPercent)
+		h.record(HistorySwap, s.Memory.SwapPercent)
+	}
+
+	if s.Disk != nil {
+		h.record(HistoryDisk, s.Disk.UsedPercent)
+	}
+
+	seen := make(map[string]bool, 2*len(s.Network))
+	for _, stat := range s.Network {
+		recv, sent := HistoryNetRecv(stat.Interface), HistoryNetSent(stat.Interface)
+		h.record(recv, stat.Rates.BytesRecv)
+		h.record(sent, stat.Rates.BytesSent)
+		seen[recv], seen[sent] = true, true
+	}
+	// Interfaces come and go on container hosts; forget the ones that are
+	// gone.
+	for key := range h.series {
+		if strings.HasPrefix(key, "net/") && !seen[key] {
+			delete(h.series, key)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package system
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// ProcessDetails is everything the inspector shows about one process. Each
+// section is read on its own; one that cannot be read, typically for lack
+// of permission, is reported in Errors under its name and left empty.
+type ProcessDetails struct {
+	PID          int32
+	CommandLine  []string
+	Cwd          string
+	Exe          string
+	Environ      []string
+	EnvHidden    bool
+	Limits       []ProcessLimit
+	Cgroups      []string
+	Namespaces   []Namespace
+	Capabilities Capabilities
+	FDs          []FileDescriptor
+	FDCount      int
+	Maps         MapsSummary
+	Threads      []Thread
+	ThreadCount  int
+	Connections  []NetworkConnection
+	Errors       map[string]string
+}
+
+type ProcessLimit struct {
+	Name  string
+	Soft  string
+	Hard  string
+	Units string
+}
+
+type Namespace struct {
+	Type  string
+	Inode string
+}
+
+// Capabilities holds the capability sets from /proc/[pid]/status with the
+// effective set decoded into names.
+type Capabilities struct {
+	Inheritable string
+	Permitted   string
+	Effective   string
+	Bounding    string
+	Ambient     string
+	Names       []string
+}
+
+type FileDescriptor struct {
+	FD     int
+	Target string
+}
+
+type MappedFile struct {
+	Path string
+	Size uint64
+}
+
+// MapsSummary totals the mappings of a process by kind and lists the
+// mapped files with the most address space.
+type MapsSummary struct {
+	Regions   int
+	Total     uint64
+	Anonymous uint64
+	Heap      uint64
+	Stack     uint64
+	Files     []MappedFile
+}
+
+type Thread struct {
+	TID   int32
+	Name  string
+	State string
+}
+
+// Bounds on the lists returned so that a process with huge numbers of
+// descriptors, mappings or threads cannot make a response unbounded.
+const (
+	maxInspectFDs     = 512
+	maxInspectFiles   = 20
+	maxInspectThreads = 512
+)
+
+// InspectProcess reads the details of pid. The environment is only read
+// when withEnv is set, as it often holds secrets.
+func InspectProcess(pid int32, withEnv bool) (*ProcessDetails, error) {
+	dir := fmt.Sprintf("/proc/%d", pid)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("pid %d: no such process", pid)
+	}
+
+	d := &ProcessDetails{PID: pid, Errors: make(map[string]string)}
+	section := func(name string, err error) {
+		if err != nil {
+			d.Errors[name] = errorText(err)
+		}
+	}
+
+	if data, err := os.ReadFile(dir + "/cmdline"); err == nil {
+		d.CommandLine = splitNul(data)
+	} else {
+		section("cmdline", err)
+	}
+
+	var err error
+	d.Cwd, err = os.Readlink(dir + "/cwd")
+	section("cwd", err)
+	d.Exe, err = os.Readlink(dir + "/exe")
+	section("exe", err)
+
+	d.EnvHidden = !withEnv
+	if withEnv {
+		if data, err := os.ReadFile(dir + "/environ"); err == nil {
+			d.Environ = splitNul(data)
+		} else {
+			section("environ", err)
+		}
+	}
+
+	d.Limits, err = readLimits(dir + "/limits")
+	section("limits", err)
+
+	if data, err := os.ReadFile(dir + "/cgroup"); err == nil {
+		d.Cgroups = strings.Split(strings.TrimSpace(string(data)), "\n")
+	} else {
+		section("cgroup", err)
+	}
+
+	d.Namespaces, err = readNamespaces(dir + "/ns")
+	section("namespaces", err)
+
+	d.Capabilities, err = readCapabilities(dir + "/status")
+	section("capabilities", err)
+
+	d.FDs, d.FDCount, err = readFDs(dir + "/fd")
+	section("fds", err)
+
+	d.Maps, err = readMaps(dir + "/maps")
+	section("maps", err)
+
+	d.Threads, d.ThreadCount, err = readThreads(dir + "/task")
+	section("threads", err)
+
+	conns, err := net.ConnectionsPidWithContext(context.Background(), "inet", pid)
+	for _, conn := range conns {
+		d.Connections = append(d.Connections, NetworkConnection{
+			Status:     conn.Status,
+			LocalAddr:  conn.Laddr.IP,
+			LocalPort:  conn.Laddr.Port,
+			RemoteAddr: conn.Raddr.IP,
+			RemotePort: conn.Raddr.Port,
+			PID:        conn.Pid,
+		})
+	}
+	section("connections", err)
+
+	return d, nil
+}
+
+func errorText(err error) string {
+	if os.IsPermission(err) {
+		return "permission denied"
+	}
+	return err.Error()
+}
+
+func splitNul(data []byte) []string {
+	s := strings.TrimRight(string(data), "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+var limitColumns = regexp.MustCompile(`\s{2,}`)
+
+// readLimits parses /proc/[pid]/limits, whose columns are separated by runs
+// of spaces while limit names contain single spaces.
+func readLimits(path string) ([]ProcessLimit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []ProcessLimit
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if i == 0 {
+			continue
+		}
+		fields := limitColumns.Split(strings.TrimSpace(line), -1)
+		if len(fields) < 3 {
+			continue
+		}
+		limit := ProcessLimit{Name: fields[0], Soft: fields[1], Hard: fields[2]}
+		if len(fields) > 3 {
+			limit.Units = fields[3]
+		}
+		limits = append(limits, limit)
+	}
+	return limits, nil
+}
+
+func readNamespaces(dir string) ([]Namespace, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var namespaces []Namespace
+	for _, entry := range entries {
+		target, err := os.Readlink(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		// Links read like "net:[4026531840]".
+		inode := strings.TrimSuffix(strings.TrimPrefix(target, entry.Name()+":["), "]")
+		namespaces = append(namespaces, Namespace{Type: entry.Name(), Inode: inode})
+	}
+	return namespaces, nil
+}
+
+var capabilityNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill",
+	"setgid", "setuid", "setpcap", "linux_immutable", "net_bind_service",
+	"net_broadcast", "net_admin", "net_raw", "ipc_lock", "ipc_owner",
+	"sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time",
+	"sys_tty_config", "mknod", "lease", "audit_write", "audit_control",
+	"setfcap", "mac_override", "mac_admin", "syslog", "wake_alarm",
+	"block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+func readCapabilities(path string) (Capabilities, error) {
+	var caps Capabilities
+
+	file, err := os.Open(path)
+	if err != nil {
+		return caps, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "CapInh":
+			caps.Inheritable = value
+		case "CapPrm":
+			caps.Permitted = value
+		case "CapEff":
+			caps.Effective = value
+		case "CapBnd":
+			caps.Bounding = value
+		case "CapAmb":
+			caps.Ambient = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return caps, err
+	}
+
+	if mask, err := strconv.ParseUint(caps.Effective, 16, 64); err == nil {
+		for bit := 0; bit < 64; bit++ {
+			if mask&(1<<bit) == 0 {
+				continue
+			}
+			if bit < len(capabilityNames) {
+				caps.Names = append(caps.Names, capabilityNames[bit])
+			} else {
+				caps.Names = append(caps.Names, "cap_"+strconv.Itoa(bit))
+			}
+		}
+	}
+	return caps, nil
+}
+
+// readFDs lists up to maxInspectFDs descriptors, lowest first, and counts
+// them all.
+func readFDs(dir string) ([]FileDescriptor, int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fds := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if fd, err := strconv.Atoi(entry.Name()); err == nil {
+			fds = append(fds, fd)
+		}
+	}
+	sort.Ints(fds)
+	if len(fds) > maxInspectFDs {
+		fds = fds[:maxInspectFDs]
+	}
+
+	list := make([]FileDescriptor, 0, len(fds))
+	for _, fd := range fds {
+		// The descriptor may close between listing and reading it.
+		target, err := os.Readlink(filepath.Join(dir, strconv.Itoa(fd)))
+		if err != nil {
+			continue
+		}
+		list = append(list, FileDescriptor{FD: fd, Target: target})
+	}
+	return list, len(entries), nil
+}
+
+func readMaps(path string) (MapsSummary, error) {
+	var summary MapsSummary
+
+	file, err := os.Open(path)
+	if err != nil {
+		return summary, err
+	}
+	defer file.Close()
+
+	files := make(map[string]uint64)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// address perms offset dev inode [pathname]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		lo, hi, ok := strings.Cut(fields[0], "-")
+		if !ok {
+			continue
+		}
+		start, err1 := strconv.ParseUint(lo, 16, 64)
+		end, err2 := strconv.ParseUint(hi, 16, 64)
+		if err1 != nil || err2 != nil || end < start {
+			continue
+		}
+		size := end - start
+
+		summary.Regions++
+		summary.Total += size
+
+		name := ""
+		if len(fields) > 5 {
+			name = strings.Join(fields[5:], " ")
+		}
+		switch {
+		case name == "":
+			summary.Anonymous += size
+		case name == "[heap]":
+			summary.Heap += size
+		case strings.HasPrefix(name, "[stack"):
+			summary.Stack += size
+		case strings.HasPrefix(name, "/"):
+			files[name] += size
+		default:
+			summary.Anonymous += size
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+
+	for name, size := range files {
+		summary.Files = append(summary.Files, MappedFile{Path: name, Size: size})
+	}
+	sort.Slice(summary.Files, func(i, j int) bool {
+		if summary.Files[i].Size != summary.Files[j].Size {
+			return summary.Files[i].Size > summary.Files[j].Size
+		}
+		return summary.Files[i].Path < summary.Files[j].Path
+	})
+	if len(summary.Files) > maxInspectFiles {
+		summary.Files = summary.Files[:maxInspectFiles]
+	}
+	return summary, nil
+}
+
+// readThreads lists up to maxInspectThreads threads, lowest TID first, and
+// counts them all.
+func readThreads(dir string) ([]Thread, int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	sort.Ints(tids)
+	count := len(tids)
+	if len(tids) > maxInspectThreads {
+		tids = tids[:maxInspectThreads]
+	}
+
+	threads := make([]Thread, 0, len(tids))
+	for _, tid := range tids {
+		data, err := os.ReadFile(filepath.Join(dir, strconv.Itoa(tid), "stat"))
+		if err != nil {
+			continue
+		}
+		// The name is in parentheses and may itself contain spaces and
+		// parentheses, so the state is found after the last ')'.
+		stat := string(data)
+		start, end := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+		if start < 0 || end < start {
+			continue
+		}
+		thread := Thread{TID: int32(tid), Name: stat[start+1 : end]}
+		if rest := strings.Fields(stat[end+1:]); len(rest) > 0 {
+			thread.State = rest[0]
+		}
+		threads = append(threads, thread)
+	}
+	return threads, count, nil
+}
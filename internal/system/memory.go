@@ -14,6 +14,10 @@ type MemoryMetrics struct {
 	UsedPercent float64
 	Cached      uint64
 	Buffers     uint64
+	SwapTotal   uint64
+	SwapUsed    uint64
+	SwapFree    uint64
+	SwapPercent float64
 }
 
 func GetMemoryMetrics() (*MemoryMetrics, error) {
@@ -24,6 +28,11 @@ func GetMemoryMetrics() (*MemoryMetrics, error) {
 		return nil, err
 	}
 
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MemoryMetrics{
 		Total:       vmem.Total,
 		Used:        vmem.Used,
@@ -32,5 +41,9 @@ func GetMemoryMetrics() (*MemoryMetrics, error) {
 		UsedPercent: vmem.UsedPercent,
 		Cached:      vmem.Cached,
 		Buffers:     vmem.Buffers,
+		SwapTotal:   swap.Total,
+		SwapUsed:    swap.Used,
+		SwapFree:    swap.Free,
+		SwapPercent: swap.UsedPercent,
 	}, nil
 }
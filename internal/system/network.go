@@ -2,6 +2,8 @@ package system
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/shirou/gopsutil/v3/net"
 )
@@ -16,6 +18,20 @@ type NetworkStats struct {
 	ErrorsOut   uint64
 	DropIn      uint64
 	DropOut     uint64
+	Rates       NetworkRates
+}
+
+type NetworkRates struct {
+	BytesSent   float64
+	BytesRecv   float64
+	PacketsSent float64
+	PacketsRecv float64
+	Errors      float64
+	Drops       float64
+	PeakSent    float64
+	PeakRecv    float64
+	AvgSent     float64
+	AvgRecv     float64
 }
 
 type NetworkConnection struct {
@@ -27,7 +43,108 @@ type NetworkConnection struct {
 	PID        int32
 }
 
+type interfaceTotals struct {
+	samples  int
+	sumSent  float64
+	sumRecv  float64
+	peakSent float64
+	peakRecv float64
+}
+
+// NetworkSampler keeps the previous interface counters so that each sample
+// carries per-second rates, plus the peak and average rate seen since the
+// sampler was created. The first sample has zero rates.
+type NetworkSampler struct {
+	mu       sync.Mutex
+	prev     map[string]NetworkStats
+	prevTime time.Time
+	totals   map[string]*interfaceTotals
+}
+
+func NewNetworkSampler() *NetworkSampler {
+	return &NetworkSampler{
+		prev:   make(map[string]NetworkStats),
+		totals: make(map[string]*interfaceTotals),
+	}
+}
+
+func (s *NetworkSampler) Sample() ([]NetworkStats, error) {
+	stats, err := readNetworkCounters()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(s.prevTime).Seconds()
+	current := make(map[string]NetworkStats, len(stats))
+
+	for i := range stats {
+		stat := &stats[i]
+		current[stat.Interface] = *stat
+
+		prev, ok := s.prev[stat.Interface]
+		if !ok || s.prevTime.IsZero() || elapsed <= 0 {
+			continue
+		}
+
+		stat.Rates = NetworkRates{
+			BytesSent:   counterRate(prev.BytesSent, stat.BytesSent, elapsed),
+			BytesRecv:   counterRate(prev.BytesRecv, stat.BytesRecv, elapsed),
+			PacketsSent: counterRate(prev.PacketsSent, stat.PacketsSent, elapsed),
+			PacketsRecv: counterRate(prev.PacketsRecv, stat.PacketsRecv, elapsed),
+			Errors:      counterRate(prev.ErrorsIn+prev.ErrorsOut, stat.ErrorsIn+stat.ErrorsOut, elapsed),
+			Drops:       counterRate(prev.DropIn+prev.DropOut, stat.DropIn+stat.DropOut, elapsed),
+		}
+
+		totals, ok := s.totals[stat.Interface]
+		if !ok {
+			totals = &interfaceTotals{}
+			s.totals[stat.Interface] = totals
+		}
+		totals.samples++
+		totals.sumSent += stat.Rates.BytesSent
+		totals.sumRecv += stat.Rates.BytesRecv
+		if stat.Rates.BytesSent > totals.peakSent {
+			totals.peakSent = stat.Rates.BytesSent
+		}
+		if stat.Rates.BytesRecv > totals.peakRecv {
+			totals.peakRecv = stat.Rates.BytesRecv
+		}
+
+		stat.Rates.PeakSent = totals.peakSent
+		stat.Rates.PeakRecv = totals.peakRecv
+		stat.Rates.AvgSent = totals.sumSent / float64(totals.samples)
+		stat.Rates.AvgRecv = totals.sumRecv / float64(totals.samples)
+	}
+
+	for iface := range s.totals {
+		if _, ok := current[iface]; !ok {
+			delete(s.totals, iface)
+		}
+	}
+	s.prev = current
+	s.prevTime = now
+
+	return stats, nil
+}
+
+func counterRate(prev, cur uint64, seconds float64) float64 {
+	if cur < prev {
+		return 0
+	}
+	return float64(cur-prev) / seconds
+}
+
+var defaultNetworkSampler = NewNetworkSampler()
+
 func GetNetworkStats() ([]NetworkStats, error) {
+	return defaultNetworkSampler.Sample()
+}
+
+func readNetworkCounters() ([]NetworkStats, error) {
 	ctx := context.Background()
 
 	ioCounters, err := net.IOCountersWithContext(ctx, true)
@@ -73,13 +73,16 @@ func listAptPackages() ([]PackageInfo, error) {
 	for _, line := range lines {
 		parts := strings.SplitN(line, "\t", 4)
 		if len(parts) >= 2 {
-			packages = append(packages, PackageInfo{
-				Name:        parts[0],
-				Version:     parts[1],
-				Description: parts[2],
-				Size:        parts[3],
-				Status:      "installed",
-			})
+			pkg := PackageInfo{
+				Name:    parts[0],
+				Version: parts[1],
+				Status:  "installed",
+			}
+			if len(parts) == 4 {
+				pkg.Description = parts[2]
+				pkg.Size = parts[3]
+			}
+			packages = append(packages, pkg)
 		}
 	}
 
@@ -0,0 +1,192 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"golang.org/x/sys/unix"
+)
+
+// IOClass is a Linux I/O scheduling class.
+type IOClass int
+
+const (
+	IOClassNone IOClass = iota
+	IOClassRealtime
+	IOClassBestEffort
+	IOClassIdle
+)
+
+var ioClassNames = []string{"none", "realtime", "best-effort", "idle"}
+
+func (c IOClass) String() string {
+	if c >= 0 && int(c) < len(ioClassNames) {
+		return ioClassNames[c]
+	}
+	return strconv.Itoa(int(c))
+}
+
+func ParseIOClass(name string) (IOClass, error) {
+	for i, n := range ioClassNames {
+		if strings.EqualFold(name, n) {
+			return IOClass(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown I/O class %q", name)
+}
+
+func (c IOClass) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+func (c *IOClass) UnmarshalText(text []byte) error {
+	class, err := ParseIOClass(string(text))
+	if err != nil {
+		return err
+	}
+	*c = class
+	return nil
+}
+
+// Priority is the scheduling state of a process. With IOClassNone the
+// kernel derives the I/O priority from the nice value.
+type Priority struct {
+	Nice     int     `json:"nice"`
+	IOClass  IOClass `json:"io_class"`
+	IOLevel  int     `json:"io_level"`
+	Affinity []int   `json:"affinity"`
+	CPUs     int     `json:"cpus"`
+}
+
+// ioprio_get and ioprio_set constants from linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioLevelMask  = 0xff
+)
+
+func GetPriority(pid int32) (Priority, error) {
+	var p Priority
+
+	// The raw syscall returns 20 - nice so that the result is never negative.
+	prio, err := unix.Getpriority(unix.PRIO_PROCESS, int(pid))
+	if err != nil {
+		return p, priorityError("read nice value of", pid, err)
+	}
+	p.Nice = 20 - prio
+
+	ioprio, _, errno := unix.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(pid), 0)
+	if errno != 0 {
+		return p, priorityError("read I/O priority of", pid, errno)
+	}
+	p.IOClass = IOClass(ioprio >> ioprioClassShift)
+	p.IOLevel = int(ioprio & ioprioLevelMask)
+
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(int(pid), &set); err != nil {
+		return p, priorityError("read CPU affinity of", pid, err)
+	}
+	for i := 0; i < len(set)*64; i++ {
+		if set.IsSet(i) {
+			p.Affinity = append(p.Affinity, i)
+		}
+	}
+
+	if n, err := cpu.Counts(true); err == nil {
+		p.CPUs = n
+	}
+	return p, nil
+}
+
+// ReniceProcess sets the nice value of every thread of pid.
+func ReniceProcess(pid int32, nice int) error {
+	if nice < -20 || nice > 19 {
+		return fmt.Errorf("nice must be between -20 and 19")
+	}
+	for _, tid := range threads(pid) {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, tid, nice); err != nil {
+			return priorityError("renice", pid, err)
+		}
+	}
+	return nil
+}
+
+// SetIOPriority sets the I/O class and level (0 highest, 7 lowest) of every
+// thread of pid. The idle and none classes take no level.
+func SetIOPriority(pid int32, class IOClass, level int) error {
+	if class < IOClassNone || class > IOClassIdle {
+		return fmt.Errorf("unknown I/O class %d", class)
+	}
+	if level < 0 || level > 7 {
+		return fmt.Errorf("I/O level must be between 0 and 7")
+	}
+	if class == IOClassIdle || class == IOClassNone {
+		level = 0
+	}
+
+	ioprio := uintptr(class)<<ioprioClassShift | uintptr(level)
+	for _, tid := range threads(pid) {
+		_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(tid), ioprio)
+		if errno != 0 {
+			return priorityError("set I/O priority of", pid, errno)
+		}
+	}
+	return nil
+}
+
+// SetAffinity restricts every thread of pid to the given CPUs.
+func SetAffinity(pid int32, cpus []int) error {
+	if len(cpus) == 0 {
+		return fmt.Errorf("affinity must include at least one CPU")
+	}
+
+	var set unix.CPUSet
+	for _, i := range cpus {
+		if i < 0 || i >= len(set)*64 {
+			return fmt.Errorf("CPU %d out of range", i)
+		}
+		set.Set(i)
+	}
+	for _, tid := range threads(pid) {
+		if err := unix.SchedSetaffinity(tid, &set); err != nil {
+			return priorityError("set CPU affinity of", pid, err)
+		}
+	}
+	return nil
+}
+
+// threads lists the thread IDs of pid. Nice values, I/O priorities and
+// affinity are per thread on Linux, so changing only the main thread would
+// leave the others as they were.
+func threads(pid int32) []int {
+	entries, err := os.ReadDir(fmt.Sprintf("/proc/%d/task", pid))
+	if err != nil {
+		return []int{int(pid)}
+	}
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		if tid, err := strconv.Atoi(entry.Name()); err == nil {
+			tids = append(tids, tid)
+		}
+	}
+	if len(tids) == 0 {
+		return []int{int(pid)}
+	}
+	return tids
+}
+
+func priorityError(op string, pid int32, err error) error {
+	switch {
+	case errors.Is(err, unix.EPERM), errors.Is(err, unix.EACCES):
+		return fmt.Errorf("%s pid %d: permission denied (raising priority or changing another user's process needs root or CAP_SYS_NICE, realtime I/O needs CAP_SYS_ADMIN)", op, pid)
+	case errors.Is(err, unix.ESRCH):
+		return fmt.Errorf("%s pid %d: no such process", op, pid)
+	case errors.Is(err, unix.EINVAL):
+		return fmt.Errorf("%s pid %d: invalid value", op, pid)
+	}
+	return fmt.Errorf("%s pid %d: %w", op, pid, err)
+}
@@ -1,16 +1,28 @@
 package system
 
 import (
+	"bytes"
 	"context"
-	"os/exec"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/process"
 )
 
 type ProcessInfo struct {
 	PID         int32
+	PPID        int32
 	Name        string
 	CPUPercent  float64
 	MemPercent  float32
@@ -19,57 +31,493 @@ type ProcessInfo struct {
 	CreateTime  int64
 	CommandLine string
 	Nice        int32
+	Threads     int32
+	Memory      ProcessMemory
 }
 
-func GetProcesses() ([]ProcessInfo, error) {
+// ProcessMemory is the memory use of a process in bytes. PSS divides each
+// shared page between the processes mapping it and USS counts only private
+// pages, so unlike RSS both can be added up across processes. They come from
+// /proc/[pid]/smaps_rollup, read again every rollupInterval; when that
+// cannot be read, typically for another user's process, Rollup is false and
+// only RSS and VSZ are set.
+type ProcessMemory struct {
+	RSS    uint64
+	PSS    uint64
+	USS    uint64
+	Shared uint64
+	Swap   uint64
+	VSZ    uint64
+	Rollup bool
+}
+
+// rollupInterval is how often the smaps_rollup of a process is read. The
+// kernel walks the page tables of the process to produce it, which costs far
+// more than stat and status, so it is not read on every scan.
+const rollupInterval = 30 * time.Second
+
+// clockTicks is USER_HZ, the unit of the CPU times in /proc/[pid]/stat. It
+// is 100 on every architecture Linux supports.
+const clockTicks = 100
+
+// ProcessSampler keeps the CPU time of every process seen in the previous
+// scan so that each call to Sample reports usage over the interval since
+// then rather than since the process started. A process seen for the first
+// time reports its average since it started. Like gopsutil, 100% is one
+// fully used CPU.
+//
+// Each scan reads /proc/[pid]/stat and /proc/[pid]/status into a reused
+// buffer; the command line is only read again when the process name changes.
+type ProcessSampler struct {
+	// proc is where the proc filesystem is mounted, /proc outside tests.
+	proc     string
+	mu       sync.Mutex
+	entries  map[int32]*processEntry
+	users    map[uint32]string
+	lastScan time.Time
+	bootTime int64
+	pageSize uint64
+	buf      []byte
+	fields   [22][]byte
+}
+
+// processEntry is the state kept for one PID between scans. The start time
+// tells a process apart from a later one that reuses its PID.
+type processEntry struct {
+	startTicks  uint64
+	cpuTicks    uint64
+	name        string
+	comm        string
+	cmdline     string
+	cmdlineRead bool
+	rollup      ProcessMemory
+	rollupRead  time.Time
+	seen        bool
+}
+
+func NewProcessSampler() *ProcessSampler {
+	return &ProcessSampler{
+		proc:     "/proc",
+		entries:  make(map[int32]*processEntry),
+		users:    make(map[uint32]string),
+		pageSize: uint64(os.Getpagesize()),
+		buf:      make([]byte, 4096),
+	}
+}
+
+func (s *ProcessSampler) Sample() ([]ProcessInfo, error) {
 	ctx := context.Background()
 
-	pids, err := process.PidsWithContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.bootTime == 0 {
+		boot, err := host.BootTimeWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s.bootTime = int64(boot)
+	}
+
+	var memTotal uint64
+	if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+		memTotal = vm.Total
+	}
+
+	dir, err := os.Open(s.proc)
+	if err != nil {
+		return nil, err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
 	if err != nil {
 		return nil, err
 	}
 
-	var processes []ProcessInfo
-	for _, pid := range pids {
-		proc, err := process.NewProcessWithContext(ctx, pid)
+	now := time.Now()
+	interval := now.Sub(s.lastScan).Seconds()
+
+	for _, entry := range s.entries {
+		entry.seen = false
+	}
+
+	processes := make([]ProcessInfo, 0, len(s.entries))
+	for _, name := range names {
+		pid, err := strconv.ParseInt(name, 10, 32)
 		if err != nil {
 			continue
 		}
+		info, ok := s.sampleProcess(int32(pid), now, interval, memTotal)
+		if ok {
+			processes = append(processes, info)
+		}
+	}
 
-		name, _ := proc.NameWithContext(ctx)
-		cpuPercent, _ := proc.CPUPercentWithContext(ctx)
-		memPercent, _ := proc.MemoryPercentWithContext(ctx)
-		status, _ := proc.StatusWithContext(ctx)
-		username, _ := proc.UsernameWithContext(ctx)
-		createTime, _ := proc.CreateTimeWithContext(ctx)
-		cmdline, _ := proc.CmdlineWithContext(ctx)
-		nice, _ := proc.NiceWithContext(ctx)
-
-		processes = append(processes, ProcessInfo{
-			PID:         pid,
-			Name:        name,
-			CPUPercent:  cpuPercent,
-			MemPercent:  memPercent,
-			Status:      status[0],
-			User:        username,
-			CreateTime:  createTime,
-			CommandLine: cmdline,
-			Nice:        nice,
-		})
+	// Forget the processes that exited since the last scan.
+	for pid, entry := range s.entries {
+		if !entry.seen {
+			delete(s.entries, pid)
+		}
 	}
+	s.lastScan = now
 
 	return processes, nil
 }
 
+// sampleProcess reads one process. It reports false when the process
+// vanished while it was being read.
+func (s *ProcessSampler) sampleProcess(pid int32, now time.Time, interval float64, memTotal uint64) (ProcessInfo, bool) {
+	prefix := s.proc + "/" + strconv.Itoa(int(pid))
+
+	stat, err := s.read(prefix + "/stat")
+	if err != nil {
+		return ProcessInfo{}, false
+	}
+	// The name is in parentheses and may itself contain spaces and
+	// parentheses, so the other fields start after the last ')'.
+	open, closing := bytes.IndexByte(stat, '('), bytes.LastIndexByte(stat, ')')
+	if open < 0 || closing < open {
+		return ProcessInfo{}, false
+	}
+	fields := s.split(stat[closing+1:])
+	if len(fields) < len(s.fields) {
+		return ProcessInfo{}, false
+	}
+
+	// Fields are numbered from 3 (state) in proc(5). Everything is parsed
+	// before the next read reuses the buffer.
+	status := statusName(fields[0])
+	ppid := parseInt(fields[1])
+	cpuTicks := uint64(parseInt(fields[11]) + parseInt(fields[12]))
+	nice := parseInt(fields[16])
+	threads := parseInt(fields[17])
+	startTicks := uint64(parseInt(fields[19]))
+	memory := ProcessMemory{
+		VSZ: uint64(parseInt(fields[20])),
+		RSS: uint64(parseInt(fields[21])) * s.pageSize,
+	}
+
+	entry, known := s.entries[pid]
+	if !known || entry.startTicks != startTicks {
+		entry = &processEntry{startTicks: startTicks}
+		s.entries[pid] = entry
+		known = false
+	}
+	entry.seen = true
+
+	createTime := s.bootTime*1000 + int64(startTicks)*1000/clockTicks
+
+	// A process seen before is measured over the interval since the last
+	// scan, a new one over its whole life.
+	elapsed := interval
+	used := cpuTicks - entry.cpuTicks
+	if !known || cpuTicks < entry.cpuTicks {
+		elapsed = float64(now.UnixMilli()-createTime) / 1000
+		used = cpuTicks
+	}
+	var cpuPercent float64
+	if elapsed > 0 {
+		cpuPercent = float64(used) / clockTicks / elapsed * 100
+	}
+	entry.cpuTicks = cpuTicks
+
+	if comm := stat[open+1 : closing]; entry.comm != string(comm) {
+		entry.comm = string(comm)
+		entry.cmdlineRead = false
+	}
+	if !entry.cmdlineRead {
+		if data, err := s.read(prefix + "/cmdline"); err == nil {
+			entry.cmdline = strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+			entry.name = processName(entry.comm, data)
+			entry.cmdlineRead = true
+		} else {
+			entry.name = entry.comm
+		}
+	}
+
+	if now.Sub(entry.rollupRead) >= rollupInterval {
+		entry.rollup = ProcessMemory{}
+		if data, err := s.read(prefix + "/smaps_rollup"); err == nil {
+			parseRollup(data, &entry.rollup)
+		}
+		entry.rollupRead = now
+	}
+	if rollup := entry.rollup; rollup.Rollup {
+		memory.PSS, memory.USS, memory.Shared, memory.Swap = rollup.PSS, rollup.USS, rollup.Shared, rollup.Swap
+		memory.Rollup = true
+	}
+
+	var memPercent float32
+	if memTotal > 0 {
+		memPercent = float32(float64(memory.RSS) / float64(memTotal) * 100)
+	}
+
+	return ProcessInfo{
+		PID:         pid,
+		PPID:        int32(ppid),
+		Name:        entry.name,
+		CPUPercent:  cpuPercent,
+		MemPercent:  memPercent,
+		Status:      status,
+		User:        s.user(prefix + "/status"),
+		CreateTime:  createTime,
+		CommandLine: entry.cmdline,
+		Nice:        int32(nice),
+		Threads:     int32(threads),
+		Memory:      memory,
+	}, true
+}
+
+// read reads a /proc file into the sampler's buffer, growing it as needed.
+// The result is only valid until the next read.
+func (s *ProcessSampler) read(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	n := 0
+	for {
+		m, err := file.Read(s.buf[n:])
+		n += m
+		if err == io.EOF {
+			return s.buf[:n], nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if n == len(s.buf) {
+			s.buf = append(s.buf, make([]byte, len(s.buf))...)
+		}
+	}
+}
+
+// split fills the sampler's field array with the first space-separated
+// fields of data.
+func (s *ProcessSampler) split(data []byte) [][]byte {
+	fields := s.fields[:0]
+	for len(fields) < len(s.fields) {
+		data = bytes.TrimLeft(data, " \n")
+		if len(data) == 0 {
+			break
+		}
+		end := bytes.IndexAny(data, " \n")
+		if end < 0 {
+			end = len(data)
+		}
+		fields = append(fields, data[:end])
+		data = data[end:]
+	}
+	return fields
+}
+
+// user returns the name of the real user of a process, looking each user ID
+// up only once.
+func (s *ProcessSampler) user(path string) string {
+	status, err := s.read(path)
+	if err != nil {
+		return ""
+	}
+	i := bytes.Index(status, []byte("\nUid:"))
+	if i < 0 {
+		return ""
+	}
+	rest := bytes.TrimLeft(status[i+len("\nUid:"):], " \t")
+	end := bytes.IndexAny(rest, " \t\n")
+	if end < 0 {
+		return ""
+	}
+	uid, err := strconv.ParseUint(string(rest[:end]), 10, 32)
+	if err != nil {
+		return ""
+	}
+
+	name, ok := s.users[uint32(uid)]
+	if !ok {
+		name = strconv.FormatUint(uid, 10)
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		s.users[uint32(uid)] = name
+	}
+	return name
+}
+
+// parseRollup fills m from the contents of /proc/[pid]/smaps_rollup, whose
+// sizes are in kB. Kernel threads have an empty file, as they have no
+// memory of their own.
+func parseRollup(data []byte, m *ProcessMemory) {
+	if len(data) == 0 {
+		m.Rollup = true
+		return
+	}
+	var rollup ProcessMemory
+	found := false
+	for len(data) > 0 {
+		line := data
+		if end := bytes.IndexByte(data, '\n'); end >= 0 {
+			line, data = data[:end], data[end+1:]
+		} else {
+			data = nil
+		}
+
+		key, value, ok := bytes.Cut(line, []byte(":"))
+		if !ok {
+			continue
+		}
+		value = bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(value), []byte("kB")))
+		kb := uint64(parseInt(value)) * 1024
+
+		switch string(key) {
+		case "Rss":
+			rollup.RSS = kb
+			found = true
+		case "Pss":
+			rollup.PSS = kb
+		case "Shared_Clean", "Shared_Dirty":
+			rollup.Shared += kb
+		case "Private_Clean", "Private_Dirty":
+			rollup.USS += kb
+		case "Swap":
+			rollup.Swap = kb
+		}
+	}
+	if found {
+		rollup.VSZ = m.VSZ
+		rollup.Rollup = true
+		*m = rollup
+	}
+}
+
+func parseInt(field []byte) int64 {
+	n, _ := strconv.ParseInt(string(field), 10, 64)
+	return n
+}
+
+// processName returns the full program name when the kernel's copy, which
+// is cut at 15 characters, is a prefix of the first command line argument.
+func processName(comm string, cmdline []byte) string {
+	if len(comm) < 15 {
+		return comm
+	}
+	arg0, _, _ := bytes.Cut(cmdline, []byte{0})
+	if base := filepath.Base(string(arg0)); strings.HasPrefix(base, comm) {
+		return base
+	}
+	return comm
+}
+
+func statusName(state []byte) string {
+	switch string(state) {
+	case "R":
+		return process.Running
+	case "S":
+		return process.Sleep
+	case "D":
+		return process.Blocked
+	case "T", "t":
+		return process.Stop
+	case "Z":
+		return process.Zombie
+	case "I":
+		return process.Idle
+	case "W":
+		return process.Wait
+	case "X", "x":
+		return "dead"
+	}
+	return "unknown"
+}
+
+var defaultProcessSampler = NewProcessSampler()
+
+func GetProcesses() ([]ProcessInfo, error) {
+	return defaultProcessSampler.Sample()
+}
+
 func KillProcess(pid int32, signal syscall.Signal) error {
 	proc, err := process.NewProcess(pid)
 	if err != nil {
 		return err
 	}
-	return proc.Kill()
+	return proc.SendSignal(signal)
+}
+
+// ErrProcessGone is returned by SignalProcessStartedAt when the process
+// exited or its PID belongs to a later process.
+var ErrProcessGone = errors.New("process exited")
+
+// SignalProcessStartedAt sends sig to pid only if it is still the process
+// started at createTime, as reported in ProcessInfo.CreateTime, so that a
+// later process reusing the PID is left alone.
+func SignalProcessStartedAt(pid int32, sig syscall.Signal, createTime int64) error {
+	boot, err := host.BootTime()
+	if err != nil {
+		return err
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrProcessGone
+	}
+	if err != nil {
+		return err
+	}
+	closing := bytes.LastIndexByte(stat, ')')
+	if closing < 0 {
+		return fmt.Errorf("pid %d: malformed stat", pid)
+	}
+	// starttime is field 22 in proc(5), the 20th after the name.
+	fields := bytes.Fields(stat[closing+1:])
+	if len(fields) < 20 {
+		return fmt.Errorf("pid %d: malformed stat", pid)
+	}
+	if int64(boot)*1000+parseInt(fields[19])*1000/clockTicks != createTime {
+		return ErrProcessGone
+	}
+	if err := syscall.Kill(int(pid), sig); err != nil {
+		if errors.Is(err, syscall.ESRCH) {
+			return ErrProcessGone
+		}
+		return err
+	}
+	return nil
+}
+
+var signalNames = map[string]syscall.Signal{
+	"HUP":  syscall.SIGHUP,
+	"INT":  syscall.SIGINT,
+	"QUIT": syscall.SIGQUIT,
+	"KILL": syscall.SIGKILL,
+	"USR1": syscall.SIGUSR1,
+	"USR2": syscall.SIGUSR2,
+	"TERM": syscall.SIGTERM,
+	"CONT": syscall.SIGCONT,
+	"STOP": syscall.SIGSTOP,
+	"TSTP": syscall.SIGTSTP,
+}
+
+// ParseSignal accepts names with or without the SIG prefix, in any case, or
+// signal numbers.
+func ParseSignal(name string) (syscall.Signal, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if n, err := strconv.Atoi(name); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("signal %d out of range", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := signalNames[strings.TrimPrefix(name, "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", name)
 }
 
-func ReniceProcess(pid int32, nice int) error {
-	cmd := exec.Command("renice", strconv.Itoa(nice), strconv.Itoa(int(pid)))
-	return cmd.Run()
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return name
+		}
+	}
+	return strconv.Itoa(int(sig))
 }
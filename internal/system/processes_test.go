@@ -0,0 +1,295 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// statLine builds a /proc/[pid]/stat line with the fields the sampler reads.
+func statLine(pid int, comm, state string, ppid int, utime, stime uint64, nice, threads int, start, vsz, rss uint64) string {
+	return fmt.Sprintf("%d (%s) %s %d 1 1 0 -1 4194304 85 0 0 0 %d %d 0 0 20 %d %d 0 %d %d %d 18446744073709551615 0 0\n",
+		pid, comm, state, ppid, utime, stime, nice, threads, start, vsz, rss)
+}
+
+// newTestSampler returns a sampler reading a proc directory in a temporary
+// directory, and a function writing the files of a process there.
+func newTestSampler(t *testing.T) (*ProcessSampler, func(pid int, files map[string]string)) {
+	s := NewProcessSampler()
+	s.proc = t.TempDir()
+	s.pageSize = 4096
+	return s, func(pid int, files map[string]string) {
+		dir := filepath.Join(s.proc, fmt.Sprint(pid))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestSampleProcessStat(t *testing.T) {
+	tests := []struct {
+		name    string
+		stat    string
+		cmdline string
+		want    ProcessInfo
+	}{
+		{
+			name:    "captured",
+			stat:    "13135 (cat) R 13075 13135 13075 0 -1 4194304 85 0 0 0 0 0 0 0 20 0 1 0 602919 2703360 335 18446744073709551615 94171444113408 94171444133289 140736770368112 0 0 0 0 0 0 0 0 0 17 0 0 0 0 0 0 94171444149296 94171444150912 94171904237568 140736770377022 140736770377042 140736770377042 140736770379755 0\n",
+			cmdline: "cat\x00/proc/self/stat\x00",
+			want: ProcessInfo{
+				PPID: 13075, Name: "cat", Status: "running", Threads: 1, CommandLine: "cat /proc/self/stat",
+				Memory: ProcessMemory{VSZ: 2703360, RSS: 335 * 4096},
+			},
+		},
+		{
+			name:    "comm with parentheses",
+			stat:    statLine(42, "evil) (name", "S", 1, 0, 0, 5, 3, 100, 8192, 2),
+			cmdline: "./evil\x00",
+			want: ProcessInfo{
+				PPID: 1, Name: "evil) (name", Status: "sleep", Nice: 5, Threads: 3, CommandLine: "./evil",
+				Memory: ProcessMemory{VSZ: 8192, RSS: 2 * 4096},
+			},
+		},
+		{
+			name: "comm with spaces",
+			stat: statLine(42, "tmux: server", "S", 1, 0, 0, -10, 1, 100, 0, 0),
+			want: ProcessInfo{PPID: 1, Name: "tmux: server", Status: "sleep", Nice: -10, Threads: 1},
+		},
+		{
+			name:    "truncated comm",
+			stat:    statLine(42, "systemd-journal", "S", 1, 0, 0, 0, 1, 100, 0, 0),
+			cmdline: "/usr/lib/systemd/systemd-journald\x00",
+			want: ProcessInfo{
+				PPID: 1, Name: "systemd-journald", Status: "sleep", Threads: 1,
+				CommandLine: "/usr/lib/systemd/systemd-journald",
+			},
+		},
+		{
+			name: "kernel thread",
+			stat: statLine(42, "kworker/0:1-events", "I", 2, 0, 0, 0, 1, 100, 0, 0),
+			want: ProcessInfo{PPID: 2, Name: "kworker/0:1-events", Status: "idle", Threads: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, write := newTestSampler(t)
+			write(42, map[string]string{"stat": tt.stat, "cmdline": tt.cmdline})
+			got, ok := s.sampleProcess(42, time.Now(), 0, 0)
+			if !ok {
+				t.Fatal("sampleProcess failed")
+			}
+			got.PID, got.CPUPercent, got.CreateTime = 0, 0, 0
+			if got != tt.want {
+				t.Errorf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleProcessMalformed(t *testing.T) {
+	for _, stat := range []string{
+		"",
+		"42 cat R 1",
+		"42 (cat) R 1 1 1 0",
+	} {
+		s, write := newTestSampler(t)
+		write(42, map[string]string{"stat": stat})
+		if _, ok := s.sampleProcess(42, time.Now(), 0, 0); ok {
+			t.Errorf("sampleProcess(%q) succeeded", stat)
+		}
+	}
+	s, _ := newTestSampler(t)
+	if _, ok := s.sampleProcess(42, time.Now(), 0, 0); ok {
+		t.Error("sampleProcess of a missing process succeeded")
+	}
+}
+
+func TestSampleProcessCPU(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	s, write := newTestSampler(t)
+	// Booted 1000s before base, so start tick 99000 is 10s before it.
+	s.bootTime = base.Unix() - 1000
+
+	steps := []struct {
+		name       string
+		at         time.Duration
+		start      uint64
+		ticks      uint64
+		want       float64
+		createTime int64
+	}{
+		{"first scan averages over the lifetime", 0, 99000, 500, 50, base.UnixMilli() - 10000},
+		{"later scans measure the interval", 2 * time.Second, 99000, 700, 100, base.UnixMilli() - 10000},
+		{"counter going backwards falls back to the lifetime", 4 * time.Second, 99000, 140, 10, base.UnixMilli() - 10000},
+		{"reused pid starts over", 6 * time.Second, 100400, 100, 50, base.UnixMilli() + 4000},
+		{"reused pid then measures the interval", 8 * time.Second, 100400, 300, 100, base.UnixMilli() + 4000},
+	}
+	var last time.Duration
+	for _, step := range steps {
+		write(42, map[string]string{"stat": statLine(42, "worker", "R", 1, step.ticks/2, step.ticks-step.ticks/2, 0, 1, step.start, 0, 0)})
+		got, ok := s.sampleProcess(42, base.Add(step.at), (step.at - last).Seconds(), 0)
+		last = step.at
+		if !ok {
+			t.Fatalf("%s: sampleProcess failed", step.name)
+		}
+		if diff := got.CPUPercent - step.want; diff < -0.001 || diff > 0.001 {
+			t.Errorf("%s: CPUPercent = %v, want %v", step.name, got.CPUPercent, step.want)
+		}
+		if got.CreateTime != step.createTime {
+			t.Errorf("%s: CreateTime = %d, want %d", step.name, got.CreateTime, step.createTime)
+		}
+	}
+}
+
+func TestSampleProcessMemPercent(t *testing.T) {
+	s, write := newTestSampler(t)
+	write(42, map[string]string{"stat": statLine(42, "worker", "R", 1, 0, 0, 0, 1, 100, 0, 256)})
+	got, _ := s.sampleProcess(42, time.Now(), 0, 4<<20)
+	if got.MemPercent != 25 {
+		t.Errorf("MemPercent = %v, want 25", got.MemPercent)
+	}
+}
+
+func TestSampleProcessRollup(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	s, write := newTestSampler(t)
+	stat := statLine(42, "worker", "R", 1, 0, 0, 0, 1, 100, 1<<20, 3)
+	write(42, map[string]string{"stat": stat, "smaps_rollup": "Rss: 12 kB\nPss: 8 kB\nPrivate_Dirty: 4 kB\n"})
+
+	steps := []struct {
+		name   string
+		at     time.Duration
+		rollup string
+		want   ProcessMemory
+	}{
+		{"first scan reads it", 0, "",
+			ProcessMemory{RSS: 12 << 10, PSS: 8 << 10, USS: 4 << 10, VSZ: 1 << 20, Rollup: true}},
+		{"later scans keep it", rollupInterval / 2, "Rss: 12 kB\nPss: 12 kB\n",
+			ProcessMemory{RSS: 12 << 10, PSS: 8 << 10, USS: 4 << 10, VSZ: 1 << 20, Rollup: true}},
+		{"read again after the interval", rollupInterval, "",
+			ProcessMemory{RSS: 12 << 10, PSS: 12 << 10, VSZ: 1 << 20, Rollup: true}},
+	}
+	for _, step := range steps {
+		if step.rollup != "" {
+			write(42, map[string]string{"smaps_rollup": step.rollup})
+		}
+		got, _ := s.sampleProcess(42, base.Add(step.at), 1, 0)
+		if got.Memory != step.want {
+			t.Errorf("%s: got %+v, want %+v", step.name, got.Memory, step.want)
+		}
+	}
+}
+
+func TestProcessUser(t *testing.T) {
+	status := "Name:\tcat\nUmask:\t0022\nState:\tR (running)\nTgid:\t42\nPid:\t42\nPPid:\t1\nTracerPid:\t0\nUid:\t%s\nGid:\t0\t0\t0\t0\n"
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{fmt.Sprintf(status, "0\t0\t0\t0"), "root"},
+		{fmt.Sprintf(status, "3999999\t0\t0\t0"), "3999999"},
+		{fmt.Sprintf(status, "x\t0\t0\t0"), ""},
+		{"Name:\tcat\nPid:\t42\n", ""},
+	}
+	for _, tt := range tests {
+		s, write := newTestSampler(t)
+		write(42, map[string]string{"status": tt.status})
+		if got := s.user(filepath.Join(s.proc, "42", "status")); got != tt.want {
+			t.Errorf("user(%q) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestParseRollup(t *testing.T) {
+	const captured = `56460a3bf000-7ffc11cef000 ---p 00000000 00:00 0                          [rollup]
+Rss:                1416 kB
+Pss:                 441 kB
+Pss_Dirty:           104 kB
+Pss_Anon:            104 kB
+Pss_File:            337 kB
+Pss_Shmem:             0 kB
+Shared_Clean:       1248 kB
+Shared_Dirty:          0 kB
+Private_Clean:        64 kB
+Private_Dirty:       104 kB
+Referenced:         1416 kB
+Anonymous:           104 kB
+LazyFree:              0 kB
+AnonHugePages:         0 kB
+ShmemPmdMapped:        0 kB
+FilePmdMapped:         0 kB
+Shared_Hugetlb:        0 kB
+Private_Hugetlb:       0 kB
+Swap:                 12 kB
+SwapPss:              12 kB
+Locked:                0 kB
+`
+	before := ProcessMemory{RSS: 1 << 20, VSZ: 1 << 30}
+	tests := []struct {
+		name string
+		data string
+		want ProcessMemory
+	}{
+		{"captured", captured, ProcessMemory{
+			RSS: 1416 << 10, PSS: 441 << 10, USS: 168 << 10, Shared: 1248 << 10, Swap: 12 << 10,
+			VSZ: 1 << 30, Rollup: true,
+		}},
+		{"kernel thread", "", ProcessMemory{RSS: 1 << 20, VSZ: 1 << 30, Rollup: true}},
+		{"no rss", "Pss: 4 kB\n", before},
+	}
+	for _, tt := range tests {
+		got := before
+		parseRollup([]byte(tt.data), &got)
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSignalProcessStartedAt(t *testing.T) {
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Process.Kill()
+	pid := int32(cmd.Process.Pid)
+
+	procs, err := NewProcessSampler().Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var createTime int64
+	for _, proc := range procs {
+		if proc.PID == pid {
+			createTime = proc.CreateTime
+		}
+	}
+	if createTime == 0 {
+		t.Fatalf("pid %d not sampled", pid)
+	}
+
+	// A different start time stands for a later process with the same PID.
+	if err := SignalProcessStartedAt(pid, syscall.SIGKILL, createTime+1000); !errors.Is(err, ErrProcessGone) {
+		t.Fatalf("other start time: err = %v, want ErrProcessGone", err)
+	}
+	if err := SignalProcessStartedAt(pid, syscall.SIGKILL, createTime); err != nil {
+		t.Fatalf("same start time: %v", err)
+	}
+	if err := cmd.Wait(); err == nil || cmd.ProcessState.Sys().(syscall.WaitStatus).Signal() != syscall.SIGKILL {
+		t.Fatalf("process not killed: %v", err)
+	}
+	if err := SignalProcessStartedAt(pid, syscall.SIGKILL, createTime); !errors.Is(err, ErrProcessGone) {
+		t.Errorf("exited process: err = %v, want ErrProcessGone", err)
+	}
+}
@@ -109,3 +109,33 @@ func RestartService(name string) error {
 
 	return nil
 }
+
+func EnableService(name string) error {
+	conn, err := dbus.NewSystemConnectionContext(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, _, err = conn.EnableUnitFilesContext(context.Background(), []string{name}, false, false)
+	if err != nil {
+		return err
+	}
+
+	return conn.ReloadContext(context.Background())
+}
+
+func DisableService(name string) error {
+	conn, err := dbus.NewSystemConnectionContext(context.Background())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.DisableUnitFilesContext(context.Background(), []string{name}, false)
+	if err != nil {
+		return err
+	}
+
+	return conn.ReloadContext(context.Background())
+}
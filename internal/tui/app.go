@@ -1,18 +1,29 @@
 package tui
 
 import (
+	"context"
+	"time"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/config"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
 	"github.com/guicybercode/systui/internal/tui/dashboard"
+	"github.com/guicybercode/systui/internal/tui/fleet"
 	"github.com/guicybercode/systui/internal/tui/logs"
 	"github.com/guicybercode/systui/internal/tui/network"
 	"github.com/guicybercode/systui/internal/tui/packages"
 	"github.com/guicybercode/systui/internal/tui/processes"
 	"github.com/guicybercode/systui/internal/tui/services"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
 type View int
 
+// contentTop is the first line below the title and the menu.
+const contentTop = 2
+
 const (
 	ViewDashboard View = iota
 	ViewProcesses
@@ -21,45 +32,124 @@ const (
 	ViewEditor
 	ViewPackages
 	ViewLogs
+	ViewFleet
 )
 
+var viewsByName = map[string]View{
+	"dashboard": ViewDashboard,
+	"processes": ViewProcesses,
+	"services":  ViewServices,
+	"network":   ViewNetwork,
+	"packages":  ViewPackages,
+	"logs":      ViewLogs,
+	"fleet":     ViewFleet,
+}
+
+var viewTitles = map[View]string{
+	ViewDashboard: "Dashboard",
+	ViewProcesses: "Processes",
+	ViewServices:  "Services",
+	ViewNetwork:   "Network",
+	ViewPackages:  "Packages",
+	ViewLogs:      "Logs",
+	ViewFleet:     "Fleet",
+}
+
 type App struct {
+	config      *config.Config
 	currentView View
+	home        datasource.Source
+	source      datasource.Source
+	snapshots   <-chan *system.Snapshot
+	unsubscribe func()
+	generation  int
+	stopRemote  context.CancelFunc
 	dashboard   dashboard.Model
 	processes   processes.Model
 	services    services.Model
 	network     network.Model
 	packages    packages.Model
 	logs        logs.Model
+	fleet       fleet.Model
 	width       int
 	height      int
 }
 
-var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("63")).
-			Padding(0, 1)
-
-	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("241")).
-			MarginTop(1)
-)
+// snapshotMsg tags a snapshot with the subscription it came from so that
+// snapshots still in flight after switching sources are dropped.
+type snapshotMsg struct {
+	generation int
+	snap       *system.Snapshot
+}
 
-func NewApp() *App {
-	return &App{
-		currentView: ViewDashboard,
-		dashboard:   dashboard.New(),
-		processes:   processes.New(),
-		services:    services.New(),
-		network:     network.New(),
-		packages:    packages.New(),
-		logs:        logs.New(),
+func NewApp(source datasource.Source, cfg *config.Config, endpoints []fleet.Endpoint) *App {
+	theme.Set(theme.FromConfig(cfg))
+	a := &App{
+		config:      cfg,
+		currentView: viewsByName[cfg.Views.Start],
+		home:        source,
+		fleet:       fleet.New(endpoints, time.Duration(cfg.Intervals.Fleet)),
 	}
+	a.setSource(source)
+	return a
 }
 
 func (a *App) Init() tea.Cmd {
+	return tea.Batch(a.initViews(), a.fleet.Init())
+}
+
+// setSource points every view except the fleet at source. Views are
+// rebuilt so no state from the previous host leaks into them.
+func (a *App) setSource(source datasource.Source) {
+	if a.unsubscribe != nil {
+		a.unsubscribe()
+	}
+	if a.stopRemote != nil {
+		a.stopRemote()
+		a.stopRemote = nil
+	}
+
+	a.logs.Close()
+
+	a.source = source
+	a.snapshots, a.unsubscribe = source.Subscribe()
+	a.generation++
+	a.dashboard = dashboard.New(source.History())
+	a.processes = processes.New(source, time.Duration(a.config.Processes.KillGrace))
+	a.services = services.New(source)
+	a.network = network.New(source)
+	a.packages = packages.New(source)
+	a.logs = logs.New(source, a.config.Logs.Sources, config.StatePath("log-presets.json"))
+	a.resizeViews()
+}
+
+// resizeViews tells every view how much space it has below the menu.
+func (a *App) resizeViews() {
+	if a.width == 0 {
+		return
+	}
+	size := tea.WindowSizeMsg{Width: a.width, Height: a.height - 5}
+
+	var m tea.Model
+	m, _ = a.dashboard.Update(size)
+	a.dashboard = m.(dashboard.Model)
+	m, _ = a.processes.Update(size)
+	a.processes = m.(processes.Model)
+	m, _ = a.services.Update(size)
+	a.services = m.(services.Model)
+	m, _ = a.network.Update(size)
+	a.network = m.(network.Model)
+	m, _ = a.packages.Update(size)
+	a.packages = m.(packages.Model)
+	m, _ = a.logs.Update(size)
+	a.logs = m.(logs.Model)
+	m, _ = a.fleet.Update(size)
+	a.fleet = m.(fleet.Model)
+}
+
+func (a *App) initViews() tea.Cmd {
 	return tea.Batch(
+		a.waitForSnapshot(),
 		a.dashboard.Init(),
 		a.processes.Init(),
 		a.services.Init(),
@@ -69,33 +159,120 @@ func (a *App) Init() tea.Cmd {
 	)
 }
 
+func (a *App) openHost(endpoint fleet.Endpoint) tea.Cmd {
+	remote, err := datasource.NewRemote(endpoint.RemoteOptions)
+	if err != nil {
+		return func() tea.Msg { return fleet.OpenFailedMsg{Endpoint: endpoint, Err: err} }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.setSource(remote)
+	a.stopRemote = cancel
+	remote.Start(ctx)
+	a.currentView = a.hostView()
+	return a.initViews()
+}
+
+// capturingInput reports whether the active view is reading text, such as a
+// filter, so that keys must reach it instead of switching views.
+func (a *App) capturingInput() bool {
+	switch a.currentView {
+	case ViewProcesses:
+		return a.processes.Capturing()
+	case ViewLogs:
+		return a.logs.Capturing()
+	}
+	return false
+}
+
+// hostView is the view shown after switching hosts: the dashboard when it
+// is enabled, otherwise the configured start view.
+func (a *App) hostView() View {
+	if a.config.ViewEnabled("dashboard") {
+		return ViewDashboard
+	}
+	return viewsByName[a.config.Views.Start]
+}
+
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if mouse, ok := msg.(tea.MouseMsg); ok {
+		// Views place their content relative to the top of the content area.
+		mouse.Y -= contentTop
+		msg = mouse
+	}
+
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		a.width = msg.Width
 		a.height = msg.Height
+		a.resizeViews()
 		return a, nil
 
+	case snapshotMsg:
+		if msg.generation != a.generation || msg.snap == nil {
+			return a, nil
+		}
+		return a, a.broadcastSnapshot(msg.snap)
+
+	// The results of a view's background work reach it even while another
+	// view is active.
+	case processes.Msg:
+		m, cmd := a.processes.Update(msg)
+		a.processes = m.(processes.Model)
+		return a, cmd
+
+	case services.Msg:
+		m, cmd := a.services.Update(msg)
+		a.services = m.(services.Model)
+		return a, cmd
+
+	case logs.Msg:
+		m, cmd := a.logs.Update(msg)
+		a.logs = m.(logs.Model)
+		return a, cmd
+
+	case fleet.OpenHostMsg:
+		return a, a.openHost(msg.Endpoint)
+
+	case fleet.HomeMsg:
+		if a.source == a.home {
+			return a, nil
+		}
+		a.setSource(a.home)
+		a.currentView = a.hostView()
+		return a, a.initViews()
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "1":
-			a.currentView = ViewDashboard
-		case "2":
-			a.currentView = ViewProcesses
-		case "3":
-			a.currentView = ViewServices
-		case "4":
-			a.currentView = ViewNetwork
-		case "5":
-			a.currentView = ViewPackages
-		case "6":
-			a.currentView = ViewLogs
-		case "q", "ctrl+c":
+		key := msg.String()
+		if key == "ctrl+c" {
 			return a, tea.Quit
 		}
+		if a.capturingInput() {
+			break
+		}
+		if a.config.Keys.Matches("quit", key) {
+			return a, tea.Quit
+		}
+		for _, name := range a.config.Views.Enabled {
+			if a.config.Keys.Matches(name, key) {
+				a.currentView = viewsByName[name]
+				return a, nil
+			}
+		}
 	}
 
-	var cmd tea.Cmd
+	var fleetCmd, cmd tea.Cmd
+
+	// The fleet polls in the background, so it sees its own messages even
+	// while another view is active. Input only goes to the active view.
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+	default:
+		if a.currentView != ViewFleet {
+			var m tea.Model
+			m, fleetCmd = a.fleet.Update(msg)
+			a.fleet = m.(fleet.Model)
+		}
+	}
 
 	switch a.currentView {
 	case ViewDashboard:
@@ -122,9 +299,49 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		var m tea.Model
 		m, cmd = a.logs.Update(msg)
 		a.logs = m.(logs.Model)
+	case ViewFleet:
+		var m tea.Model
+		m, cmd = a.fleet.Update(msg)
+		a.fleet = m.(fleet.Model)
+	}
+
+	return a, tea.Batch(fleetCmd, cmd)
+}
+
+func (a *App) waitForSnapshot() tea.Cmd {
+	snapshots, generation := a.snapshots, a.generation
+	return func() tea.Msg {
+		return snapshotMsg{generation: generation, snap: <-snapshots}
 	}
+}
+
+func (a *App) broadcastSnapshot(snap *system.Snapshot) tea.Cmd {
+	var m tea.Model
+	var cmds []tea.Cmd
+	var cmd tea.Cmd
+
+	m, cmd = a.dashboard.Update(snap)
+	a.dashboard = m.(dashboard.Model)
+	cmds = append(cmds, cmd)
 
-	return a, cmd
+	m, cmd = a.processes.Update(snap)
+	a.processes = m.(processes.Model)
+	cmds = append(cmds, cmd)
+
+	m, cmd = a.services.Update(snap)
+	a.services = m.(services.Model)
+	cmds = append(cmds, cmd)
+
+	m, cmd = a.network.Update(snap)
+	a.network = m.(network.Model)
+	cmds = append(cmds, cmd)
+
+	m, cmd = a.packages.Update(snap)
+	a.packages = m.(packages.Model)
+	cmds = append(cmds, cmd)
+
+	cmds = append(cmds, a.waitForSnapshot())
+	return tea.Batch(cmds...)
 }
 
 func (a *App) View() string {
@@ -147,9 +364,23 @@ func (a *App) View() string {
 		content = a.packages.View()
 	case ViewLogs:
 		content = a.logs.View()
+	case ViewFleet:
+		content = a.fleet.View()
 	}
 
-	header := titleStyle.Render("SysTUI - System Monitor")
+	titleStyle := theme.Current().Header
+	helpStyle := theme.Current().Muted.Copy().MarginTop(1)
+
+	help := "Press the keys in brackets to switch views"
+	if quit := a.config.Keys["quit"]; len(quit) > 0 {
+		help += ", " + quit[0] + " to quit"
+	}
+
+	title := "SysTUI - System Monitor"
+	if name := a.source.Name(); name != "local" {
+		title += " @ " + name
+	}
+	header := titleStyle.Render(title)
 	menu := a.renderMenu()
 
 	contentHeight := a.height - 5
@@ -162,22 +393,26 @@ func (a *App) View() string {
 		header,
 		menu,
 		renderedContent,
-		helpStyle.Render("Press 1-6 to switch views, q to quit"),
+		helpStyle.Render(help),
 	)
 }
 
 func (a *App) renderMenu() string {
-	menuItems := []string{"[1] Dashboard", "[2] Processes", "[3] Services", "[4] Network", "[5] Packages", "[6] Logs"}
 	menu := ""
-	for i, item := range menuItems {
+	for i, name := range a.config.Views.Enabled {
 		if i > 0 {
 			menu += " | "
 		}
+		view := viewsByName[name]
+		label := viewTitles[view]
+		if keys := a.config.Keys[name]; len(keys) > 0 {
+			label = "[" + keys[0] + "] " + label
+		}
 		style := lipgloss.NewStyle()
-		if int(a.currentView) == i {
-			style = style.Bold(true).Foreground(lipgloss.Color("63"))
+		if a.currentView == view {
+			style = theme.Current().Active
 		}
-		menu += style.Render(item)
+		menu += style.Render(label)
 	}
 	return menu
 }
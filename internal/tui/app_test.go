@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guicybercode/systui/internal/config"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/logparser"
+	"github.com/guicybercode/systui/internal/system"
+)
+
+// fakeSource serves one log entry once release is closed. The methods the
+// test does not reach are left to the nil embedded Source.
+type fakeSource struct {
+	datasource.Source
+	release chan struct{}
+}
+
+func (s *fakeSource) Name() string { return "local" }
+
+func (s *fakeSource) Subscribe() (<-chan *system.Snapshot, func()) {
+	return make(chan *system.Snapshot), func() {}
+}
+
+func (s *fakeSource) History() *system.History { return system.NewHistory(0) }
+
+func (s *fakeSource) Refresh(...system.Group) {}
+
+func (s *fakeSource) Logs(ctx context.Context, query datasource.LogQuery) ([]logparser.LogEntry, error) {
+	<-s.release
+	return []logparser.LogEntry{{Timestamp: "2026-10-17 12:00:00", Severity: "INFO", Message: "loaded entry"}}, nil
+}
+
+// TestViewMessagesWhileInactive switches views while the logs view is
+// loading and checks that the result still reaches it.
+func TestViewMessagesWhileInactive(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	cfg := config.Default()
+	cfg.Logs.Sources = []string{"/var/log/test.log"}
+	source := &fakeSource{release: make(chan struct{})}
+	a := NewApp(source, cfg, nil)
+	a.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("6")})
+	load := a.logs.Init()
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1")})
+	if a.currentView != ViewDashboard {
+		t.Fatalf("current view = %v, want the dashboard", a.currentView)
+	}
+
+	close(source.release)
+	a.Update(load())
+
+	a.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("6")})
+	view := a.View()
+	if !strings.Contains(view, "loaded entry") {
+		t.Errorf("logs view does not show the entry loaded while inactive:\n%s", view)
+	}
+	if strings.Contains(view, "Loading logs") {
+		t.Errorf("logs view still loading:\n%s", view)
+	}
+}
@@ -0,0 +1,118 @@
+package chart
+
+import (
+	"strings"
+)
+
+var sparkRunes = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders the last width values as a single row of block glyphs.
+// A max of zero scales against the largest value in the window.
+func Sparkline(values []float64, width int, max float64) string {
+	if width <= 0 {
+		return ""
+	}
+	values = tail(values, width)
+	max = scale(values, max)
+
+	var b strings.Builder
+	for i := len(values); i < width; i++ {
+		b.WriteRune(' ')
+	}
+	for _, v := range values {
+		idx := 0
+		if max > 0 {
+			idx = int(clamp(v/max, 0, 1) * float64(len(sparkRunes)-1))
+		}
+		b.WriteRune(sparkRunes[idx])
+	}
+	return b.String()
+}
+
+var brailleDots = [4][2]rune{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// Braille renders a line chart width cells wide and height rows tall. Each
+// cell holds a 2x4 dot matrix, so the chart plots the last 2*width values.
+func Braille(values []float64, width, height int, max float64) string {
+	if width <= 0 || height <= 0 {
+		return ""
+	}
+	cols := width * 2
+	rows := height * 4
+	values = tail(values, cols)
+	max = scale(values, max)
+
+	grid := make([][]rune, height)
+	for i := range grid {
+		grid[i] = make([]rune, width)
+	}
+
+	offset := cols - len(values)
+	prevY := -1
+	for i, v := range values {
+		y := 0
+		if max > 0 {
+			y = int(clamp(v/max, 0, 1) * float64(rows-1))
+		}
+		x := offset + i
+
+		lo, hi := y, y
+		if prevY >= 0 {
+			if prevY < lo {
+				lo = prevY
+			}
+			if prevY > hi {
+				hi = prevY
+			}
+		}
+		for dy := lo; dy <= hi; dy++ {
+			row := rows - 1 - dy
+			grid[row/4][x/2] |= brailleDots[row%4][x%2]
+		}
+		prevY = y
+	}
+
+	lines := make([]string, height)
+	for i, row := range grid {
+		var b strings.Builder
+		for _, dots := range row {
+			b.WriteRune(0x2800 + dots)
+		}
+		lines[i] = b.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tail(values []float64, n int) []float64 {
+	if len(values) > n {
+		return values[len(values)-n:]
+	}
+	return values
+}
+
+func scale(values []float64, max float64) float64 {
+	if max > 0 {
+		return max
+	}
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+	return max
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -2,16 +2,22 @@ package dashboard
 
 import (
 	"fmt"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/chart"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
-type tickMsg time.Time
+const (
+	chartWidth = 34
+	sparkWidth = 20
+	maxCores   = 16
+)
 
 type Model struct {
+	history *system.History
 	cpu     *system.CPUMetrics
 	mem     *system.MemoryMetrics
 	disk    *system.DiskMetrics
@@ -20,26 +26,29 @@ type Model struct {
 	err     error
 }
 
-func New() Model {
-	return Model{loading: true}
+func New(history *system.History) Model {
+	return Model{history: history, loading: true}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(tick(), fetchMetrics())
+	return nil
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tickMsg:
-		return m, tea.Batch(tick(), fetchMetrics())
-
-	case metricsMsg:
-		m.cpu = msg.cpu
-		m.mem = msg.mem
-		m.disk = msg.disk
-		m.network = msg.network
+	case *system.Snapshot:
+		if !msg.Has(system.GroupMetrics) {
+			return m, nil
+		}
+		m.cpu = msg.CPU
+		m.mem = msg.Memory
+		m.disk = msg.Disk
+		m.network = msg.Network
 		m.loading = false
-		m.err = msg.err
+		m.err = nil
+		if msg.Err(system.GroupMetrics) != nil {
+			m.err = fmt.Errorf("failed to fetch metrics")
+		}
 		return m, nil
 
 	case error:
@@ -63,78 +72,97 @@ func (m Model) View() string {
 	var sections []string
 
 	if m.cpu != nil {
-		sections = append(sections, renderCPU(m.cpu))
+		sections = append(sections, renderCPU(m.cpu, m.history))
 	}
 
 	if m.mem != nil {
-		sections = append(sections, renderMemory(m.mem))
+		sections = append(sections, renderMemory(m.mem, m.history))
 	}
 
 	if m.disk != nil {
-		sections = append(sections, renderDisk(m.disk))
+		sections = append(sections, renderDisk(m.disk, m.history))
 	}
 
 	if len(m.network) > 0 {
-		sections = append(sections, renderNetwork(m.network))
+		sections = append(sections, renderNetwork(m.network, m.history))
 	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
-func renderCPU(cpu *system.CPUMetrics) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+func renderCPU(cpu *system.CPUMetrics, history *system.History) string {
+	boxStyle := theme.Current().Box.Copy().
 		Padding(1, 2).
 		Margin(1).
 		Width(40)
 
-	title := lipgloss.NewStyle().Bold(true).Render("CPU")
+	title := theme.Current().Title.Render("CPU")
 	usage := fmt.Sprintf("Usage: %.2f%%", cpu.Usage)
+	breakdown := fmt.Sprintf("usr %.1f%% sys %.1f%% iow %.1f%% st %.1f%%",
+		cpu.Breakdown.User, cpu.Breakdown.System, cpu.Breakdown.IOWait, cpu.Breakdown.Steal)
 	cores := fmt.Sprintf("Cores: %d", cpu.Cores)
 	model := fmt.Sprintf("Model: %s", truncate(cpu.Model, 30))
+	loadAvg := "Load: n/a"
+	if len(cpu.LoadAvg) == 3 {
+		loadAvg = fmt.Sprintf("Load: %.2f %.2f %.2f", cpu.LoadAvg[0], cpu.LoadAvg[1], cpu.LoadAvg[2])
+	}
 
-	content := lipgloss.JoinVertical(lipgloss.Left,
+	lines := []string{
 		title,
 		"",
+		chart.Braille(history.Series(system.HistoryCPU), chartWidth, 3, 100),
 		usage,
+		breakdown,
+		loadAvg,
 		cores,
 		model,
-	)
+		"",
+	}
+	for i, pct := range cpu.PerCore[:min(maxCores, len(cpu.PerCore))] {
+		lines = append(lines, fmt.Sprintf("cpu%-3d %s %5.1f%%",
+			i, chart.Sparkline(history.Series(system.HistoryCore(i)), sparkWidth, 100), pct))
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, lines...)
 
 	return boxStyle.Render(content)
 }
 
-func renderMemory(mem *system.MemoryMetrics) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+func renderMemory(mem *system.MemoryMetrics, history *system.History) string {
+	boxStyle := theme.Current().Box.Copy().
 		Padding(1, 2).
 		Margin(1).
 		Width(40)
 
-	title := lipgloss.NewStyle().Bold(true).Render("Memory")
+	title := theme.Current().Title.Render("Memory")
 	total := fmt.Sprintf("Total: %s", formatBytes(mem.Total))
 	used := fmt.Sprintf("Used: %s (%.2f%%)", formatBytes(mem.Used), mem.UsedPercent)
 	available := fmt.Sprintf("Available: %s", formatBytes(mem.Available))
+	swap := fmt.Sprintf("Swap: %s / %s (%.2f%%)", formatBytes(mem.SwapUsed), formatBytes(mem.SwapTotal), mem.SwapPercent)
 
 	content := lipgloss.JoinVertical(lipgloss.Left,
 		title,
 		"",
+		chart.Braille(history.Series(system.HistoryMemory), chartWidth, 2, 100),
 		total,
 		used,
 		available,
+		"",
+		"RAM  "+chart.Sparkline(history.Series(system.HistoryMemory), sparkWidth, 100),
+		"Swap "+chart.Sparkline(history.Series(system.HistorySwap), sparkWidth, 100),
+		swap,
 	)
 
 	return boxStyle.Render(content)
 }
 
-func renderDisk(disk *system.DiskMetrics) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+func renderDisk(disk *system.DiskMetrics, history *system.History) string {
+	boxStyle := theme.Current().Box.Copy().
 		Padding(1, 2).
 		Margin(1).
 		Width(40)
 
-	title := lipgloss.NewStyle().Bold(true).Render("Disk")
+	title := theme.Current().Title.Render("Disk")
 	total := fmt.Sprintf("Total: %s", formatBytes(disk.Total))
 	used := fmt.Sprintf("Used: %s (%.2f%%)", formatBytes(disk.Used), disk.UsedPercent)
 	free := fmt.Sprintf("Free: %s", formatBytes(disk.Free))
@@ -145,69 +173,36 @@ func renderDisk(disk *system.DiskMetrics) string {
 		total,
 		used,
 		free,
+		"",
+		chart.Sparkline(history.Series(system.HistoryDisk), chartWidth, 100),
 	)
 
 	return boxStyle.Render(content)
 }
 
-func renderNetwork(stats []system.NetworkStats) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+func renderNetwork(stats []system.NetworkStats, history *system.History) string {
+	boxStyle := theme.Current().Box.Copy().
 		Padding(1, 2).
 		Margin(1).
 		Width(50)
 
-	title := lipgloss.NewStyle().Bold(true).Render("Network")
+	title := theme.Current().Title.Render("Network")
 	var lines []string
 	lines = append(lines, title, "")
 
 	for _, stat := range stats[:min(5, len(stats))] {
 		line := fmt.Sprintf("%s: ↑ %s ↓ %s",
 			stat.Interface,
-			formatBytes(stat.BytesSent),
-			formatBytes(stat.BytesRecv))
-		lines = append(lines, line)
+			formatRate(stat.Rates.BytesSent),
+			formatRate(stat.Rates.BytesRecv))
+		lines = append(lines, line,
+			"  ↑ "+chart.Sparkline(history.Series(system.HistoryNetSent(stat.Interface)), chartWidth, 0),
+			"  ↓ "+chart.Sparkline(history.Series(system.HistoryNetRecv(stat.Interface)), chartWidth, 0))
 	}
 
 	return boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
 }
 
-type metricsMsg struct {
-	cpu     *system.CPUMetrics
-	mem     *system.MemoryMetrics
-	disk    *system.DiskMetrics
-	network []system.NetworkStats
-	err     error
-}
-
-func fetchMetrics() tea.Cmd {
-	return func() tea.Msg {
-		cpu, err1 := system.GetCPUMetrics()
-		mem, err2 := system.GetMemoryMetrics()
-		disk, err3 := system.GetDiskMetrics()
-		network, err4 := system.GetNetworkStats()
-
-		var err error
-		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
-			err = fmt.Errorf("failed to fetch metrics")
-		}
-
-		return metricsMsg{
-			cpu:     cpu,
-			mem:     mem,
-			disk:    disk,
-			network: network,
-			err:     err,
-		}
-	}
-}
-
-func tick() tea.Cmd {
-	return tea.Tick(time.Second, func(t time.Time) tea.Msg {
-		return tickMsg(t)
-	})
-}
-
 func formatBytes(bytes uint64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -221,6 +216,10 @@ func formatBytes(bytes uint64) string {
 	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+func formatRate(rate float64) string {
+	return formatBytes(uint64(rate)) + "/s"
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
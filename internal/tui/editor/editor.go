@@ -6,6 +6,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
 type Model struct {
@@ -69,10 +70,7 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v", m.err)
 	}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+	headerStyle := theme.Current().Header
 
 	header := headerStyle.Render(fmt.Sprintf("Editor: %s (q: quit, arrow keys: navigate)", m.filePath))
 
@@ -90,10 +88,7 @@ func (m Model) View() string {
 			if m.cursorX+1 < len(line) {
 				afterCursor = line[m.cursorX+1:]
 			}
-			cursorStyle := lipgloss.NewStyle().
-				Background(lipgloss.Color("230")).
-				Foreground(lipgloss.Color("63"))
-			line = beforeCursor + cursorStyle.Render(atCursor) + afterCursor
+			line = beforeCursor + theme.Current().Selection.Render(atCursor) + afterCursor
 		}
 		lines = append(lines, line)
 	}
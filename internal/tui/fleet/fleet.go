@@ -0,0 +1,320 @@
+package fleet
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+type Endpoint struct {
+	Name string
+	datasource.RemoteOptions
+}
+
+// OpenHostMsg asks the app to drill down into the dashboard of a host.
+type OpenHostMsg struct {
+	Endpoint Endpoint
+}
+
+// OpenFailedMsg tells the fleet view that a host could not be opened.
+type OpenFailedMsg struct {
+	Endpoint Endpoint
+	Err      error
+}
+
+// HomeMsg asks the app to switch the views back to the local source.
+type HomeMsg struct{}
+
+type sortColumn int
+
+const (
+	sortName sortColumn = iota
+	sortReachable
+	sortCPU
+	sortMemory
+	sortDisk
+	sortFailed
+)
+
+var sortNames = []string{"name", "reachable", "cpu", "memory", "disk", "failed"}
+
+type hostStatus struct {
+	endpoint  Endpoint
+	polled    bool
+	reachable bool
+	err       error
+	cpu       float64
+	memory    float64
+	disk      float64
+	failed    int
+	latency   time.Duration
+	updated   time.Time
+}
+
+type Model struct {
+	interval time.Duration
+	hosts    []hostStatus
+	remotes  []*datasource.Remote
+	order    []int
+	table    table.Model
+	sortBy   sortColumn
+	reverse  bool
+	// openErr is why the last host could not be opened.
+	openErr string
+}
+
+func New(endpoints []Endpoint, interval time.Duration) Model {
+	m := Model{
+		interval: interval,
+		table: table.New(
+			table.Column{Title: "Host", Width: 20},
+			table.Column{Title: "Status", Width: 11},
+			table.Column{Title: "CPU%", Width: 7, Right: true},
+			table.Column{Title: "Mem%", Width: 7, Right: true},
+			table.Column{Title: "Disk%", Width: 7, Right: true},
+			table.Column{Title: "Failed", Width: 7, Right: true},
+			table.Column{Title: "Latency", Width: 9, Right: true},
+			table.Column{Title: "Error", Width: 0},
+		),
+	}
+	for _, endpoint := range endpoints {
+		remote, err := datasource.NewRemote(endpoint.RemoteOptions)
+		m.hosts = append(m.hosts, hostStatus{endpoint: endpoint, polled: err != nil, err: err})
+		m.remotes = append(m.remotes, remote)
+	}
+	m.sort()
+	return m
+}
+
+func (m Model) Init() tea.Cmd {
+	return m.pollAll()
+}
+
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.table.SetSize(msg.Width, msg.Height-2)
+		return m, nil
+
+	case tea.MouseMsg:
+		m.table, _ = m.table.Update(msg)
+		return m, nil
+
+	case tea.KeyMsg:
+		m.openErr = ""
+		switch msg.String() {
+		case "s":
+			m.sortBy = (m.sortBy + 1) % sortColumn(len(sortNames))
+			m.sort()
+		case "S":
+			m.reverse = !m.reverse
+			m.sort()
+		case "r":
+			return m, m.pollHosts()
+		case "enter":
+			if m.table.Cursor() < len(m.order) && m.remotes[m.order[m.table.Cursor()]] != nil {
+				host := m.hosts[m.order[m.table.Cursor()]]
+				return m, func() tea.Msg { return OpenHostMsg{Endpoint: host.endpoint} }
+			}
+		case "l":
+			return m, func() tea.Msg { return HomeMsg{} }
+		default:
+			m.table, _ = m.table.Update(msg)
+		}
+
+	case OpenFailedMsg:
+		m.openErr = fmt.Sprintf("opening %s: %v", msg.Endpoint.Name, msg.Err)
+		return m, nil
+
+	case tickMsg:
+		return m, m.pollAll()
+
+	case hostMsg:
+		if msg.index < len(m.hosts) {
+			m.hosts[msg.index] = msg.status
+			m.sort()
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
+
+func (m *Model) sort() {
+	selectedHost := -1
+	if m.table.Cursor() < len(m.order) {
+		selectedHost = m.order[m.table.Cursor()]
+	}
+
+	m.order = make([]int, len(m.hosts))
+	for i := range m.order {
+		m.order[i] = i
+	}
+
+	less := func(a, b hostStatus) bool {
+		switch m.sortBy {
+		case sortReachable:
+			if a.reachable != b.reachable {
+				return !a.reachable
+			}
+		case sortCPU:
+			return a.cpu > b.cpu
+		case sortMemory:
+			return a.memory > b.memory
+		case sortDisk:
+			return a.disk > b.disk
+		case sortFailed:
+			return a.failed > b.failed
+		}
+		return a.endpoint.Name < b.endpoint.Name
+	}
+
+	sort.SliceStable(m.order, func(i, j int) bool {
+		a, b := m.hosts[m.order[i]], m.hosts[m.order[j]]
+		if m.reverse {
+			return less(b, a)
+		}
+		return less(a, b)
+	})
+
+	hosts, order := m.hosts, m.order
+	m.table.SetStyleFunc(func(i int) lipgloss.Style {
+		if host := hosts[order[i]]; host.polled && !host.reachable {
+			return theme.Current().Critical
+		}
+		return lipgloss.NewStyle()
+	})
+	m.table.SetRows(m.rows())
+	for i, idx := range m.order {
+		if idx == selectedHost {
+			m.table.SetCursor(i)
+		}
+	}
+}
+
+func (m Model) View() string {
+	headerStyle := theme.Current().Header
+
+	if len(m.hosts) == 0 {
+		return headerStyle.Render("Fleet") + "\n\nNo fleet hosts configured (add [[fleet]] entries to the config file)"
+	}
+
+	order := ""
+	if m.reverse {
+		order = " (reversed)"
+	}
+	header := headerStyle.Render(fmt.Sprintf(
+		"Fleet - sorted by %s%s (j/k/pgup/pgdn: navigate, s/S: sort, enter: open, l: local, r: refresh)",
+		sortNames[m.sortBy], order))
+
+	status := ""
+	if m.openErr != "" {
+		status = theme.Current().Critical.Render(m.openErr)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, status, m.table.View())
+}
+
+func (m Model) rows() []table.Row {
+	rows := make([]table.Row, len(m.order))
+	for i, idx := range m.order {
+		host := m.hosts[idx]
+
+		status := "pending"
+		if host.polled {
+			status = "up"
+			if !host.reachable {
+				status = "unreachable"
+			}
+		}
+
+		errText := ""
+		if host.err != nil {
+			errText = host.err.Error()
+		}
+
+		if host.reachable {
+			rows[i] = table.Row{
+				host.endpoint.Name, status,
+				fmt.Sprintf("%.1f", host.cpu),
+				fmt.Sprintf("%.1f", host.memory),
+				fmt.Sprintf("%.1f", host.disk),
+				fmt.Sprint(host.failed),
+				host.latency.Round(time.Millisecond).String(),
+				errText,
+			}
+		} else {
+			rows[i] = table.Row{host.endpoint.Name, status, "-", "-", "-", "-", "-", errText}
+		}
+	}
+	return rows
+}
+
+type tickMsg time.Time
+
+type hostMsg struct {
+	index  int
+	status hostStatus
+}
+
+func (m Model) pollAll() tea.Cmd {
+	if len(m.hosts) == 0 {
+		return nil
+	}
+	return tea.Batch(m.pollHosts(), tea.Tick(m.interval, func(t time.Time) tea.Msg {
+		return tickMsg(t)
+	}))
+}
+
+func (m Model) pollHosts() tea.Cmd {
+	cmds := make([]tea.Cmd, 0, len(m.hosts))
+	for i := range m.hosts {
+		if m.remotes[i] == nil {
+			continue
+		}
+		cmds = append(cmds, pollHost(i, m.hosts[i].endpoint, m.remotes[i]))
+	}
+	return tea.Batch(cmds...)
+}
+
+func pollHost(index int, endpoint Endpoint, remote *datasource.Remote) tea.Cmd {
+	return func() tea.Msg {
+		start := time.Now()
+		snap, err := remote.Poll()
+		status := hostStatus{
+			endpoint: endpoint,
+			polled:   true,
+			latency:  time.Since(start),
+			updated:  time.Now(),
+			err:      err,
+		}
+		if err != nil {
+			return hostMsg{index: index, status: status}
+		}
+
+		status.reachable = true
+		if snap.CPU != nil {
+			status.cpu = snap.CPU.Usage
+		}
+		if snap.Memory != nil {
+			status.memory = snap.Memory.UsedPercent
+		}
+		if snap.Disk != nil {
+			status.disk = snap.Disk.UsedPercent
+		}
+		for _, svc := range snap.Services {
+			if svc.ActiveState == "failed" {
+				status.failed++
+			}
+		}
+		status.err = snap.Err(system.GroupServices)
+		return hostMsg{index: index, status: status}
+	}
+}
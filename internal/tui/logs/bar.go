@@ -0,0 +1,377 @@
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+type barField int
+
+const (
+	fieldPattern barField = iota
+	fieldSince
+	fieldUntil
+	fieldSeverity
+	fieldSource
+	fieldPreset
+	numFields
+)
+
+var fieldNames = [numFields]string{"Pattern", "Since", "Until", "Severity", "Source", "Preset"}
+
+// filterBar edits a copy of the filter, which replaces the one in effect
+// only when it is applied without errors.
+type filterBar struct {
+	filter filter
+	source int
+	// preset is the name the filter is saved under, and selected the index
+	// of the preset last loaded, or -1.
+	preset   string
+	selected int
+	focus    barField
+	severity int
+	errs     [numFields]error
+	// patternErr is the error of checking the pattern against the source,
+	// which runs in the background as a remote source checks it on the
+	// server.
+	patternErr error
+	message    string
+	failed     bool
+}
+
+// patternMsg is the result of checking a pattern for the source path.
+type patternMsg struct {
+	path    string
+	pattern string
+	err     error
+}
+
+func (patternMsg) isLogsMsg() {}
+
+func (m Model) openBar() *filterBar {
+	b := &filterBar{filter: m.filter, source: m.current, selected: -1}
+	b.filter.Severity = append([]string(nil), m.filter.Severity...)
+	if m.presetsErr != nil {
+		b.message, b.failed = m.presetsErr.Error(), true
+	}
+	b.validate()
+	return b
+}
+
+// validate checks each field as it is edited, so that errors show before
+// the filter is applied. The pattern is checked by checkPattern.
+func (b *filterBar) validate() {
+	b.errs = [numFields]error{}
+	b.errs[fieldPattern] = b.patternErr
+	now := time.Now()
+	since, err := parseWhen(b.filter.Since, now)
+	b.errs[fieldSince] = err
+	until, err := parseWhen(b.filter.Until, now)
+	b.errs[fieldUntil] = err
+	if b.errs[fieldSince] == nil && b.errs[fieldUntil] == nil &&
+		!since.IsZero() && !until.IsZero() && until.Before(since) {
+		b.errs[fieldUntil] = fmt.Errorf("before since")
+	}
+}
+
+func (b *filterBar) valid() bool {
+	for _, err := range b.errs {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// text returns field f if it is edited as text, or nil.
+func (b *filterBar) text(f barField) *string {
+	switch f {
+	case fieldPattern:
+		return &b.filter.Pattern
+	case fieldSince:
+		return &b.filter.Since
+	case fieldUntil:
+		return &b.filter.Until
+	case fieldPreset:
+		return &b.preset
+	}
+	return nil
+}
+
+// checkPattern checks the pattern of the bar with the regex engine that
+// will run the query: the journal's, the local log parser's or the
+// server's.
+func (m Model) checkPattern() tea.Cmd {
+	b := m.bar
+	b.patternErr = nil
+	b.validate()
+	if b.filter.Pattern == "" {
+		return nil
+	}
+	source, path, pattern := m.source, m.sources[b.source], b.filter.Pattern
+	return func() tea.Msg {
+		return patternMsg{path: path, pattern: pattern, err: source.CheckPattern(path, pattern)}
+	}
+}
+
+// updateBar handles a key while the filter bar is open, checking the
+// pattern again when it or the source changed.
+func (m Model) updateBar(msg tea.KeyMsg) (Model, tea.Cmd) {
+	b := m.bar
+	pattern, source := b.filter.Pattern, b.source
+	m, cmd := m.editBar(msg)
+	if m.bar != nil && (b.filter.Pattern != pattern || b.source != source) {
+		cmd = tea.Batch(cmd, m.checkPattern())
+	}
+	return m, cmd
+}
+
+// editBar handles a key while the filter bar is open: tab and shift+tab
+// move between fields, enter applies the filter, esc discards the edit,
+// ctrl+s saves it as a preset and ctrl+d deletes the preset.
+func (m Model) editBar(msg tea.KeyMsg) (Model, tea.Cmd) {
+	b := m.bar
+	switch msg.String() {
+	case "esc":
+		m.bar = nil
+		return m, nil
+	case "enter":
+		if !b.valid() {
+			b.message, b.failed = "fix the errors above to apply the filter", true
+			return m, nil
+		}
+		m.bar = nil
+		m.setFilter(b.filter)
+		if b.source != m.current {
+			m.current = b.source
+			m.logPath = m.sources[m.current]
+			m.table.SetCursor(0)
+		}
+		return m, m.reload()
+	case "tab", "down":
+		b.focus = (b.focus + 1) % numFields
+		return m, nil
+	case "shift+tab", "up":
+		b.focus = (b.focus + numFields - 1) % numFields
+		return m, nil
+	case "ctrl+s":
+		m.savePreset()
+		return m, nil
+	case "ctrl+d":
+		m.deletePreset()
+		return m, nil
+	}
+
+	b.message, b.failed = "", false
+	switch b.focus {
+	case fieldSeverity:
+		switch msg.String() {
+		case "left", "h":
+			b.severity = (b.severity + len(severities) - 1) % len(severities)
+		case "right", "l":
+			b.severity = (b.severity + 1) % len(severities)
+		case " ", "x":
+			b.filter.toggleSeverity(severities[b.severity])
+		case "e", "w", "i", "d":
+			for i, s := range severities {
+				if strings.HasPrefix(strings.ToLower(s), msg.String()) {
+					b.severity = i
+					b.filter.toggleSeverity(s)
+				}
+			}
+		}
+	case fieldSource:
+		switch msg.String() {
+		case "left", "h":
+			b.source = (b.source + len(m.sources) - 1) % len(m.sources)
+		case "right", "l":
+			b.source = (b.source + 1) % len(m.sources)
+		}
+	case fieldPreset:
+		switch msg.String() {
+		case "left":
+			m.loadPreset(-1)
+			return m, nil
+		case "right":
+			m.loadPreset(1)
+			return m, nil
+		}
+	}
+
+	if text := b.text(b.focus); text != nil {
+		switch msg.Type {
+		case tea.KeyBackspace:
+			if r := []rune(*text); len(r) > 0 {
+				*text = string(r[:len(r)-1])
+			}
+		case tea.KeyCtrlU:
+			*text = ""
+		case tea.KeyRunes, tea.KeySpace:
+			*text += string(msg.Runes)
+		}
+	}
+	b.validate()
+	return m, nil
+}
+
+// loadPreset fills the bar with the preset step places from the one
+// loaded last.
+func (m *Model) loadPreset(step int) {
+	b := m.bar
+	if len(m.presets) == 0 {
+		b.message, b.failed = "no saved presets, ctrl+s saves one", false
+		return
+	}
+	if b.selected < 0 && step < 0 {
+		b.selected = 0
+	}
+	b.selected = (b.selected + step + len(m.presets)) % len(m.presets)
+	p := m.presets[b.selected]
+
+	b.preset = p.Name
+	b.filter = p.filter
+	b.filter.Severity = append([]string(nil), p.Severity...)
+	b.message, b.failed = "", false
+	if p.Source != "" {
+		found := false
+		for i, source := range m.sources {
+			if source == p.Source {
+				b.source, found = i, true
+			}
+		}
+		if !found {
+			b.message, b.failed = fmt.Sprintf("source %s of preset %s is not configured", p.Source, p.Name), true
+		}
+	}
+	b.validate()
+}
+
+// savePreset saves the filter and source of the bar under the preset
+// name, replacing a preset of the same name.
+func (m *Model) savePreset() {
+	b := m.bar
+	name := strings.TrimSpace(b.preset)
+	if name == "" {
+		b.focus = fieldPreset
+		b.message, b.failed = "enter a name for the preset", true
+		return
+	}
+	if !b.valid() {
+		b.message, b.failed = "fix the errors above to save the filter", true
+		return
+	}
+
+	p := preset{Name: name, Source: m.sources[b.source], filter: b.filter}
+	presets := append([]preset(nil), m.presets...)
+	b.selected = len(presets)
+	for i := range presets {
+		if presets[i].Name == name {
+			b.selected = i
+		}
+	}
+	if b.selected == len(presets) {
+		presets = append(presets, p)
+	} else {
+		presets[b.selected] = p
+	}
+
+	if err := savePresets(m.presetsPath, presets); err != nil {
+		b.message, b.failed = fmt.Sprintf("saving presets: %v", err), true
+		return
+	}
+	m.presets = presets
+	m.presetsErr = nil
+	b.message, b.failed = fmt.Sprintf("saved preset %s", name), false
+}
+
+func (m *Model) deletePreset() {
+	b := m.bar
+	name := strings.TrimSpace(b.preset)
+	var presets []preset
+	for _, p := range m.presets {
+		if p.Name != name {
+			presets = append(presets, p)
+		}
+	}
+	if len(presets) == len(m.presets) {
+		b.message, b.failed = fmt.Sprintf("no preset named %q", name), true
+		return
+	}
+	if err := savePresets(m.presetsPath, presets); err != nil {
+		b.message, b.failed = fmt.Sprintf("saving presets: %v", err), true
+		return
+	}
+	m.presets = presets
+	b.selected = -1
+	b.message, b.failed = fmt.Sprintf("deleted preset %s", name), false
+}
+
+func (b *filterBar) view(sources []string, presets []preset) string {
+	t := theme.Current()
+	lines := []string{
+		t.Title.Render("Filter") + t.Muted.Render(" (tab: next field, enter: apply, esc: cancel, ctrl+s: save preset, ctrl+d: delete preset)"),
+		"",
+	}
+	for f := barField(0); f < numFields; f++ {
+		label := fmt.Sprintf("  %-9s ", fieldNames[f]+":")
+		if f == b.focus {
+			label = t.Active.Render(fmt.Sprintf("> %-9s ", fieldNames[f]+":"))
+		}
+
+		value, hint := "", ""
+		switch f {
+		case fieldSeverity:
+			var boxes []string
+			for i, s := range severities {
+				box := "[ ] " + s
+				if b.filter.hasSeverity(s) {
+					box = "[x] " + s
+				}
+				if f == b.focus && i == b.severity {
+					box = t.Selection.Render(box)
+				}
+				boxes = append(boxes, box)
+			}
+			value = strings.Join(boxes, "  ")
+			hint = "left/right, space: toggle, e/w/i/d; none selects all"
+		case fieldSource:
+			value = "< " + sources[b.source] + " >"
+			hint = "left/right: change"
+		case fieldPreset:
+			hint = fmt.Sprintf("left/right: load (%d saved), type a name to save", len(presets))
+		case fieldPattern:
+			hint = "regular expression matched against the message and source"
+		default:
+			hint = "YYYY-MM-DD [HH:MM[:SS]], now, or relative: -30m, -1h, -2d, -1w"
+		}
+		if text := b.text(f); text != nil {
+			value = *text
+			if f == b.focus {
+				value += t.Selection.Render(" ")
+			}
+		}
+
+		line := label + value
+		switch {
+		case b.errs[f] != nil:
+			line += "  " + t.Critical.Render(b.errs[f].Error())
+		case f == b.focus:
+			line += "  " + t.Muted.Render(hint)
+		}
+		lines = append(lines, line)
+	}
+
+	if b.message != "" {
+		style := t.OK
+		if b.failed {
+			style = t.Critical
+		}
+		lines = append(lines, "", style.Render(b.message))
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
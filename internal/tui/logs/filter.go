@@ -0,0 +1,198 @@
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/logparser"
+)
+
+// severities are the severities the filter can select, most severe first.
+var severities = []string{"ERROR", "WARN", "INFO", "DEBUG"}
+
+// filter selects the entries shown. Since and Until are absolute times or
+// relative ones such as -1h, which are taken from the time of each read.
+// No severity selects all of them.
+type filter struct {
+	Pattern  string   `json:"pattern,omitempty"`
+	Since    string   `json:"since,omitempty"`
+	Until    string   `json:"until,omitempty"`
+	Severity []string `json:"severity,omitempty"`
+}
+
+// preset is a saved filter. An empty Source keeps the current source.
+type preset struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	filter
+}
+
+func (f filter) empty() bool {
+	return f.Pattern == "" && f.Since == "" && f.Until == "" && len(f.Severity) == 0
+}
+
+func (f filter) hasSeverity(severity string) bool {
+	for _, s := range f.Severity {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleSeverity selects or deselects severity, keeping the order of
+// severities.
+func (f *filter) toggleSeverity(severity string) {
+	selected := !f.hasSeverity(severity)
+	var list []string
+	for _, s := range severities {
+		if s == severity && selected || s != severity && f.hasSeverity(s) {
+			list = append(list, s)
+		}
+	}
+	f.Severity = list
+}
+
+// highlight compiles the pattern for marking matches, or returns nil when
+// there is none or Go's regexp cannot compile it, as for some patterns the
+// Rust parser accepts.
+func (f filter) highlight() *regexp.Regexp {
+	if f.Pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(f.Pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// query builds the query of path with the times resolved at now. The
+// filter must have been validated.
+func (f filter) query(path string, now time.Time) datasource.LogQuery {
+	q := datasource.LogQuery{
+		Path:     path,
+		Pattern:  f.Pattern,
+		Severity: strings.Join(f.Severity, ","),
+	}
+	if t, err := parseWhen(f.Since, now); err == nil && !t.IsZero() {
+		q.Start = t.Format(logparser.TimeFormat)
+	}
+	if t, err := parseWhen(f.Until, now); err == nil && !t.IsZero() {
+		q.End = t.Format(logparser.TimeFormat)
+	}
+	// Log file parsers only apply a range with both ends, so an open end
+	// is closed far enough out to hold every entry, including those
+	// written while following.
+	if path != logparser.JournalSource && (q.Start != "") != (q.End != "") {
+		if q.Start == "" {
+			q.Start = firstTime
+		} else {
+			q.End = lastTime
+		}
+	}
+	return q
+}
+
+// firstTime and lastTime close the open end of a time range.
+const (
+	firstTime = "0000-01-01 00:00:00"
+	lastTime  = "9999-12-31 23:59:59"
+)
+
+// String describes the filter on one line.
+func (f filter) String() string {
+	var parts []string
+	if f.Pattern != "" {
+		parts = append(parts, "/"+f.Pattern+"/")
+	}
+	if f.Since != "" {
+		parts = append(parts, "since "+f.Since)
+	}
+	if f.Until != "" {
+		parts = append(parts, "until "+f.Until)
+	}
+	if len(f.Severity) > 0 {
+		parts = append(parts, strings.Join(f.Severity, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+var relativeUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+var absoluteFormats = []string{logparser.TimeFormat, "2006-01-02 15:04", "2006-01-02"}
+
+// parseWhen parses a time of the filter: "now", a time before now such as
+// -30m, -1h, -2d or -1w, or a local time in one of absoluteFormats. An
+// empty string gives the zero time.
+func parseWhen(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return time.Time{}, nil
+	case s == "now":
+		return now, nil
+	case strings.HasPrefix(s, "-"):
+		if len(s) >= 3 {
+			unit, ok := relativeUnits[s[len(s)-1]]
+			n, err := strconv.Atoi(s[1 : len(s)-1])
+			if ok && err == nil && n >= 0 {
+				return now.Add(-time.Duration(n) * unit), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid relative time %q, expected a number and s, m, h, d or w such as -1h", s)
+	}
+	for _, format := range absoluteFormats {
+		if t, err := time.ParseInLocation(format, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD [HH:MM[:SS]] or a relative time such as -1h", s)
+}
+
+// loadPresets reads the presets saved at path. A missing file holds none.
+func loadPresets(path string) ([]preset, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var presets []preset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return presets, nil
+}
+
+func savePresets(path string, presets []preset) error {
+	if path == "" {
+		return errors.New("no home directory to save presets in")
+	}
+	data, err := json.MarshalIndent(presets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
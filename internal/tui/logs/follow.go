@@ -0,0 +1,222 @@
+package logs
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/logparser"
+)
+
+// maxFollowEntries bounds the entries kept while following; the oldest are
+// dropped first.
+const maxFollowEntries = 20000
+
+// follow is the state of following the current log. Entries matching query
+// are read from position each time the watcher signals a change; while
+// paused, changes only mark the view as behind until it is resumed.
+type follow struct {
+	query    datasource.LogQuery
+	position logparser.Position
+	changes  <-chan struct{}
+	stop     func()
+	paused   bool
+	// reading is set while a read is in flight, and behind when the log
+	// changed during it or during a pause.
+	reading bool
+	behind  bool
+	err     error
+}
+
+type followStartMsg struct {
+	query    datasource.LogQuery
+	entries  []logparser.LogEntry
+	position logparser.Position
+	changes  <-chan struct{}
+	stop     func()
+	err      error
+}
+
+type logChangedMsg struct {
+	path string
+}
+
+type tailMsg struct {
+	follow *follow
+	tail   *logparser.Tail
+	err    error
+}
+
+func (followStartMsg) isLogsMsg() {}
+func (logChangedMsg) isLogsMsg()  {}
+func (tailMsg) isLogsMsg()        {}
+
+// startFollow reloads the log and starts watching it. The position is taken
+// before the reload so that nothing written in between is missed, and the
+// lines written in between are then read from it, leaving out those the
+// reload already holds. For the journal the cursor of the last entry loaded
+// is exact.
+func startFollow(ctx context.Context, source datasource.Source, query datasource.LogQuery) tea.Cmd {
+	return func() tea.Msg {
+		msg := followStartMsg{query: query}
+		tail, err := source.TailLogs(query, logparser.Position{})
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		msg.position = tail.Position
+
+		msg.entries, err = source.Logs(ctx, query)
+		if ctx.Err() != nil {
+			// Another read replaced this one.
+			return nil
+		}
+		if err != nil {
+			msg.err = err
+			return msg
+		}
+		if n := len(msg.entries); n > 0 && msg.entries[n-1].Cursor != "" {
+			msg.position.Cursor = msg.entries[n-1].Cursor
+		} else if n > 0 {
+			if msg.entries, msg.position, err = catchUp(source, query, msg.entries, msg.position); err != nil {
+				msg.err = err
+				return msg
+			}
+		}
+
+		msg.changes, msg.stop, msg.err = source.WatchLogs(query.Path)
+		return msg
+	}
+}
+
+// catchUp reads the file from pos, taken before entries were loaded, and
+// adds what was written after them. Lines up to the offset of the last
+// entry loaded are left out unless the file was replaced in between.
+func catchUp(source datasource.Source, query datasource.LogQuery, entries []logparser.LogEntry, pos logparser.Position) ([]logparser.LogEntry, logparser.Position, error) {
+	tail, err := source.TailLogs(query, pos)
+	if err != nil {
+		return nil, pos, err
+	}
+	last := entries[len(entries)-1].Offset
+	for _, e := range tail.Entries {
+		if tail.Position.Inode != pos.Inode || e.Offset > last {
+			entries = append(entries, e)
+		}
+	}
+	return entries, tail.Position, nil
+}
+
+func waitForChange(path string, changes <-chan struct{}) tea.Cmd {
+	return func() tea.Msg {
+		if _, ok := <-changes; !ok {
+			return nil
+		}
+		return logChangedMsg{path: path}
+	}
+}
+
+func readTail(source datasource.Source, f *follow) tea.Cmd {
+	query, pos := f.query, f.position
+	return func() tea.Msg {
+		tail, err := source.TailLogs(query, pos)
+		return tailMsg{follow: f, tail: tail, err: err}
+	}
+}
+
+func (m *Model) stopFollow() {
+	if m.follow != nil {
+		m.follow.stop()
+		m.follow = nil
+	}
+}
+
+func (m *Model) startTail() tea.Cmd {
+	m.follow.reading = true
+	m.follow.behind = false
+	return readTail(m.source, m.follow)
+}
+
+func (m Model) updateFollow(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case followStartMsg:
+		path := msg.query.Path
+		if path != m.logPath || msg.err != nil {
+			if msg.stop != nil {
+				msg.stop()
+			}
+			if path == m.logPath {
+				m.err = msg.err
+				m.loading = false
+			}
+			return m, nil
+		}
+		m.stopFollow()
+		m.follow = &follow{query: msg.query, position: msg.position, changes: msg.changes, stop: msg.stop}
+		m.setEntries(msg.entries)
+		m.table.SetCursor(len(m.entries) - 1)
+		m.loading = false
+		m.atStart = false
+		m.err = nil
+		return m, waitForChange(path, msg.changes)
+
+	case logChangedMsg:
+		if m.follow == nil || msg.path != m.logPath {
+			return m, nil
+		}
+		wait := waitForChange(msg.path, m.follow.changes)
+		if m.follow.paused || m.follow.reading {
+			m.follow.behind = true
+			return m, wait
+		}
+		return m, tea.Batch(wait, m.startTail())
+
+	case tailMsg:
+		if msg.follow != m.follow {
+			return m, nil
+		}
+		m.follow.reading = false
+		m.follow.err = msg.err
+		if msg.err == nil {
+			m.follow.position = msg.tail.Position
+			m.appendEntries(msg.tail.Entries)
+		}
+		if m.follow.behind && !m.follow.paused {
+			return m, m.startTail()
+		}
+	}
+	return m, nil
+}
+
+// togglePause pauses or resumes following, catching up on resume.
+func (m *Model) togglePause() tea.Cmd {
+	m.follow.paused = !m.follow.paused
+	if !m.follow.paused && m.follow.behind && !m.follow.reading {
+		return m.startTail()
+	}
+	return nil
+}
+
+// appendEntries adds entries read while following. The view keeps to the
+// newest entry unless the cursor was moved off it.
+func (m *Model) appendEntries(entries []logparser.LogEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	atEnd := m.table.Cursor() >= m.table.Len()-1
+	cursor := m.table.Cursor()
+
+	all := append(m.entries, entries...)
+	if drop := len(all) - maxFollowEntries; drop > 0 {
+		all = append([]logparser.LogEntry(nil), all[drop:]...)
+		cursor -= drop
+	}
+	m.setEntries(all)
+
+	if atEnd && !m.follow.paused {
+		cursor = len(all) - 1
+	}
+	if cursor < 0 {
+		cursor = 0
+	}
+	m.table.SetCursor(cursor)
+}
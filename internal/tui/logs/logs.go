@@ -1,55 +1,228 @@
 package logs
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
 	"github.com/guicybercode/systui/internal/logparser"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
 type Model struct {
-	entries  []logparser.LogEntry
-	selected int
-	logPath  string
-	pattern  string
-	loading  bool
-	err      error
+	source  datasource.Source
+	entries []logparser.LogEntry
+	table   table.Model
+	sources []string
+	current int
+	logPath string
+	pattern string
+	loading bool
+	err     error
+	follow  *follow
+	// load cancels the read in progress when another one starts, so that
+	// switching away from a large file does not wait for it.
+	load *load
+	// older is set while older entries are read, and atStart once the
+	// start of the file was reached.
+	older   bool
+	atStart bool
+	// filter is applied to every read; bar edits it while open. Presets
+	// are saved in the file at presetsPath.
+	filter      filter
+	bar         *filterBar
+	presets     []preset
+	presetsPath string
+	presetsErr  error
+	// unhighlighted is set when Go's regexp, which marks the matches,
+	// cannot compile a pattern the parser accepted.
+	unhighlighted bool
+}
+
+type load struct {
+	cancel context.CancelFunc
 }
 
-func New() Model {
+// messageColumn is the column whose matches of the filter pattern are
+// highlighted.
+const messageColumn = 2
+
+func New(source datasource.Source, sources []string, presetsPath string) Model {
+	presets, err := loadPresets(presetsPath)
 	return Model{
-		logPath: "/var/log/syslog",
-		loading: true,
+		source:  source,
+		sources: sources,
+		logPath: sources[0],
+		table: table.New(
+			table.Column{Title: "Timestamp", Width: 20},
+			table.Column{Title: "Severity", Width: 8},
+			table.Column{Title: "Message", Width: 0},
+		),
+		loading:     true,
+		load:        &load{},
+		presets:     presets,
+		presetsPath: presetsPath,
+		presetsErr:  err,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return fetchLogs
+	return fetchLogs(m.newLoad(), m.source, m.query())
+}
+
+// Capturing reports whether the filter bar is taking keyboard input, in
+// which case the app must not treat keys as shortcuts.
+func (m Model) Capturing() bool {
+	return m.bar != nil
+}
+
+// query is the query of the current source with the filter applied.
+func (m Model) query() datasource.LogQuery {
+	return m.filter.query(m.logPath, time.Now())
+}
+
+func (m *Model) setFilter(f filter) {
+	m.filter = f
+	re := f.highlight()
+	m.table.SetHighlight(messageColumn, re)
+	m.unhighlighted = f.Pattern != "" && re == nil
+}
+
+// reload reads the current source again, following it if it was followed.
+func (m *Model) reload() tea.Cmd {
+	following := m.follow != nil
+	m.stopFollow()
+	m.loading = true
+	if following {
+		return startFollow(m.newLoad(), m.source, m.query())
+	}
+	return fetchLogs(m.newLoad(), m.source, m.query())
+}
+
+// newLoad cancels the read in progress and returns the context of the next.
+func (m Model) newLoad() context.Context {
+	m.cancelLoad()
+	ctx, cancel := context.WithCancel(context.Background())
+	m.load.cancel = cancel
+	return ctx
+}
+
+func (m Model) cancelLoad() {
+	if m.load != nil && m.load.cancel != nil {
+		m.load.cancel()
+		m.load.cancel = nil
+	}
+}
+
+// Close stops following and cancels the read in progress.
+func (m *Model) Close() {
+	m.stopFollow()
+	m.cancelLoad()
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.table.SetSize(msg.Width, msg.Height-2)
+		return m, nil
+
+	case tea.MouseMsg:
+		m.table, _ = m.table.Update(msg)
+		return m, nil
+
 	case tea.KeyMsg:
+		if m.bar != nil {
+			return m.updateBar(msg)
+		}
 		switch msg.String() {
-		case "j", "down":
-			if m.selected < len(m.entries)-1 {
-				m.selected++
-			}
-		case "k", "up":
-			if m.selected > 0 {
-				m.selected--
+		case "s":
+			if len(m.sources) > 1 {
+				m.current = (m.current + 1) % len(m.sources)
+				m.logPath = m.sources[m.current]
+				m.table.SetCursor(0)
+				return m, m.reload()
 			}
 		case "r":
-			return m, fetchLogs
+			if m.follow != nil {
+				return m, startFollow(m.newLoad(), m.source, m.query())
+			}
+			return m, fetchLogs(m.newLoad(), m.source, m.query())
+		case "f":
+			if m.follow != nil {
+				m.stopFollow()
+				return m, nil
+			}
+			return m, startFollow(m.newLoad(), m.source, m.query())
+		case "b":
+			if m.canLoadOlder() {
+				m.older = true
+				query := m.query()
+				query.Before = m.entries[0].Offset
+				return m, fetchOlder(m.newLoad(), m.source, query)
+			}
+		case "/":
+			m.bar = m.openBar()
+			return m, m.checkPattern()
+		case "esc":
+			if !m.filter.empty() {
+				m.setFilter(filter{})
+				return m, m.reload()
+			}
+		case "p", " ":
+			if m.follow != nil {
+				return m, m.togglePause()
+			}
+		default:
+			m.table, _ = m.table.Update(msg)
 		}
 
+	case followStartMsg, logChangedMsg, tailMsg:
+		return m.updateFollow(msg)
+
+	case patternMsg:
+		if b := m.bar; b != nil && b.filter.Pattern == msg.pattern && m.sources[b.source] == msg.path {
+			b.patternErr = msg.err
+			b.validate()
+		}
+		return m, nil
+
 	case logsMsg:
-		m.entries = msg.entries
+		if msg.path != m.logPath || m.follow != nil {
+			return m, nil
+		}
+		m.setEntries(msg.entries)
+		m.table.SetCursor(len(m.entries) - 1)
 		m.loading = false
+		m.atStart = false
 		m.err = msg.err
 		return m, nil
 
+	case olderMsg:
+		m.older = false
+		if msg.path != m.logPath || len(m.entries) == 0 || m.entries[0].Offset != msg.before {
+			return m, nil
+		}
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		if len(msg.entries) == 0 {
+			m.atStart = true
+			return m, nil
+		}
+		cursor := m.table.Cursor() + len(msg.entries)
+		m.setEntries(append(msg.entries, m.entries...))
+		m.table.SetCursor(cursor)
+		return m, nil
+
 	case error:
 		m.err = msg
 		m.loading = false
@@ -60,6 +233,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m Model) View() string {
+	if m.bar != nil {
+		header := theme.Current().Header.Render("Log Viewer: " + m.logPath)
+		return lipgloss.JoinVertical(lipgloss.Left, header, m.bar.view(m.sources, m.presets))
+	}
+
 	if m.loading {
 		return "Loading logs..."
 	}
@@ -68,67 +246,158 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v", m.err)
 	}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+	headerStyle := theme.Current().Header
 
-	header := headerStyle.Render(fmt.Sprintf("Log Viewer: %s (j/k: navigate, r: refresh)", m.logPath))
-
-	var lines []string
-	lines = append(lines, header, "")
+	help := "j/k/pgup/pgdn: navigate, /: filter, r: refresh, f: follow"
+	if m.follow != nil {
+		help = "j/k/pgup/pgdn: navigate, /: filter, r: reload, f: stop following, p: pause"
+		if m.follow.paused {
+			help = "j/k/pgup/pgdn: navigate, /: filter, r: reload, f: stop following, p: resume"
+		}
+	}
+	if m.canLoadOlder() {
+		help += ", b: older"
+	}
+	if len(m.sources) > 1 {
+		help += ", s: next source"
+	}
+	header := headerStyle.Render(fmt.Sprintf("Log Viewer: %s (%s)", m.logPath, help))
 
-	headerRow := fmt.Sprintf("%-20s %-8s %s",
-		"Timestamp", "Severity", "Message")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
+	status := m.followStatus()
+	if !m.filter.empty() {
+		if status != "" {
+			status += "  "
+		}
+		note := ""
+		if m.unhighlighted {
+			note = ", matches not highlighted as Go's regexp cannot compile the pattern"
+		}
+		status += theme.Current().Muted.Render("filter: " + m.filter.String() + " (/: edit, esc: clear" + note + ")")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, header, status, m.table.View())
+}
 
-	for i, entry := range m.entries {
-		style := lipgloss.NewStyle()
-		if i == m.selected {
-			style = style.Background(lipgloss.Color("63")).Foreground(lipgloss.Color("230"))
+// followStatus describes the follow state on the line under the header.
+func (m Model) followStatus() string {
+	if m.follow == nil {
+		return ""
+	}
+	t := theme.Current()
+	if m.follow.err != nil {
+		return t.Critical.Render(fmt.Sprintf("Following stopped reading: %v", m.follow.err))
+	}
+	status := t.Info.Render("Following")
+	if m.follow.paused {
+		status = t.Warning.Render("Paused")
+		if m.follow.behind {
+			status += t.Muted.Render(" (new entries will be read on resume)")
 		}
+	}
+	if below := m.table.Len() - 1 - m.table.Cursor(); below > 0 {
+		status += t.Muted.Render(fmt.Sprintf("  %d newer entries below", below))
+	}
+	return status
+}
+
+// canLoadOlder reports whether the file has lines before the oldest entry
+// shown. The journal is read from the newest entry only.
+func (m Model) canLoadOlder() bool {
+	return m.logPath != logparser.JournalSource && !m.older && !m.atStart &&
+		len(m.entries) > 0 && m.entries[0].Offset > 0
+}
+
+func (m *Model) setEntries(entries []logparser.LogEntry) {
+	m.entries = entries
+	m.table.SetRows(entryRows(entries))
+	m.table.SetStyleFunc(severityStyle(entries))
+}
 
-		severityColor := lipgloss.Color("241")
-		switch entry.Severity {
+func entryRows(entries []logparser.LogEntry) []table.Row {
+	rows := make([]table.Row, len(entries))
+	for i, entry := range entries {
+		rows[i] = table.Row{entry.Timestamp, entry.Severity, message(entry)}
+	}
+	return rows
+}
+
+// message prefixes journal messages with their source, as syslog does.
+func message(entry logparser.LogEntry) string {
+	switch {
+	case entry.Source != "" && entry.PID > 0:
+		return fmt.Sprintf("%s[%d]: %s", entry.Source, entry.PID, entry.Message)
+	case entry.Source != "":
+		return entry.Source + ": " + entry.Message
+	}
+	return entry.Message
+}
+
+func severityStyle(entries []logparser.LogEntry) func(int) lipgloss.Style {
+	return func(i int) lipgloss.Style {
+		t := theme.Current()
+		switch entries[i].Severity {
 		case "ERROR":
-			severityColor = lipgloss.Color("196")
+			return t.Critical
 		case "WARN":
-			severityColor = lipgloss.Color("220")
+			return t.Warning
 		case "INFO":
-			severityColor = lipgloss.Color("39")
+			return t.Info
 		}
-
-		severityStyle := style.Copy().Foreground(severityColor)
-		msg := entry.Message
-		if len(msg) > 50 {
-			msg = msg[:47] + "..."
-		}
-
-		line := fmt.Sprintf("%-20s %-8s %s",
-			entry.Timestamp, entry.Severity, msg)
-		lines = append(lines, severityStyle.Render(line))
+		return t.Muted
 	}
+}
 
-	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+// Msg is implemented by the messages of the logs view's reads, which must
+// reach it even while another view is active.
+type Msg interface {
+	isLogsMsg()
 }
 
 type logsMsg struct {
+	path    string
 	entries []logparser.LogEntry
 	err     error
 }
 
-func fetchLogs() tea.Msg {
-	entries, err := logparser.ParseLogs("/var/log/syslog", "", "", "", "")
-	if err != nil {
+func fetchLogs(ctx context.Context, source datasource.Source, query datasource.LogQuery) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := source.Logs(ctx, query)
+		if ctx.Err() != nil {
+			// Another read replaced this one.
+			return nil
+		}
+		if err != nil {
+			return logsMsg{
+				path:    query.Path,
+				entries: []logparser.LogEntry{},
+				err:     err,
+			}
+		}
+
 		return logsMsg{
-			entries: []logparser.LogEntry{},
-			err:     err,
+			path:    query.Path,
+			entries: entries,
+			err:     nil,
 		}
 	}
+}
+
+type olderMsg struct {
+	path    string
+	before  int64
+	entries []logparser.LogEntry
+	err     error
+}
 
-	return logsMsg{
-		entries: entries,
-		err:     nil,
+func (logsMsg) isLogsMsg()  {}
+func (olderMsg) isLogsMsg() {}
+
+// fetchOlder reads the page of entries before query.Before.
+func fetchOlder(ctx context.Context, source datasource.Source, query datasource.LogQuery) tea.Cmd {
+	return func() tea.Msg {
+		entries, err := source.Logs(ctx, query)
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return olderMsg{path: query.Path, before: query.Before, entries: entries, err: err}
 	}
 }
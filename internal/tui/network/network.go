@@ -5,49 +5,111 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
 	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
+// boxChrome is the number of lines a box adds around its table: border,
+// vertical padding, margin and the title with its blank line.
+const boxChrome = 8
+
 type Model struct {
+	source      datasource.Source
 	stats       []system.NetworkStats
 	connections []system.NetworkConnection
-	selected    int
+	interfaces  table.Model
+	conns       table.Model
+	focusConns  bool
+	width       int
+	height      int
 	loading     bool
 	err         error
 }
 
-func New() Model {
-	return Model{loading: true}
+func New(source datasource.Source) Model {
+	m := Model{
+		source: source,
+		interfaces: table.New(
+			table.Column{Title: "Interface", Width: 15},
+			table.Column{Title: "Sent/s", Width: 12, Right: true},
+			table.Column{Title: "Recv/s", Width: 12, Right: true},
+			table.Column{Title: "TxPkt/s", Width: 9, Right: true},
+			table.Column{Title: "RxPkt/s", Width: 9, Right: true},
+			table.Column{Title: "Err/s", Width: 7, Right: true},
+			table.Column{Title: "Drop/s", Width: 7, Right: true},
+			table.Column{Title: "Peak Sent", Width: 12, Right: true},
+			table.Column{Title: "Peak Recv", Width: 12, Right: true},
+			table.Column{Title: "Avg Sent", Width: 12, Right: true},
+			table.Column{Title: "Avg Recv", Width: 12, Right: true},
+		),
+		conns: table.New(
+			table.Column{Title: "Status", Width: 12},
+			table.Column{Title: "Local", Width: 28},
+			table.Column{Title: "Remote", Width: 28},
+			table.Column{Title: "PID", Width: 8, Right: true},
+		),
+		loading: true,
+	}
+	m.conns.Blur()
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(fetchStats, fetchConnections)
+	return nil
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+		m.layout()
+		return m, nil
+
+	case tea.MouseMsg:
+		if m.focusConns {
+			m.conns, _ = m.conns.Update(msg)
+		} else {
+			m.interfaces, _ = m.interfaces.Update(msg)
+		}
+		return m, nil
+
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "j", "down":
-			if m.selected < len(m.stats)-1 {
-				m.selected++
-			}
-		case "k", "up":
-			if m.selected > 0 {
-				m.selected--
+		case "tab":
+			m.focusConns = !m.focusConns
+			if m.focusConns {
+				m.interfaces.Blur()
+				m.conns.Focus()
+			} else {
+				m.conns.Blur()
+				m.interfaces.Focus()
 			}
 		case "r":
-			return m, tea.Batch(fetchStats, fetchConnections)
+			m.source.Refresh(system.GroupMetrics, system.GroupConnections)
+		default:
+			if m.focusConns {
+				m.conns, _ = m.conns.Update(msg)
+			} else {
+				m.interfaces, _ = m.interfaces.Update(msg)
+			}
 		}
 
-	case statsMsg:
-		m.stats = msg.stats
+	case *system.Snapshot:
+		if msg.Has(system.GroupConnections) {
+			m.connections = msg.Connections
+			m.conns.SetRows(connectionRows(m.connections))
+		}
+		if !msg.Has(system.GroupMetrics) {
+			return m, nil
+		}
+		m.stats = msg.Network
+		m.interfaces.SetRows(statRows(m.stats))
+		m.layout()
 		m.loading = false
-		m.err = msg.err
-		return m, nil
-
-	case connectionsMsg:
-		m.connections = msg.connections
+		m.err = msg.Err(system.GroupMetrics)
 		return m, nil
 
 	case error:
@@ -68,109 +130,106 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v", m.err)
 	}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+	headerStyle := theme.Current().Header
 
-	header := headerStyle.Render("Network Monitor (r: refresh)")
+	header := headerStyle.Render("Network Monitor (j/k/pgup/pgdn: navigate, tab: switch table, r: refresh)")
 
 	var sections []string
 	sections = append(sections, header, "")
 
-	statsSection := renderStats(m.stats, m.selected)
+	statsSection := m.renderStats()
 	sections = append(sections, statsSection)
 
 	if len(m.connections) > 0 {
-		sections = append(sections, "", renderConnections(m.connections))
+		sections = append(sections, m.renderConnections())
 	}
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
 
-func renderStats(stats []system.NetworkStats, selected int) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		Padding(1, 2).
-		Margin(1)
+// layout splits the height left by the header, the two boxes and the
+// totals line between the interface and connection tables.
+func (m *Model) layout() {
+	width := m.width - 8
+	avail := m.height - 2 - 2*boxChrome - 2
 
-	title := lipgloss.NewStyle().Bold(true).Render("Network Interfaces")
-	var lines []string
-	lines = append(lines, title, "")
+	ifaceHeight := len(m.stats) + 2
+	if limit := avail / 3; ifaceHeight > limit {
+		ifaceHeight = limit
+	}
+	if ifaceHeight < 3 {
+		ifaceHeight = 3
+	}
+	connHeight := avail - ifaceHeight
+	if connHeight < 3 {
+		connHeight = 3
+	}
 
-	headerRow := fmt.Sprintf("%-15s %12s %12s %8s %8s",
-		"Interface", "Bytes Sent", "Bytes Recv", "Errors", "Dropped")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
+	m.interfaces.SetSize(width, ifaceHeight)
+	m.conns.SetSize(width, connHeight)
+}
 
+func statRows(stats []system.NetworkStats) []table.Row {
+	rows := make([]table.Row, len(stats))
 	for i, stat := range stats {
-		style := lipgloss.NewStyle()
-		if i == selected {
-			style = style.Background(lipgloss.Color("63")).Foreground(lipgloss.Color("230"))
-		}
-
-		line := fmt.Sprintf("%-15s %12s %12s %8d %8d",
+		rows[i] = table.Row{
 			stat.Interface,
-			formatBytes(stat.BytesSent),
-			formatBytes(stat.BytesRecv),
-			stat.ErrorsIn+stat.ErrorsOut,
-			stat.DropIn+stat.DropOut)
-		lines = append(lines, style.Render(line))
+			formatRate(stat.Rates.BytesSent),
+			formatRate(stat.Rates.BytesRecv),
+			fmt.Sprintf("%.1f", stat.Rates.PacketsSent),
+			fmt.Sprintf("%.1f", stat.Rates.PacketsRecv),
+			fmt.Sprintf("%.1f", stat.Rates.Errors),
+			fmt.Sprintf("%.1f", stat.Rates.Drops),
+			formatRate(stat.Rates.PeakSent),
+			formatRate(stat.Rates.PeakRecv),
+			formatRate(stat.Rates.AvgSent),
+			formatRate(stat.Rates.AvgRecv),
+		}
 	}
+	return rows
+}
 
-	return boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+func connectionRows(conns []system.NetworkConnection) []table.Row {
+	rows := make([]table.Row, len(conns))
+	for i, conn := range conns {
+		rows[i] = table.Row{
+			conn.Status,
+			fmt.Sprintf("%s:%d", conn.LocalAddr, conn.LocalPort),
+			fmt.Sprintf("%s:%d", conn.RemoteAddr, conn.RemotePort),
+			fmt.Sprint(conn.PID),
+		}
+	}
+	return rows
 }
 
-func renderConnections(conns []system.NetworkConnection) string {
-	boxStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
+func (m Model) renderStats() string {
+	boxStyle := theme.Current().Box.Copy().
 		Padding(1, 2).
 		Margin(1)
 
-	title := lipgloss.NewStyle().Bold(true).Render("Active Connections")
-	var lines []string
-	lines = append(lines, title, "")
-
-	headerRow := fmt.Sprintf("%-8s %-20s %-20s %-8s",
-		"Status", "Local", "Remote", "PID")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
-
-	for _, conn := range conns[:min(20, len(conns))] {
-		local := fmt.Sprintf("%s:%d", conn.LocalAddr, conn.LocalPort)
-		remote := fmt.Sprintf("%s:%d", conn.RemoteAddr, conn.RemotePort)
-		line := fmt.Sprintf("%-8s %-20s %-20s %-8d",
-			conn.Status, local, remote, conn.PID)
-		lines = append(lines, line)
+	title := theme.Current().Title.Render("Network Interfaces")
+	lines := []string{title, "", m.interfaces.View()}
+
+	if selected := m.interfaces.Cursor(); selected < len(m.stats) {
+		stat := m.stats[selected]
+		lines = append(lines, "", fmt.Sprintf("%s totals: sent %s, received %s, errors %d, dropped %d",
+			stat.Interface,
+			formatBytes(stat.BytesSent),
+			formatBytes(stat.BytesRecv),
+			stat.ErrorsIn+stat.ErrorsOut,
+			stat.DropIn+stat.DropOut))
 	}
 
 	return boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
 }
 
-type statsMsg struct {
-	stats []system.NetworkStats
-	err   error
-}
-
-type connectionsMsg struct {
-	connections []system.NetworkConnection
-	err         error
-}
-
-func fetchStats() tea.Msg {
-	stats, err := system.GetNetworkStats()
-	return statsMsg{
-		stats: stats,
-		err:   err,
-	}
-}
+func (m Model) renderConnections() string {
+	boxStyle := theme.Current().Box.Copy().
+		Padding(1, 2).
+		Margin(1)
 
-func fetchConnections() tea.Msg {
-	connections, err := system.GetNetworkConnections()
-	return connectionsMsg{
-		connections: connections,
-		err:         err,
-	}
+	title := theme.Current().Title.Render("Active Connections")
+	return boxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, title, "", m.conns.View()))
 }
 
 func formatBytes(bytes uint64) string {
@@ -186,6 +245,10 @@ func formatBytes(bytes uint64) string {
 	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+func formatRate(rate float64) string {
+	return formatBytes(uint64(rate)) + "/s"
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -2,52 +2,70 @@ package packages
 
 import (
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
 	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
 type Model struct {
+	source         datasource.Source
 	packages       []system.PackageInfo
 	packageManager system.PackageManager
-	selected       int
+	table          table.Model
+	updated        time.Time
 	loading        bool
 	err            error
 }
 
-func New() Model {
-	return Model{loading: true}
+func New(source datasource.Source) Model {
+	return Model{
+		source: source,
+		table: table.New(
+			table.Column{Title: "Name", Width: 30},
+			table.Column{Title: "Version", Width: 15},
+			table.Column{Title: "Description", Width: 0},
+		),
+		loading: true,
+	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(detectPackageManager, fetchPackages)
+	return nil
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.table.SetSize(msg.Width, msg.Height-2)
+		return m, nil
+
+	case tea.MouseMsg:
+		m.table, _ = m.table.Update(msg)
+		return m, nil
+
 	case tea.KeyMsg:
 		switch msg.String() {
-		case "j", "down":
-			if m.selected < len(m.packages)-1 {
-				m.selected++
-			}
-		case "k", "up":
-			if m.selected > 0 {
-				m.selected--
-			}
 		case "r":
-			return m, fetchPackages
+			m.source.Refresh(system.GroupPackages)
+		default:
+			m.table, _ = m.table.Update(msg)
 		}
 
-	case packageManagerMsg:
-		m.packageManager = msg.pm
-		return m, nil
-
-	case packagesMsg:
-		m.packages = msg.packages
+	case *system.Snapshot:
+		if !msg.Has(system.GroupPackages) || msg.Updated[system.GroupPackages].Equal(m.updated) {
+			return m, nil
+		}
+		m.updated = msg.Updated[system.GroupPackages]
+		m.packageManager = msg.PackageManager
+		m.packages = msg.Packages
+		m.table.SetRows(packageRows(m.packages))
 		m.loading = false
-		m.err = msg.err
+		m.err = msg.Err(system.GroupPackages)
 		return m, nil
 
 	case error:
@@ -68,65 +86,18 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v", m.err)
 	}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+	headerStyle := theme.Current().Header
 
 	pmName := string(m.packageManager)
-	header := headerStyle.Render(fmt.Sprintf("Packages (%s) - j/k: navigate, r: refresh", pmName))
-
-	var lines []string
-	lines = append(lines, header, "")
-
-	headerRow := fmt.Sprintf("%-30s %-15s %s",
-		"Name", "Version", "Description")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
-
-	for i, pkg := range m.packages {
-		style := lipgloss.NewStyle()
-		if i == m.selected {
-			style = style.Background(lipgloss.Color("63")).Foreground(lipgloss.Color("230"))
-		}
-
-		name := pkg.Name
-		if len(name) > 30 {
-			name = name[:27] + "..."
-		}
-
-		desc := pkg.Description
-		if len(desc) > 40 {
-			desc = desc[:37] + "..."
-		}
-
-		line := fmt.Sprintf("%-30s %-15s %s",
-			name, pkg.Version, desc)
-		lines = append(lines, style.Render(line))
-	}
-
-	return lipgloss.JoinVertical(lipgloss.Left, lines...)
-}
-
-type packageManagerMsg struct {
-	pm system.PackageManager
-}
-
-type packagesMsg struct {
-	packages []system.PackageInfo
-	err      error
-}
+	header := headerStyle.Render(fmt.Sprintf("Packages (%s) - j/k/pgup/pgdn: navigate, r: refresh", pmName))
 
-func detectPackageManager() tea.Msg {
-	pm := system.DetectPackageManager()
-	return packageManagerMsg{pm: pm}
+	return lipgloss.JoinVertical(lipgloss.Left, header, "", m.table.View())
 }
 
-func fetchPackages() tea.Msg {
-	pm := system.DetectPackageManager()
-	packages, err := system.ListPackages(pm)
-	return packagesMsg{
-		packages: packages,
-		err:      err,
+func packageRows(packages []system.PackageInfo) []table.Row {
+	rows := make([]table.Row, len(packages))
+	for i, pkg := range packages {
+		rows[i] = table.Row{pkg.Name, pkg.Version, pkg.Description}
 	}
+	return rows
 }
@@ -0,0 +1,156 @@
+package processes
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+// matcher reports whether a process passes a filter.
+type matcher func(p *system.ProcessInfo) bool
+
+var numericFields = map[string]func(p *system.ProcessInfo) float64{
+	"pid":  func(p *system.ProcessInfo) float64 { return float64(p.PID) },
+	"cpu":  func(p *system.ProcessInfo) float64 { return p.CPUPercent },
+	"mem":  func(p *system.ProcessInfo) float64 { return float64(p.MemPercent) },
+	"nice": func(p *system.ProcessInfo) float64 { return float64(p.Nice) },
+}
+
+var stringFields = map[string]func(p *system.ProcessInfo) string{
+	"name":   func(p *system.ProcessInfo) string { return p.Name },
+	"user":   func(p *system.ProcessInfo) string { return p.User },
+	"cmd":    func(p *system.ProcessInfo) string { return p.CommandLine },
+	"status": func(p *system.ProcessInfo) string { return p.Status },
+}
+
+// Longer operators come first so that ">=" is not read as ">".
+var operators = []string{">=", "<=", "!=", ">", "<", "=", ":"}
+
+// parseFilter compiles a query into a matcher. The query is a list of
+// space-separated terms that must all match:
+//
+//	text       case-insensitive substring of the name, user, command or PID
+//	/regexp/   regular expression matched against the name and command
+//	name:text  substring of a field (name, user, cmd, status); = matches
+//	           exactly and != excludes
+//	cpu>5      numeric comparison on cpu, mem, pid or nice with
+//	           >, >=, <, <=, =, != or :
+//
+// A term whose key is not one of these fields, such as a=b, is text.
+// An empty query returns a nil matcher.
+func parseFilter(query string) (matcher, error) {
+	var matchers []matcher
+	for _, term := range strings.Fields(query) {
+		m, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, m)
+	}
+	if len(matchers) == 0 {
+		return nil, nil
+	}
+
+	return func(p *system.ProcessInfo) bool {
+		for _, m := range matchers {
+			if !m(p) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func parseTerm(term string) (matcher, error) {
+	if len(term) > 2 && strings.HasPrefix(term, "/") && strings.HasSuffix(term, "/") {
+		re, err := regexp.Compile(term[1 : len(term)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp %s: %w", term, err)
+		}
+		return func(p *system.ProcessInfo) bool {
+			return re.MatchString(p.Name) || re.MatchString(p.CommandLine)
+		}, nil
+	}
+
+	i := strings.IndexAny(term, "<>=!:")
+	if i <= 0 {
+		return substring(term), nil
+	}
+	field, rest := strings.ToLower(term[:i]), term[i:]
+	_, numeric := numericFields[field]
+	if _, ok := stringFields[field]; !ok && !numeric {
+		return substring(term), nil
+	}
+
+	var op string
+	for _, candidate := range operators {
+		if strings.HasPrefix(rest, candidate) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return nil, fmt.Errorf("invalid term %q", term)
+	}
+	value := rest[len(op):]
+
+	if get, ok := numericFields[field]; ok {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %q is not a number", field, value)
+		}
+		return compare(get, op, n), nil
+	}
+
+	get := stringFields[field]
+	value = strings.ToLower(value)
+	switch op {
+	case ":":
+		return func(p *system.ProcessInfo) bool {
+			return strings.Contains(strings.ToLower(get(p)), value)
+		}, nil
+	case "=":
+		return func(p *system.ProcessInfo) bool {
+			return strings.ToLower(get(p)) == value
+		}, nil
+	case "!=":
+		return func(p *system.ProcessInfo) bool {
+			return !strings.Contains(strings.ToLower(get(p)), value)
+		}, nil
+	}
+	return nil, fmt.Errorf("%s: operator %s needs a numeric field", field, op)
+}
+
+// substring matches text case-insensitively in the name, user, command or
+// PID.
+func substring(text string) matcher {
+	text = strings.ToLower(text)
+	return func(p *system.ProcessInfo) bool {
+		return strings.Contains(strings.ToLower(p.Name), text) ||
+			strings.Contains(strings.ToLower(p.User), text) ||
+			strings.Contains(strings.ToLower(p.CommandLine), text) ||
+			strings.Contains(strconv.Itoa(int(p.PID)), text)
+	}
+}
+
+func compare(get func(p *system.ProcessInfo) float64, op string, n float64) matcher {
+	return func(p *system.ProcessInfo) bool {
+		v := get(p)
+		switch op {
+		case ">":
+			return v > n
+		case ">=":
+			return v >= n
+		case "<":
+			return v < n
+		case "<=":
+			return v <= n
+		case "!=":
+			return v != n
+		}
+		return v == n
+	}
+}
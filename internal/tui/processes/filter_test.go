@@ -0,0 +1,89 @@
+package processes
+
+import (
+	"testing"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+var filterProcs = []system.ProcessInfo{
+	{PID: 1, Name: "systemd", User: "root", CommandLine: "/sbin/init", Status: "S", CPUPercent: 0.1, MemPercent: 0.25},
+	{PID: 812, Name: "sshd", User: "root", CommandLine: "/usr/sbin/sshd -D", Status: "S", CPUPercent: 0, MemPercent: 0.125, Nice: -5},
+	{PID: 2045, Name: "bash", User: "alice", CommandLine: "-bash", Status: "S", CPUPercent: 1.5, MemPercent: 0.5},
+	{PID: 3110, Name: "python3", User: "alice", CommandLine: "/usr/bin/python3 serve.py a=b", Status: "R", CPUPercent: 42, MemPercent: 5, Nice: 10},
+	{PID: 4001, Name: "node", User: "bob", CommandLine: "PATH=/usr/bin:x node app.js", Status: "R", CPUPercent: 7.5, MemPercent: 2},
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []int32
+	}{
+		{"", []int32{1, 812, 2045, 3110, 4001}},
+		{"SSH", []int32{812}},
+		{"alice", []int32{2045, 3110}},
+		{"311", []int32{3110}},
+		{"/^s/", []int32{1, 812}},
+		{"/serve\\.py$/", nil},
+		{"user:root", []int32{1, 812}},
+		{"NAME=bash", []int32{2045}},
+		{"name=bas", nil},
+		{"cmd!=usr", []int32{1, 2045}},
+		{"status:r", []int32{3110, 4001}},
+		{"cpu>5", []int32{3110, 4001}},
+		{"cpu>=7.5", []int32{3110, 4001}},
+		{"mem<0.5", []int32{1, 812}},
+		{"mem<=0.25", []int32{1, 812}},
+		{"nice!=0", []int32{812, 3110}},
+		{"pid=1", []int32{1}},
+		{"pid:812", []int32{812}},
+		{"user:alice cpu>5", []int32{3110}},
+		{"a=b", []int32{3110}},
+		{"/usr/bin:x", []int32{4001}},
+		{"path=/usr", []int32{4001}},
+	}
+	for _, tt := range tests {
+		match, err := parseFilter(tt.query)
+		if err != nil {
+			t.Errorf("parseFilter(%q): %v", tt.query, err)
+			continue
+		}
+		var got []int32
+		for i := range filterProcs {
+			if match == nil || match(&filterProcs[i]) {
+				got = append(got, filterProcs[i].PID)
+			}
+		}
+		if !equalPIDs(got, tt.want) {
+			t.Errorf("parseFilter(%q) matched %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, query := range []string{
+		"/[/",
+		"cpu>high",
+		"pid=",
+		"name>bash",
+		"user<=root",
+		"cpu!5",
+		"ssh mem>x",
+	} {
+		if _, err := parseFilter(query); err == nil {
+			t.Errorf("parseFilter(%q) succeeded, want an error", query)
+		}
+	}
+}
+
+func equalPIDs(a, b []int32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
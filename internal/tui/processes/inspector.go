@@ -0,0 +1,272 @@
+package processes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/chart"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+// inspector shows the details of one process. Its CPU and memory charts
+// start when it opens and follow the process through later snapshots.
+type inspector struct {
+	target  target
+	name    string
+	details *system.ProcessDetails
+	err     error
+	cpu     *system.Ring
+	mem     *system.Ring
+	memory  system.ProcessMemory
+	exited  bool
+	offset  int
+}
+
+type detailsMsg struct {
+	pid     int32
+	details *system.ProcessDetails
+	err     error
+}
+
+func (detailsMsg) isProcessesMsg() {}
+
+func fetchDetails(source datasource.Source, pid int32) tea.Cmd {
+	return func() tea.Msg {
+		details, err := source.InspectProcess(pid)
+		return detailsMsg{pid: pid, details: details, err: err}
+	}
+}
+
+// openInspector opens the inspector on the process under the cursor.
+func (m *Model) openInspector() tea.Cmd {
+	proc, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	length := 120
+	if history := m.source.History(); history != nil {
+		length = history.Length()
+	}
+	m.inspector = &inspector{
+		target: target{pid: proc.PID, createTime: proc.CreateTime},
+		name:   proc.Name,
+		cpu:    system.NewRing(length),
+		mem:    system.NewRing(length),
+	}
+	m.inspector.observe(m.processes)
+	return fetchDetails(m.source, proc.PID)
+}
+
+// observe records the usage of the inspected process from a process list.
+func (in *inspector) observe(procs []system.ProcessInfo) {
+	for _, proc := range procs {
+		if proc.PID == in.target.pid && proc.CreateTime == in.target.createTime {
+			in.cpu.Push(proc.CPUPercent)
+			in.mem.Push(float64(proc.MemPercent))
+			in.memory = proc.Memory
+			in.exited = false
+			return
+		}
+	}
+	in.exited = true
+}
+
+// update handles a key in the inspector and reports whether it stays open.
+func (in *inspector) update(msg tea.KeyMsg, source datasource.Source, height int) (bool, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "q", "enter":
+		return false, nil
+	case "r":
+		return true, fetchDetails(source, in.target.pid)
+	case "j", "down":
+		in.offset++
+	case "k", "up":
+		in.offset--
+	case "pgdown", "ctrl+f", " ":
+		in.offset += height
+	case "pgup", "ctrl+b":
+		in.offset -= height
+	case "home", "g":
+		in.offset = 0
+	case "end", "G":
+		in.offset = 1 << 30
+	}
+	return true, nil
+}
+
+func (in *inspector) view(width, height int) string {
+	t := theme.Current()
+
+	title := fmt.Sprintf("Process %d (%s)", in.target.pid, in.name)
+	if in.exited {
+		title += " - exited"
+	}
+	lines := []string{t.Title.Render(title)}
+
+	chartWidth := width - 16
+	if chartWidth < 10 {
+		chartWidth = 10
+	}
+	cpu, mem := in.cpu.Values(), in.mem.Values()
+	last := func(values []float64) float64 {
+		if len(values) == 0 {
+			return 0
+		}
+		return values[len(values)-1]
+	}
+	lines = append(lines,
+		fmt.Sprintf("CPU %6.1f%%   %s", last(cpu), chart.Sparkline(cpu, chartWidth, 0)),
+		fmt.Sprintf("Mem %6.1f%%   %s", last(mem), chart.Sparkline(mem, chartWidth, 0)),
+		"")
+
+	switch {
+	case in.err != nil:
+		lines = append(lines, t.Critical.Render(in.err.Error()))
+	case in.details == nil:
+		lines = append(lines, "Loading...")
+	default:
+		lines = append(lines, in.sections(width)...)
+	}
+
+	// The title and charts stay in place while the details scroll.
+	body := lines[4:]
+	visible := height - 4 - 1
+	if visible < 1 {
+		visible = 1
+	}
+	if in.offset > len(body)-visible {
+		in.offset = len(body) - visible
+	}
+	if in.offset < 0 {
+		in.offset = 0
+	}
+	end := in.offset + visible
+	if end > len(body) {
+		end = len(body)
+	}
+
+	out := append(lines[:4:4], body[in.offset:end]...)
+	out = append(out, t.Muted.Render(fmt.Sprintf(
+		"j/k/pgup/pgdn: scroll, r: reload, esc: close (%d-%d of %d)", in.offset+1, end, len(body))))
+	return strings.Join(out, "\n")
+}
+
+// sections renders the details as a list of lines under section headings.
+func (in *inspector) sections(width int) []string {
+	t := theme.Current()
+	d := in.details
+
+	var lines []string
+	section := func(name, key string, body ...string) {
+		lines = append(lines, t.Header.Render(name))
+		if err, ok := d.Errors[key]; ok {
+			lines = append(lines, "  "+t.Critical.Render(err))
+		} else if len(body) == 0 {
+			lines = append(lines, "  "+t.Muted.Render("none"))
+		}
+		for _, line := range body {
+			lines = append(lines, "  "+table.Truncate(line, width-2))
+		}
+		lines = append(lines, "")
+	}
+
+	section("Command line", "cmdline", strings.Join(d.CommandLine, " "))
+	section("Executable", "exe", d.Exe)
+	section("Working directory", "cwd", d.Cwd)
+
+	mem := in.memory
+	section("Memory", "",
+		fmt.Sprintf("RSS %s  PSS %s  USS %s  Shared %s  Swap %s  VSZ %s",
+			formatBytes(mem.RSS), rollupBytes(mem, mem.PSS), rollupBytes(mem, mem.USS),
+			rollupBytes(mem, mem.Shared), rollupBytes(mem, mem.Swap), formatBytes(mem.VSZ)))
+
+	env := append([]string(nil), d.Environ...)
+	sort.Strings(env)
+	if d.EnvHidden {
+		env = []string{"hidden (the server only sends it to admin tokens)"}
+	}
+	section("Environment", "environ", env...)
+
+	var limits []string
+	for _, l := range d.Limits {
+		limits = append(limits, fmt.Sprintf("%-26s %-20s %-20s %s", l.Name, l.Soft, l.Hard, l.Units))
+	}
+	if len(limits) > 0 {
+		limits = append([]string{fmt.Sprintf("%-26s %-20s %-20s %s", "Limit", "Soft", "Hard", "Units")}, limits...)
+	}
+	section("Limits", "limits", limits...)
+
+	section("Cgroups", "cgroup", d.Cgroups...)
+
+	var namespaces []string
+	for _, ns := range d.Namespaces {
+		namespaces = append(namespaces, fmt.Sprintf("%-18s %s", ns.Type, ns.Inode))
+	}
+	section("Namespaces", "namespaces", namespaces...)
+
+	caps := d.Capabilities
+	effective := strings.Join(caps.Names, ", ")
+	if effective == "" {
+		effective = "none"
+	}
+	section("Capabilities", "capabilities",
+		"effective:   "+effective,
+		fmt.Sprintf("sets:        eff %s  prm %s  inh %s  bnd %s  amb %s",
+			caps.Effective, caps.Permitted, caps.Inheritable, caps.Bounding, caps.Ambient))
+
+	var fds []string
+	for _, fd := range d.FDs {
+		fds = append(fds, fmt.Sprintf("%5d  %s", fd.FD, fd.Target))
+	}
+	if d.FDCount > len(d.FDs) {
+		fds = append(fds, fmt.Sprintf("... %d more", d.FDCount-len(d.FDs)))
+	}
+	section(fmt.Sprintf("Open file descriptors (%d)", d.FDCount), "fds", fds...)
+
+	maps := d.Maps
+	mapLines := []string{
+		fmt.Sprintf("%d regions, %s total: %s anonymous, %s heap, %s stack",
+			maps.Regions, formatBytes(maps.Total), formatBytes(maps.Anonymous),
+			formatBytes(maps.Heap), formatBytes(maps.Stack)),
+	}
+	for _, f := range maps.Files {
+		mapLines = append(mapLines, fmt.Sprintf("%10s  %s", formatBytes(f.Size), f.Path))
+	}
+	section("Memory maps", "maps", mapLines...)
+
+	var threads []string
+	for _, th := range d.Threads {
+		threads = append(threads, fmt.Sprintf("%8d  %-2s %s", th.TID, th.State, th.Name))
+	}
+	if d.ThreadCount > len(d.Threads) {
+		threads = append(threads, fmt.Sprintf("... %d more", d.ThreadCount-len(d.Threads)))
+	}
+	section(fmt.Sprintf("Threads (%d)", d.ThreadCount), "threads", threads...)
+
+	var conns []string
+	for _, c := range d.Connections {
+		conns = append(conns, fmt.Sprintf("%-12s %s:%d -> %s:%d",
+			c.Status, c.LocalAddr, c.LocalPort, c.RemoteAddr, c.RemotePort))
+	}
+	section("Sockets", "connections", conns...)
+
+	return lines
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.2f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
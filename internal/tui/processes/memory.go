@@ -0,0 +1,172 @@
+package processes
+
+import (
+	"fmt"
+	"sort"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+// memoryValue returns the memory figure a memory sort column orders by.
+func memoryValue(p *system.ProcessInfo, col sortColumn) uint64 {
+	switch col {
+	case sortRSS:
+		return p.Memory.RSS
+	case sortPSS:
+		return p.Memory.PSS
+	case sortUSS:
+		return p.Memory.USS
+	case sortShared:
+		return p.Memory.Shared
+	case sortSwap:
+		return p.Memory.Swap
+	case sortVSZ:
+		return p.Memory.VSZ
+	}
+	return 0
+}
+
+// rollupBytes formats a figure that is only known when smaps_rollup could
+// be read.
+func rollupBytes(mem system.ProcessMemory, v uint64) string {
+	if !mem.Rollup {
+		return "-"
+	}
+	return formatBytes(v)
+}
+
+// memoryGroup is the memory use of the processes of one user or command.
+type memoryGroup struct {
+	name    string
+	count   int
+	cpu     float64
+	rss     uint64
+	pss     uint64
+	uss     uint64
+	swap    uint64
+	partial int
+}
+
+// totals adds up memory use per user or per command name, so that the
+// owners of memory on a shared host stand out. It ranks by PSS, which
+// unlike RSS does not count shared pages once per process.
+type totals struct {
+	byCommand bool
+	procs     []system.ProcessInfo
+	table     table.Model
+}
+
+func (m *Model) openTotals() {
+	m.totals = &totals{table: table.New()}
+	m.totals.table.SetSize(m.width, m.height-3)
+	m.totals.table.Focus()
+	m.totals.load(m.filtered())
+}
+
+// update handles a key in the totals pane and reports whether it stays open.
+func (t *totals) update(msg tea.KeyMsg) bool {
+	switch msg.String() {
+	case "esc", "q", "u":
+		return false
+	case "tab":
+		t.byCommand = !t.byCommand
+		t.table.SetCursor(0)
+		t.load(t.procs)
+	default:
+		t.table, _ = t.table.Update(msg)
+	}
+	return true
+}
+
+func (t *totals) load(procs []system.ProcessInfo) {
+	t.procs = procs
+
+	groups := make(map[string]*memoryGroup)
+	for _, proc := range procs {
+		key := proc.User
+		if t.byCommand {
+			key = proc.Name
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &memoryGroup{name: key}
+			groups[key] = g
+		}
+		g.count++
+		g.cpu += proc.CPUPercent
+		g.rss += proc.Memory.RSS
+		g.pss += proc.Memory.PSS
+		g.uss += proc.Memory.USS
+		g.swap += proc.Memory.Swap
+		if !proc.Memory.Rollup {
+			g.partial++
+		}
+	}
+
+	list := make([]*memoryGroup, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.pss != b.pss {
+			return a.pss > b.pss
+		}
+		if a.rss != b.rss {
+			return a.rss > b.rss
+		}
+		return a.name < b.name
+	})
+
+	rows := make([]table.Row, len(list))
+	for i, g := range list {
+		partial := ""
+		if g.partial > 0 {
+			partial = fmt.Sprintf("%d without PSS", g.partial)
+		}
+		rows[i] = table.Row{
+			g.name,
+			fmt.Sprint(g.count),
+			fmt.Sprintf("%.2f", g.cpu),
+			formatBytes(g.rss),
+			formatBytes(g.pss),
+			formatBytes(g.uss),
+			formatBytes(g.swap),
+			partial,
+		}
+	}
+
+	title := "User"
+	if t.byCommand {
+		title = "Command"
+	}
+	cols := []table.Column{{Title: title, Width: 24}}
+	t.table.SetColumns(append(cols, totalsColumns...)...)
+	t.table.SetRows(rows)
+}
+
+var totalsColumns = []table.Column{
+	{Title: "Procs", Width: 7, Right: true},
+	{Title: "CPU%", Width: 8, Right: true},
+	{Title: "RSS", Width: 11, Right: true},
+	{Title: "PSS ▼", Width: 11, Right: true},
+	{Title: "USS", Width: 11, Right: true},
+	{Title: "Swap", Width: 11, Right: true},
+	{Title: "Partial", Width: 0},
+}
+
+func (t *totals) view() string {
+	th := theme.Current()
+
+	by := "user"
+	if t.byCommand {
+		by = "command"
+	}
+	title := th.Title.Render(fmt.Sprintf("Memory by %s", by)) + "  " +
+		th.Muted.Render("tab: by user/command, esc: close; groups without PSS lack permission to read smaps_rollup")
+	return lipgloss.JoinVertical(lipgloss.Left, title, t.table.View())
+}
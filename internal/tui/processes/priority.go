@@ -0,0 +1,305 @@
+package processes
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+type priorityField int
+
+const (
+	fieldNice priorityField = iota
+	fieldIOClass
+	fieldIOLevel
+	fieldAffinity
+	fieldCount
+)
+
+// priorityPanel edits the nice value, I/O priority and CPU affinity of a
+// process, starting from its current values.
+type priorityPanel struct {
+	pid      int32
+	subject  string
+	loaded   bool
+	current  system.Priority
+	nice     string
+	ioClass  system.IOClass
+	ioLevel  string
+	affinity string
+	field    priorityField
+	applying bool
+	err      error
+}
+
+type priorityMsg struct {
+	pid      int32
+	priority system.Priority
+	err      error
+}
+
+// appliedMsg reports the settings apply changed. On failure, priority
+// holds the values in effect: those applied before the error and the
+// current ones for the rest.
+type appliedMsg struct {
+	pid      int32
+	priority system.Priority
+	applied  []string
+	err      error
+}
+
+func (priorityMsg) isProcessesMsg() {}
+func (appliedMsg) isProcessesMsg()  {}
+
+func fetchPriority(source datasource.Source, pid int32) tea.Cmd {
+	return func() tea.Msg {
+		priority, err := source.ProcessPriority(pid)
+		return priorityMsg{pid: pid, priority: priority, err: err}
+	}
+}
+
+// openPanel opens the priority panel for the process under the cursor and
+// loads its current values.
+func (m *Model) openPanel() tea.Cmd {
+	proc, ok := m.selected()
+	if !ok {
+		return nil
+	}
+	m.panel = &priorityPanel{pid: proc.PID, subject: fmt.Sprintf("%d (%s)", proc.PID, proc.Name)}
+	return fetchPriority(m.source, proc.PID)
+}
+
+func (p *priorityPanel) load(msg priorityMsg) {
+	if msg.err != nil {
+		p.err = msg.err
+		return
+	}
+	p.loaded = true
+	p.current = msg.priority
+	p.nice = strconv.Itoa(msg.priority.Nice)
+	p.ioClass = msg.priority.IOClass
+	p.ioLevel = strconv.Itoa(msg.priority.IOLevel)
+	p.affinity = formatCPUList(msg.priority.Affinity)
+}
+
+// update handles a key in the panel. It returns whether the panel stays
+// open and whether the values should be applied.
+func (p *priorityPanel) update(msg tea.KeyMsg) (open, apply bool) {
+	switch msg.String() {
+	case "esc":
+		return false, false
+	case "enter":
+		return true, p.loaded && !p.applying
+	case "tab", "down":
+		p.field = (p.field + 1) % fieldCount
+	case "shift+tab", "up":
+		p.field = (p.field + fieldCount - 1) % fieldCount
+	case "left", "right", " ":
+		if p.field == fieldIOClass {
+			step := system.IOClass(1)
+			if msg.String() == "left" {
+				step = system.IOClassIdle
+			}
+			p.ioClass = (p.ioClass + step) % (system.IOClassIdle + 1)
+		}
+	case "backspace":
+		if text := p.text(); text != nil && len(*text) > 0 {
+			*text = (*text)[:len(*text)-1]
+		}
+	default:
+		if text := p.text(); text != nil && msg.Type == tea.KeyRunes {
+			for _, r := range msg.Runes {
+				if strings.ContainsRune("0123456789-,", r) {
+					*text += string(r)
+				}
+			}
+		}
+	}
+	p.err = nil
+	return true, false
+}
+
+// text returns the text being edited in the focused field, if any.
+func (p *priorityPanel) text() *string {
+	switch p.field {
+	case fieldNice:
+		return &p.nice
+	case fieldIOLevel:
+		return &p.ioLevel
+	case fieldAffinity:
+		return &p.affinity
+	}
+	return nil
+}
+
+// apply checks the values and returns a cmd sending those that differ from
+// the current ones. The cmd stops at the first error, reporting the
+// settings it changed before.
+func (p *priorityPanel) apply(source datasource.Source) (tea.Cmd, error) {
+	nice, err := strconv.Atoi(p.nice)
+	if err != nil || nice < -20 || nice > 19 {
+		return nil, fmt.Errorf("nice must be a number between -20 and 19")
+	}
+	level, err := strconv.Atoi(p.ioLevel)
+	if err != nil || level < 0 || level > 7 {
+		return nil, fmt.Errorf("I/O level must be a number between 0 and 7")
+	}
+	cpus, err := parseCPUList(p.affinity)
+	if err != nil {
+		return nil, err
+	}
+
+	pid, priority, ioClass := p.pid, p.current, p.ioClass
+	return func() tea.Msg {
+		msg := appliedMsg{pid: pid, priority: priority}
+		set := func(name string, changed bool, send func() error) bool {
+			if !changed || msg.err != nil {
+				return false
+			}
+			if err := send(); err != nil {
+				msg.err = fmt.Errorf("%s: %w", name, err)
+				return false
+			}
+			msg.applied = append(msg.applied, name)
+			return true
+		}
+
+		if set("nice", nice != priority.Nice, func() error {
+			return source.ReniceProcess(pid, nice)
+		}) {
+			msg.priority.Nice = nice
+		}
+		if set("I/O priority", ioClass != priority.IOClass || level != priority.IOLevel, func() error {
+			return source.SetIOPriority(pid, ioClass, level)
+		}) {
+			msg.priority.IOClass, msg.priority.IOLevel = ioClass, level
+		}
+		if set("CPU affinity", formatCPUList(cpus) != formatCPUList(priority.Affinity), func() error {
+			return source.SetAffinity(pid, cpus)
+		}) {
+			msg.priority.Affinity = cpus
+		}
+		return msg
+	}, nil
+}
+
+// applied takes the outcome of apply. After a failure the panel stays open
+// with the error and the settings already changed.
+func (p *priorityPanel) applied(msg appliedMsg) {
+	p.applying = false
+	p.current = msg.priority
+	p.err = msg.err
+	if msg.err != nil && len(msg.applied) > 0 {
+		p.err = fmt.Errorf("applied %s, then %w", strings.Join(msg.applied, " and "), msg.err)
+	}
+}
+
+func (p *priorityPanel) view() string {
+	t := theme.Current()
+
+	lines := []string{t.Title.Render("Priority of " + p.subject), ""}
+	if !p.loaded {
+		if p.err != nil {
+			lines = append(lines, t.Critical.Render(p.err.Error()), "", "esc: close")
+		} else {
+			lines = append(lines, "Loading...")
+		}
+		return t.Box.Copy().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+	}
+
+	cpus := ""
+	if p.current.CPUs > 0 {
+		cpus = fmt.Sprintf(" (0-%d)", p.current.CPUs-1)
+	}
+	fields := []struct {
+		label, value, hint string
+	}{
+		{"Nice", p.nice, "-20 (highest) to 19 (lowest)"},
+		{"I/O class", p.ioClass.String(), "left/right: none, realtime, best-effort, idle"},
+		{"I/O level", p.ioLevel, "0 (highest) to 7 (lowest), realtime and best-effort only"},
+		{"CPU affinity", p.affinity, "CPU list such as 0-3,6" + cpus},
+	}
+	for i, f := range fields {
+		value := fmt.Sprintf("%-12s", f.value+" ")
+		if priorityField(i) == p.field {
+			value = t.Selection.Render(value)
+		}
+		lines = append(lines, fmt.Sprintf("%-13s %s  %s", f.label+":", value, t.Muted.Render(f.hint)))
+	}
+
+	switch {
+	case p.applying:
+		lines = append(lines, "", "Applying...")
+	case p.err != nil:
+		lines = append(lines, "", t.Critical.Render(p.err.Error()))
+	}
+	lines = append(lines, "", "tab/up/down: field, enter: apply, esc: cancel")
+
+	return t.Box.Copy().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// maxCPUs is the size of the kernel's default CPU mask.
+const maxCPUs = 1024
+
+// parseCPUList parses a list such as "0-3,6" into sorted CPU numbers.
+func parseCPUList(list string) ([]int, error) {
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU %q", part)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(hi); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU range %q", part)
+			}
+		}
+		if first < 0 || last >= maxCPUs {
+			return nil, fmt.Errorf("CPU %q out of range", part)
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			seen[cpu] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("CPU affinity must include at least one CPU")
+	}
+
+	cpus := make([]int, 0, len(seen))
+	for cpu := range seen {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// formatCPUList writes sorted CPU numbers as a list with ranges.
+func formatCPUList(cpus []int) string {
+	var parts []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(cpus[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
@@ -2,56 +2,307 @@ package processes
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
 	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
+// tableTop is the line the table header is drawn on, below the view header
+// and the filter line.
+const tableTop = 2
+
+type sortColumn int
+
+const (
+	sortPID sortColumn = iota
+	sortName
+	sortCPU
+	sortMemory
+	sortThreads
+	sortNice
+	sortStart
+	sortUser
+	sortRSS
+	sortPSS
+	sortUSS
+	sortShared
+	sortSwap
+	sortVSZ
+)
+
+var sortNames = []string{"pid", "name", "cpu", "memory", "threads", "nice", "start", "user", "rss", "pss", "uss", "shared", "swap", "vsz"}
+
+// column is a table column and the sort a click on its header selects.
+type column struct {
+	table.Column
+	sort sortColumn
+}
+
+var flatColumns = []column{
+	{table.Column{Title: "PID", Width: 8}, sortPID},
+	{table.Column{Title: "Name", Width: 20}, sortName},
+	{table.Column{Title: "CPU%", Width: 8, Right: true}, sortCPU},
+	{table.Column{Title: "Mem%", Width: 8, Right: true}, sortMemory},
+	{table.Column{Title: "Thr", Width: 5, Right: true}, sortThreads},
+	{table.Column{Title: "Nice", Width: 6, Right: true}, sortNice},
+	{table.Column{Title: "Started", Width: 9, Right: true}, sortStart},
+	{table.Column{Title: "User", Width: 0}, sortUser},
+}
+
+// The memory columns break the memory use of each process down. PSS and USS
+// show "-" for processes whose smaps_rollup cannot be read.
+var memoryColumns = []column{
+	{table.Column{Title: "PID", Width: 8}, sortPID},
+	{table.Column{Title: "Name", Width: 20}, sortName},
+	{table.Column{Title: "CPU%", Width: 8, Right: true}, sortCPU},
+	{table.Column{Title: "RSS", Width: 10, Right: true}, sortRSS},
+	{table.Column{Title: "PSS", Width: 10, Right: true}, sortPSS},
+	{table.Column{Title: "USS", Width: 10, Right: true}, sortUSS},
+	{table.Column{Title: "Shared", Width: 10, Right: true}, sortShared},
+	{table.Column{Title: "Swap", Width: 10, Right: true}, sortSwap},
+	{table.Column{Title: "VSZ", Width: 10, Right: true}, sortVSZ},
+	{table.Column{Title: "User", Width: 0}, sortUser},
+}
+
+// In tree mode the totals of each subtree are shown next to the process's
+// own usage, and siblings sort by those totals.
+var treeColumns = []column{
+	{table.Column{Title: "PID", Width: 8}, sortPID},
+	{table.Column{Title: "Name", Width: 32}, sortName},
+	{table.Column{Title: "CPU%", Width: 8, Right: true}, sortCPU},
+	{table.Column{Title: "Mem%", Width: 8, Right: true}, sortMemory},
+	{table.Column{Title: "ΣCPU%", Width: 8, Right: true}, sortCPU},
+	{table.Column{Title: "ΣMem%", Width: 8, Right: true}, sortMemory},
+	{table.Column{Title: "Thr", Width: 5, Right: true}, sortThreads},
+	{table.Column{Title: "Nice", Width: 6, Right: true}, sortNice},
+	{table.Column{Title: "Started", Width: 9, Right: true}, sortStart},
+	{table.Column{Title: "User", Width: 0}, sortUser},
+}
+
 type Model struct {
-	processes []system.ProcessInfo
-	selected  int
-	loading   bool
-	err       error
+	source     datasource.Source
+	processes  []system.ProcessInfo
+	visible    []system.ProcessInfo
+	tree       *tree
+	table      table.Model
+	treeMode   bool
+	memoryMode bool
+	collapsed  map[int32]bool
+	sortBy     sortColumn
+	reverse    bool
+	query      string
+	match      matcher
+	filterErr  error
+	filtering  bool
+	picker     *picker
+	panel      *priorityPanel
+	inspector  *inspector
+	totals     *totals
+	width      int
+	height     int
+	killGrace  time.Duration
+	message    string
+	messageErr bool
+	updated    time.Time
+	loading    bool
+	err        error
 }
 
-func New() Model {
-	return Model{loading: true}
+func New(source datasource.Source, killGrace time.Duration) Model {
+	m := Model{
+		source:    source,
+		killGrace: killGrace,
+		table:     table.New(),
+		collapsed: make(map[int32]bool),
+		loading:   true,
+	}
+	m.apply()
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return fetchProcesses
+	return nil
+}
+
+// Capturing reports whether the filter line or a dialog is taking keyboard
+// input, in which case the app must not treat keys as shortcuts.
+func (m Model) Capturing() bool {
+	return m.filtering || m.picker != nil || m.panel != nil || m.inspector != nil || m.totals != nil
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.table.SetSize(msg.Width, msg.Height-2)
+		if m.totals != nil {
+			m.totals.table.SetSize(msg.Width, msg.Height-3)
+		}
+		return m, nil
+
+	case tea.MouseMsg:
+		if m.totals != nil {
+			m.totals.table, _ = m.totals.table.Update(msg)
+			return m, nil
+		}
+		if msg.Y == tableTop && msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+			if col := m.table.ColumnAt(msg.X); col >= 0 {
+				m.sortOn(m.columns()[col].sort)
+			}
+			return m, nil
+		}
+		m.table, _ = m.table.Update(msg)
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "j", "down":
-			if m.selected < len(m.processes)-1 {
-				m.selected++
+		m.message = ""
+		if m.picker != nil {
+			if open, run := m.picker.update(msg); run {
+				return m, m.runPicker()
+			} else if !open {
+				m.picker = nil
+			}
+			return m, nil
+		}
+		if m.inspector != nil {
+			open, cmd := m.inspector.update(msg, m.source, m.height-5)
+			if !open {
+				m.inspector = nil
+			}
+			return m, cmd
+		}
+		if m.totals != nil {
+			if !m.totals.update(msg) {
+				m.totals = nil
 			}
-		case "k", "up":
-			if m.selected > 0 {
-				m.selected--
+			return m, nil
+		}
+		if m.panel != nil {
+			open, apply := m.panel.update(msg)
+			if !open {
+				m.panel = nil
+			} else if apply {
+				cmd, err := m.panel.apply(m.source)
+				m.panel.err, m.panel.applying = err, err == nil
+				return m, cmd
 			}
+			return m, nil
+		}
+		if m.filtering {
+			m.editFilter(msg)
+			return m, nil
+		}
+		switch msg.String() {
 		case "d":
-			if len(m.processes) > 0 && m.selected < len(m.processes) {
-				pid := m.processes[m.selected].PID
-				err := system.KillProcess(pid, syscall.SIGTERM)
-				if err == nil {
-					return m, fetchProcesses
-				}
+			m.openPicker(false)
+		case "D":
+			m.openPicker(true)
+		case "p":
+			return m, m.openPanel()
+		case "enter":
+			return m, m.openInspector()
+		case "t":
+			m.treeMode = !m.treeMode
+			m.apply()
+		case "m":
+			m.memoryMode = !m.memoryMode
+			m.apply()
+		case "u":
+			m.openTotals()
+		case " ":
+			if proc, ok := m.selected(); ok && m.treeMode {
+				m.collapsed[proc.PID] = !m.collapsed[proc.PID]
+				m.apply()
+			}
+		case "h", "left":
+			m.collapse()
+		case "l", "right":
+			if proc, ok := m.selected(); ok && m.treeMode {
+				delete(m.collapsed, proc.PID)
+				m.apply()
 			}
+		case "s":
+			m.sortOn((m.sortBy + 1) % sortColumn(len(sortNames)))
+		case "S":
+			m.reverse = !m.reverse
+			m.apply()
+		case "/":
+			m.filtering = true
+		case "esc":
+			m.setQuery("")
 		case "r":
-			return m, fetchProcesses
+			m.source.Refresh(system.GroupProcesses)
+		default:
+			m.table, _ = m.table.Update(msg)
 		}
 
-	case processesMsg:
-		m.processes = msg.processes
+	case *system.Snapshot:
+		if !msg.Has(system.GroupProcesses) || msg.Updated[system.GroupProcesses].Equal(m.updated) {
+			return m, nil
+		}
+		m.updated = msg.Updated[system.GroupProcesses]
+		m.processes = msg.Processes
+		m.apply()
+		if m.inspector != nil {
+			m.inspector.observe(m.processes)
+		}
+		if m.totals != nil {
+			m.totals.load(m.filtered())
+		}
 		m.loading = false
-		m.err = msg.err
+		m.err = msg.Err(system.GroupProcesses)
+		return m, nil
+
+	case detailsMsg:
+		if m.inspector != nil && m.inspector.target.pid == msg.pid {
+			m.inspector.details, m.inspector.err = msg.details, msg.err
+		}
+		return m, nil
+
+	case priorityMsg:
+		if m.panel != nil && m.panel.pid == msg.pid {
+			m.panel.load(msg)
+		}
+		return m, nil
+
+	case appliedMsg:
+		if len(msg.applied) > 0 {
+			m.source.Refresh(system.GroupProcesses)
+		}
+		panel := m.panel
+		if panel == nil || panel.pid != msg.pid {
+			// The panel was closed while applying.
+			panel = &priorityPanel{subject: fmt.Sprintf("%d", msg.pid)}
+		}
+		panel.applied(msg)
+		switch {
+		case msg.err == nil:
+			m.message, m.messageErr = "Updated priority of "+panel.subject, false
+			if panel == m.panel {
+				m.panel = nil
+			}
+		case panel != m.panel:
+			m.message, m.messageErr = fmt.Sprintf("Priority of %s: %v", panel.subject, panel.err), true
+		}
+		return m, nil
+
+	case escalateMsg:
+		if msg.source == m.source {
+			return m, m.escalate(msg)
+		}
+		return m, nil
+
+	case signalMsg:
+		if msg.source == m.source {
+			m.report(msg.sig, msg.n, msg.escalated, msg.err)
+		}
 		return m, nil
 
 	case error:
@@ -63,58 +314,356 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m Model) View() string {
-	if m.loading {
-		return "Loading processes..."
+// editFilter handles a key while the filter line has focus. The filter is
+// applied as it is typed; enter keeps it and esc clears it.
+func (m *Model) editFilter(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		m.setQuery("")
+	case tea.KeyBackspace:
+		if r := []rune(m.query); len(r) > 0 {
+			m.setQuery(string(r[:len(r)-1]))
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		m.setQuery(m.query + string(msg.Runes))
+	case tea.KeyUp, tea.KeyDown, tea.KeyPgUp, tea.KeyPgDown:
+		m.table, _ = m.table.Update(msg)
 	}
+}
 
-	if m.err != nil {
-		return fmt.Sprintf("Error: %v", m.err)
+// setQuery compiles query and applies it. An invalid query is kept on the
+// filter line with its error while the last valid filter stays in effect.
+func (m *Model) setQuery(query string) {
+	m.query = query
+	match, err := parseFilter(query)
+	m.filterErr = err
+	if err != nil {
+		return
+	}
+	m.match = match
+	m.apply()
+}
+
+// sortOn sorts by col, or reverses the order when already sorted by it.
+func (m *Model) sortOn(col sortColumn) {
+	if col == m.sortBy {
+		m.reverse = !m.reverse
+	} else {
+		m.sortBy = col
+		m.reverse = false
+	}
+	m.apply()
+}
+
+// descending reports whether a column sorts largest first before reversing.
+func descending(col sortColumn) bool {
+	switch col {
+	case sortPID, sortName, sortNice, sortUser:
+		return false
+	}
+	return true
+}
+
+// selected returns the process under the cursor.
+func (m Model) selected() (system.ProcessInfo, bool) {
+	if m.table.Cursor() < len(m.visible) {
+		return m.visible[m.table.Cursor()], true
+	}
+	return system.ProcessInfo{}, false
+}
+
+// Msg is implemented by the messages of the process view's background
+// work, which must reach it even while another view is active.
+type Msg interface {
+	isProcessesMsg()
+}
+
+// signalMsg reports the outcome of a signal sent by signal, or of the KILL
+// sent by escalate.
+type signalMsg struct {
+	source    datasource.Source
+	sig       syscall.Signal
+	n         int
+	escalated bool
+	err       error
+}
+
+func (signalMsg) isProcessesMsg() {}
+
+// signal sends sig to every pid in order in the background, so that a
+// remote source does not block the UI, and refreshes the list if any of
+// them was delivered. The signalMsg carries the first error.
+func (m Model) signal(pids []int32, sig syscall.Signal) tea.Cmd {
+	source := m.source
+	return func() tea.Msg {
+		var first error
+		sent := false
+		for _, pid := range pids {
+			if err := source.SignalProcess(pid, sig); err != nil {
+				if first == nil {
+					first = fmt.Errorf("pid %d: %w", pid, err)
+				}
+			} else {
+				sent = true
+			}
+		}
+		if sent {
+			source.Refresh(system.GroupProcesses)
+		}
+		return signalMsg{source: source, sig: sig, n: len(pids), err: first}
 	}
+}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+// collapse folds the subtree under the cursor, or moves to the parent when
+// there is nothing to fold.
+func (m *Model) collapse() {
+	proc, ok := m.selected()
+	if !ok || !m.treeMode {
+		return
+	}
+	i := m.tree.byPID[proc.PID]
+	if m.tree.hasChildren(i) && !m.collapsed[proc.PID] {
+		m.collapsed[proc.PID] = true
+		m.apply()
+		return
+	}
+	if parent := m.tree.parent(i); parent >= 0 {
+		for row, p := range m.visible {
+			if p.PID == m.tree.procs[parent].PID {
+				m.table.SetCursor(row)
+				break
+			}
+		}
+	}
+}
 
-	header := headerStyle.Render("Processes (j/k: navigate, d: kill, r: refresh)")
+func (m Model) columns() []column {
+	switch {
+	case m.treeMode:
+		return treeColumns
+	case m.memoryMode:
+		return memoryColumns
+	}
+	return flatColumns
+}
 
-	var lines []string
-	lines = append(lines, header, "")
+// filtered returns the processes that match the filter.
+func (m Model) filtered() []system.ProcessInfo {
+	if m.match == nil {
+		return m.processes
+	}
+	var procs []system.ProcessInfo
+	for i := range m.processes {
+		if m.match(&m.processes[i]) {
+			procs = append(procs, m.processes[i])
+		}
+	}
+	return procs
+}
 
-	headerRow := fmt.Sprintf("%-8s %-20s %8s %8s %-12s",
-		"PID", "Name", "CPU%", "Mem%", "User")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
+// apply filters and sorts the processes into the table, keeping the cursor
+// on the same process when it is still shown.
+func (m *Model) apply() {
+	selected, hasSelected := m.selected()
+
+	m.tree = buildTree(m.processes)
+	procs, totals := m.tree.procs, m.tree.totals
+
+	less := func(i, j int) bool {
+		if m.reverse {
+			i, j = j, i
+		}
+		a, b := &procs[i], &procs[j]
+		switch m.sortBy {
+		case sortName:
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+		case sortCPU:
+			x, y := a.CPUPercent, b.CPUPercent
+			if m.treeMode {
+				x, y = totals[i].cpu, totals[j].cpu
+			}
+			if x != y {
+				return x > y
+			}
+		case sortMemory:
+			x, y := a.MemPercent, b.MemPercent
+			if m.treeMode {
+				x, y = totals[i].mem, totals[j].mem
+			}
+			if x != y {
+				return x > y
+			}
+		case sortThreads:
+			if a.Threads != b.Threads {
+				return a.Threads > b.Threads
+			}
+		case sortNice:
+			if a.Nice != b.Nice {
+				return a.Nice < b.Nice
+			}
+		case sortStart:
+			if a.CreateTime != b.CreateTime {
+				return a.CreateTime > b.CreateTime
+			}
+		case sortUser:
+			if a.User != b.User {
+				return a.User < b.User
+			}
+		default:
+			if x, y := memoryValue(a, m.sortBy), memoryValue(b, m.sortBy); x != y {
+				return x > y
+			}
+		}
+		return a.PID < b.PID
+	}
+	keep := func(i int) bool {
+		return m.match == nil || m.match(&procs[i])
+	}
 
-	for i, proc := range m.processes {
-		style := lipgloss.NewStyle()
-		if i == m.selected {
-			style = style.Background(lipgloss.Color("63")).Foreground(lipgloss.Color("230"))
+	var rows []table.Row
+	m.visible = make([]system.ProcessInfo, 0, len(procs))
+	if m.treeMode {
+		for _, node := range m.tree.flatten(less, keep, m.collapsed) {
+			m.visible = append(m.visible, procs[node.index])
+			rows = append(rows, m.row(node.index, node.label))
+		}
+	} else {
+		var indexes []int
+		for i := range procs {
+			if keep(i) {
+				indexes = append(indexes, i)
+			}
 		}
+		sort.SliceStable(indexes, func(a, b int) bool { return less(indexes[a], indexes[b]) })
+		for _, i := range indexes {
+			m.visible = append(m.visible, procs[i])
+			rows = append(rows, m.row(i, procs[i].Name))
+		}
+	}
 
-		name := proc.Name
-		if len(name) > 20 {
-			name = name[:17] + "..."
+	arrow := " ▲"
+	if descending(m.sortBy) != m.reverse {
+		arrow = " ▼"
+	}
+	var cols []table.Column
+	for _, col := range m.columns() {
+		if col.sort == m.sortBy {
+			col.Title += arrow
 		}
+		cols = append(cols, col.Column)
+	}
+	m.table.SetColumns(cols...)
 
-		line := fmt.Sprintf("%-8d %-20s %8.2f %8.2f %-12s",
-			proc.PID, name, proc.CPUPercent, proc.MemPercent, proc.User)
-		lines = append(lines, style.Render(line))
+	m.table.SetRows(rows)
+	if hasSelected {
+		for i, proc := range m.visible {
+			if proc.PID == selected.PID {
+				m.table.SetCursor(i)
+				break
+			}
+		}
 	}
+}
 
-	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+// row formats the process at index i of the tree, with name as drawn.
+func (m Model) row(i int, name string) table.Row {
+	proc := m.tree.procs[i]
+	if m.memoryMode && !m.treeMode {
+		mem := proc.Memory
+		return table.Row{
+			fmt.Sprint(proc.PID),
+			name,
+			fmt.Sprintf("%.2f", proc.CPUPercent),
+			formatBytes(mem.RSS),
+			rollupBytes(mem, mem.PSS),
+			rollupBytes(mem, mem.USS),
+			rollupBytes(mem, mem.Shared),
+			rollupBytes(mem, mem.Swap),
+			formatBytes(mem.VSZ),
+			proc.User,
+		}
+	}
+	row := table.Row{
+		fmt.Sprint(proc.PID),
+		name,
+		fmt.Sprintf("%.2f", proc.CPUPercent),
+		fmt.Sprintf("%.2f", proc.MemPercent),
+	}
+	if m.treeMode {
+		row = append(row,
+			fmt.Sprintf("%.2f", m.tree.totals[i].cpu),
+			fmt.Sprintf("%.2f", m.tree.totals[i].mem))
+	}
+	return append(row,
+		fmt.Sprint(proc.Threads),
+		fmt.Sprint(proc.Nice),
+		formatStart(proc.CreateTime),
+		proc.User)
 }
 
-type processesMsg struct {
-	processes []system.ProcessInfo
-	err       error
+func (m Model) View() string {
+	if m.loading {
+		return "Loading processes..."
+	}
+
+	if m.err != nil {
+		return fmt.Sprintf("Error: %v", m.err)
+	}
+
+	t := theme.Current()
+
+	if m.inspector != nil {
+		return m.inspector.view(m.width, m.height)
+	}
+
+	header := t.Header.Render(fmt.Sprintf(
+		"Processes (%d/%d) (j/k/pgup/pgdn: navigate, s/S: sort, /: filter, t: tree, m: memory, u: totals, d/D: signal process/tree, enter: inspect, p: priority, r: refresh)",
+		len(m.visible), len(m.processes)))
+
+	filter := ""
+	switch {
+	case m.message != "" && m.messageErr:
+		filter = t.Critical.Render(m.message)
+	case m.message != "":
+		filter = t.OK.Render(m.message)
+	case m.filterErr != nil:
+		filter = "/" + m.query + "  " + t.Critical.Render(m.filterErr.Error())
+	case m.filtering:
+		filter = "/" + m.query + t.Selection.Render(" ")
+	case m.query != "":
+		filter = t.Muted.Render("filter: " + strings.TrimSpace(m.query) + " (/: edit, esc: clear)")
+	case m.treeMode:
+		filter = t.Muted.Render("tree: space: fold/unfold, h/l: collapse/expand")
+	}
+
+	if m.picker != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, header, filter, m.picker.view(m.killGrace))
+	}
+	if m.panel != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, header, filter, m.panel.view())
+	}
+	if m.totals != nil {
+		return lipgloss.JoinVertical(lipgloss.Left, header, filter, m.totals.view())
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, header, filter, m.table.View())
 }
 
-func fetchProcesses() tea.Msg {
-	processes, err := system.GetProcesses()
-	return processesMsg{
-		processes: processes,
-		err:       err,
+// formatStart shows the time of day for processes started today and the
+// date for older ones. createTime is in milliseconds since the epoch.
+func formatStart(createTime int64) string {
+	if createTime <= 0 {
+		return "-"
+	}
+	start := time.UnixMilli(createTime)
+	now := time.Now()
+	if start.YearDay() == now.YearDay() && start.Year() == now.Year() {
+		return start.Format("15:04:05")
 	}
+	return start.Format("Jan 02")
 }
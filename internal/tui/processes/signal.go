@@ -0,0 +1,282 @@
+package processes
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
+	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+type signalChoice struct {
+	label    string
+	sig      syscall.Signal
+	escalate bool
+	custom   bool
+}
+
+var signalChoices = []signalChoice{
+	{label: "TERM  ask to terminate", sig: syscall.SIGTERM},
+	{label: "KILL  terminate immediately", sig: syscall.SIGKILL},
+	{label: "HUP   hang up / reload", sig: syscall.SIGHUP},
+	{label: "INT   interrupt", sig: syscall.SIGINT},
+	{label: "STOP  pause", sig: syscall.SIGSTOP},
+	{label: "CONT  resume", sig: syscall.SIGCONT},
+	{label: "USR1", sig: syscall.SIGUSR1},
+	{label: "USR2", sig: syscall.SIGUSR2},
+	{label: "TERM, then KILL if still running", sig: syscall.SIGTERM, escalate: true},
+	{label: "Other signal number...", custom: true},
+}
+
+// target is a process to signal. The start time tells it apart from a later
+// process that reuses the PID.
+type target struct {
+	pid        int32
+	createTime int64
+}
+
+// picker is the signal menu and its confirmation step.
+type picker struct {
+	choices  []signalChoice
+	targets  []target
+	subject  string
+	cursor   int
+	number   string
+	editing  bool
+	confirm  bool
+	sig      syscall.Signal
+	escalate bool
+	err      error
+}
+
+// escalateMsg fires when the grace period after an escalating TERM is over.
+type escalateMsg struct {
+	source  datasource.Source
+	targets []target
+}
+
+func (escalateMsg) isProcessesMsg() {}
+
+// startedSignaler is implemented by sources that can check that a PID still
+// belongs to the same process when signalling it. Escalating to KILL is
+// only offered for them: a remote host would need the check and the signal
+// in one request, and its server does not allow KILL by default.
+type startedSignaler interface {
+	SignalProcessStartedAt(pid int32, sig syscall.Signal, createTime int64) error
+}
+
+func newPicker(targets []target, subject string, escalate bool) *picker {
+	choices := signalChoices
+	if !escalate {
+		choices = nil
+		for _, choice := range signalChoices {
+			if !choice.escalate {
+				choices = append(choices, choice)
+			}
+		}
+	}
+	return &picker{choices: choices, targets: targets, subject: subject}
+}
+
+// update handles a key in the picker. It returns whether the picker stays
+// open and, once confirmed, the choice to carry out.
+func (p *picker) update(msg tea.KeyMsg) (open, run bool) {
+	key := msg.String()
+
+	switch {
+	case p.confirm:
+		switch key {
+		case "y", "Y", "enter":
+			return false, true
+		case "n", "N", "esc":
+			p.confirm = false
+		}
+
+	case p.editing:
+		switch msg.Type {
+		case tea.KeyEnter:
+			sig, err := system.ParseSignal(p.number)
+			if err != nil {
+				p.err = err
+				return true, false
+			}
+			p.sig, p.escalate, p.confirm, p.editing = sig, false, true, false
+		case tea.KeyEsc:
+			p.editing = false
+		case tea.KeyBackspace:
+			if len(p.number) > 0 {
+				p.number = p.number[:len(p.number)-1]
+			}
+		case tea.KeyRunes:
+			for _, r := range msg.Runes {
+				if r >= '0' && r <= '9' {
+					p.number += string(r)
+				}
+			}
+		}
+		p.err = nil
+
+	default:
+		switch key {
+		case "j", "down":
+			if p.cursor < len(p.choices)-1 {
+				p.cursor++
+			}
+		case "k", "up":
+			if p.cursor > 0 {
+				p.cursor--
+			}
+		case "enter":
+			choice := p.choices[p.cursor]
+			if choice.custom {
+				p.editing = true
+				return true, false
+			}
+			p.sig, p.escalate, p.confirm = choice.sig, choice.escalate, true
+		case "esc", "q":
+			return false, false
+		}
+	}
+	return true, false
+}
+
+func (p *picker) view(grace time.Duration) string {
+	t := theme.Current()
+
+	var lines []string
+	lines = append(lines, t.Title.Render("Send signal to "+p.subject), "")
+
+	switch {
+	case p.confirm:
+		action := "Send " + system.SignalName(p.sig)
+		if p.escalate {
+			action = fmt.Sprintf("Send TERM, then KILL after %s,", grace)
+		}
+		lines = append(lines, t.Warning.Render(action+" to "+p.subject+"?"), "", "y: confirm, n: back")
+
+	case p.editing:
+		lines = append(lines, "Signal number: "+p.number+t.Selection.Render(" "))
+		if p.err != nil {
+			lines = append(lines, t.Critical.Render(p.err.Error()))
+		}
+		lines = append(lines, "", "enter: continue, esc: back")
+
+	default:
+		for i, choice := range p.choices {
+			label := choice.label
+			if choice.escalate {
+				label = fmt.Sprintf("TERM, then KILL after %s if still running", grace)
+			}
+			if i == p.cursor {
+				label = t.Selection.Render("> " + label)
+			} else {
+				label = "  " + label
+			}
+			lines = append(lines, label)
+		}
+		lines = append(lines, "", "j/k: choose, enter: select, esc: cancel")
+	}
+
+	return t.Box.Copy().Padding(1, 2).Render(lipgloss.JoinVertical(lipgloss.Left, lines...))
+}
+
+// openPicker opens the signal menu for the process under the cursor, or
+// for it and all its descendants.
+func (m *Model) openPicker(subtree bool) {
+	proc, ok := m.selected()
+	if !ok {
+		return
+	}
+
+	pids := []int32{proc.PID}
+	subject := fmt.Sprintf("%d (%s)", proc.PID, proc.Name)
+	if subtree {
+		pids = m.tree.descendants(proc.PID)
+		switch n := len(pids) - 1; {
+		case n == 1:
+			subject += " and 1 descendant"
+		case n > 1:
+			subject += fmt.Sprintf(" and %d descendants", n)
+		}
+	}
+
+	targets := make([]target, len(pids))
+	for i, pid := range pids {
+		targets[i] = target{pid: pid, createTime: m.tree.procs[m.tree.byPID[pid]].CreateTime}
+	}
+	_, escalate := m.source.(startedSignaler)
+	m.picker = newPicker(targets, subject, escalate)
+}
+
+// runPicker sends the confirmed signal. An escalating TERM schedules an
+// escalateMsg after the grace period.
+func (m *Model) runPicker() tea.Cmd {
+	p := m.picker
+	m.picker = nil
+
+	pids := make([]int32, len(p.targets))
+	for i, t := range p.targets {
+		pids[i] = t.pid
+	}
+	send := m.signal(pids, p.sig)
+
+	if !p.escalate {
+		return send
+	}
+	msg := escalateMsg{source: m.source, targets: p.targets}
+	return tea.Batch(send, tea.Tick(m.killGrace, func(time.Time) tea.Msg { return msg }))
+}
+
+// escalate sends KILL to the targets that are still running. Each start
+// time is read again just before, so a PID reused since the process list
+// was last sampled is skipped.
+func (m *Model) escalate(msg escalateMsg) tea.Cmd {
+	source := msg.source
+	signaler := source.(startedSignaler)
+	return func() tea.Msg {
+		var first error
+		n := 0
+		for _, t := range msg.targets {
+			err := signaler.SignalProcessStartedAt(t.pid, syscall.SIGKILL, t.createTime)
+			switch {
+			case errors.Is(err, system.ErrProcessGone):
+			case err != nil:
+				if first == nil {
+					first = fmt.Errorf("pid %d: %w", t.pid, err)
+				}
+			default:
+				n++
+			}
+		}
+		if n > 0 {
+			source.Refresh(system.GroupProcesses)
+		}
+		return signalMsg{source: source, sig: syscall.SIGKILL, n: n, escalated: true, err: first}
+	}
+}
+
+// report sets the status line after sending sig to n processes, or
+// escalating to it.
+func (m *Model) report(sig syscall.Signal, n int, escalated bool, err error) {
+	if escalated && n == 0 && err == nil {
+		m.message = "All processes exited after TERM"
+		m.messageErr = false
+		return
+	}
+	if err != nil {
+		m.message = fmt.Sprintf("%s: %v", system.SignalName(sig), err)
+		m.messageErr = true
+		return
+	}
+	noun := "processes"
+	if n == 1 {
+		noun = "process"
+	}
+	m.message = fmt.Sprintf("Sent %s to %d %s", system.SignalName(sig), n, noun)
+	m.messageErr = false
+}
@@ -0,0 +1,200 @@
+package processes
+
+import (
+	"sort"
+
+	"github.com/guicybercode/systui/internal/system"
+)
+
+// subtree holds the usage of a process together with all its descendants.
+type subtree struct {
+	cpu float64
+	mem float32
+}
+
+// tree links processes to their children by PPID. Processes whose parent is
+// not in the list are roots. Indexes refer to the slice the tree was built
+// from.
+type tree struct {
+	procs    []system.ProcessInfo
+	children [][]int
+	roots    []int
+	totals   []subtree
+	byPID    map[int32]int
+}
+
+func buildTree(procs []system.ProcessInfo) *tree {
+	t := &tree{
+		procs:    procs,
+		children: make([][]int, len(procs)),
+		totals:   make([]subtree, len(procs)),
+		byPID:    make(map[int32]int, len(procs)),
+	}
+	for i, p := range procs {
+		t.byPID[p.PID] = i
+	}
+
+	hasParent := make([]bool, len(procs))
+	for i, p := range procs {
+		if parent, ok := t.byPID[p.PPID]; ok && p.PPID != p.PID {
+			t.children[parent] = append(t.children[parent], i)
+			hasParent[i] = true
+		}
+	}
+	for i := range procs {
+		if !hasParent[i] {
+			t.roots = append(t.roots, i)
+		}
+	}
+
+	// PID reuse between reads can leave a parent loop with no root. Walking
+	// from the roots and promoting whatever was not reached keeps every
+	// process in the tree.
+	seen := make([]bool, len(procs))
+	for _, root := range t.roots {
+		t.sum(root, seen)
+	}
+	for i := range procs {
+		if !seen[i] {
+			t.roots = append(t.roots, i)
+			t.sum(i, seen)
+		}
+	}
+	return t
+}
+
+// sum fills in the totals of the subtree rooted at i.
+func (t *tree) sum(i int, seen []bool) subtree {
+	seen[i] = true
+	total := subtree{cpu: t.procs[i].CPUPercent, mem: t.procs[i].MemPercent}
+	for _, child := range t.children[i] {
+		if seen[child] {
+			continue
+		}
+		s := t.sum(child, seen)
+		total.cpu += s.cpu
+		total.mem += s.mem
+	}
+	t.totals[i] = total
+	return total
+}
+
+// descendants returns the PIDs below pid, deepest first, followed by pid.
+func (t *tree) descendants(pid int32) []int32 {
+	start, ok := t.byPID[pid]
+	if !ok {
+		return nil
+	}
+	var pids []int32
+	seen := make(map[int]bool)
+	var walk func(i int)
+	walk = func(i int) {
+		seen[i] = true
+		for _, child := range t.children[i] {
+			if !seen[child] {
+				walk(child)
+			}
+		}
+		pids = append(pids, t.procs[i].PID)
+	}
+	walk(start)
+	return pids
+}
+
+// treeRow is a process as placed in the drawn tree.
+type treeRow struct {
+	index int
+	label string
+}
+
+// flatten orders the tree for display. Siblings are sorted with less, nodes
+// that fail keep are dropped unless a descendant passes, and the children of
+// collapsed nodes are hidden.
+func (t *tree) flatten(less func(a, b int) bool, keep func(i int) bool, collapsed map[int32]bool) []treeRow {
+	shown := make([]bool, len(t.procs))
+	seen := make([]bool, len(t.procs))
+	var mark func(i int) bool
+	mark = func(i int) bool {
+		seen[i] = true
+		shown[i] = keep == nil || keep(i)
+		for _, child := range t.children[i] {
+			if !seen[child] && mark(child) {
+				shown[i] = true
+			}
+		}
+		return shown[i]
+	}
+	for _, root := range t.roots {
+		if !seen[root] {
+			mark(root)
+		}
+	}
+
+	sorted := func(indexes []int) []int {
+		var out []int
+		for _, i := range indexes {
+			if shown[i] {
+				out = append(out, i)
+			}
+		}
+		sort.SliceStable(out, func(a, b int) bool { return less(out[a], out[b]) })
+		return out
+	}
+
+	var rows []treeRow
+	placed := make([]bool, len(t.procs))
+	var walk func(i int, indent string, branch string)
+	walk = func(i int, indent, branch string) {
+		placed[i] = true
+		children := sorted(t.children[i])
+
+		folded := len(children) > 0 && collapsed[t.procs[i].PID]
+		label := t.procs[i].Name
+		switch {
+		case branch != "" && folded:
+			label = indent + branch + "+ " + label
+		case branch != "":
+			label = indent + branch + "─ " + label
+		case folded:
+			label = "+ " + label
+		}
+		rows = append(rows, treeRow{index: i, label: label})
+		if folded {
+			return
+		}
+
+		if branch == "├─" {
+			indent += "│   "
+		} else if branch == "└─" {
+			indent += "    "
+		}
+		for n, child := range children {
+			if placed[child] {
+				continue
+			}
+			if n == len(children)-1 {
+				walk(child, indent, "└─")
+			} else {
+				walk(child, indent, "├─")
+			}
+		}
+	}
+	for _, root := range sorted(t.roots) {
+		walk(root, "", "")
+	}
+	return rows
+}
+
+// hasChildren reports whether the process at i has any children.
+func (t *tree) hasChildren(i int) bool {
+	return len(t.children[i]) > 0
+}
+
+// parent returns the index of the parent of i, or -1 for roots.
+func (t *tree) parent(i int) int {
+	p := t.procs[i]
+	if parent, ok := t.byPID[p.PPID]; ok && p.PPID != p.PID {
+		return parent
+	}
+	return -1
+}
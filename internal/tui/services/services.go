@@ -2,71 +2,99 @@ package services
 
 import (
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/datasource"
 	"github.com/guicybercode/systui/internal/system"
+	"github.com/guicybercode/systui/internal/tui/table"
+	"github.com/guicybercode/systui/internal/tui/theme"
 )
 
 type Model struct {
+	source   datasource.Source
 	services []system.ServiceInfo
-	selected int
+	table    table.Model
+	updated  time.Time
 	loading  bool
 	err      error
+	// message reports the last start, stop or restart.
+	message    string
+	messageErr bool
 }
 
-func New() Model {
-	return Model{loading: true}
+// Msg is implemented by the messages of the service view's background
+// work, which must reach it even while another view is active.
+type Msg interface {
+	isServicesMsg()
+}
+
+// controlMsg reports the outcome of a unit action run by control.
+type controlMsg struct {
+	source  datasource.Source
+	message string
+	err     bool
+}
+
+func (controlMsg) isServicesMsg() {}
+
+func New(source datasource.Source) Model {
+	return Model{
+		source: source,
+		table: table.New(
+			table.Column{Title: "Name", Width: 35},
+			table.Column{Title: "State", Width: 12},
+			table.Column{Title: "Description", Width: 0},
+		),
+		loading: true,
+	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return fetchServices
+	return nil
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.table.SetSize(msg.Width, msg.Height-2)
+		return m, nil
+
+	case tea.MouseMsg:
+		m.table, _ = m.table.Update(msg)
+		return m, nil
+
 	case tea.KeyMsg:
+		m.message = ""
 		switch msg.String() {
-		case "j", "down":
-			if m.selected < len(m.services)-1 {
-				m.selected++
-			}
-		case "k", "up":
-			if m.selected > 0 {
-				m.selected--
-			}
 		case "s":
-			if len(m.services) > 0 && m.selected < len(m.services) {
-				name := m.services[m.selected].Name
-				err := system.StartService(name)
-				if err == nil {
-					return m, fetchServices
-				}
-			}
+			return m, m.control("start", "Started", m.source.StartService)
 		case "x":
-			if len(m.services) > 0 && m.selected < len(m.services) {
-				name := m.services[m.selected].Name
-				err := system.StopService(name)
-				if err == nil {
-					return m, fetchServices
-				}
-			}
+			return m, m.control("stop", "Stopped", m.source.StopService)
 		case "t":
-			if len(m.services) > 0 && m.selected < len(m.services) {
-				name := m.services[m.selected].Name
-				err := system.RestartService(name)
-				if err == nil {
-					return m, fetchServices
-				}
-			}
+			return m, m.control("restart", "Restarted", m.source.RestartService)
 		case "r":
-			return m, fetchServices
+			m.source.Refresh(system.GroupServices)
+		default:
+			m.table, _ = m.table.Update(msg)
 		}
 
-	case servicesMsg:
-		m.services = msg.services
+	case *system.Snapshot:
+		if !msg.Has(system.GroupServices) || msg.Updated[system.GroupServices].Equal(m.updated) {
+			return m, nil
+		}
+		m.updated = msg.Updated[system.GroupServices]
+		m.services = msg.Services
+		m.table.SetRows(serviceRows(m.services))
 		m.loading = false
-		m.err = msg.err
+		m.err = msg.Err(system.GroupServices)
+		return m, nil
+
+	case controlMsg:
+		if msg.source == m.source {
+			m.message, m.messageErr = msg.message, msg.err
+		}
 		return m, nil
 
 	case error:
@@ -87,54 +115,42 @@ func (m Model) View() string {
 		return fmt.Sprintf("Error: %v", m.err)
 	}
 
-	headerStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("63")).
-		Padding(0, 1)
+	t := theme.Current()
 
-	header := headerStyle.Render("Services (j/k: navigate, s: start, x: stop, t: restart, r: refresh)")
+	header := t.Header.Render("Services (j/k/pgup/pgdn: navigate, s: start, x: stop, t: restart, r: refresh)")
 
-	var lines []string
-	lines = append(lines, header, "")
-
-	headerRow := fmt.Sprintf("%-35s %-12s %s",
-		"Name", "State", "Description")
-	lines = append(lines, headerRow)
-	lines = append(lines, "")
-
-	for i, svc := range m.services {
-		style := lipgloss.NewStyle()
-		if i == m.selected {
-			style = style.Background(lipgloss.Color("63")).Foreground(lipgloss.Color("230"))
-		}
-
-		name := svc.Name
-		if len(name) > 35 {
-			name = name[:32] + "..."
-		}
-
-		desc := svc.Description
-		if len(desc) > 40 {
-			desc = desc[:37] + "..."
-		}
-
-		line := fmt.Sprintf("%-35s %-12s %s",
-			name, svc.State, desc)
-		lines = append(lines, style.Render(line))
+	status := ""
+	switch {
+	case m.message != "" && m.messageErr:
+		status = t.Critical.Render(m.message)
+	case m.message != "":
+		status = t.OK.Render(m.message)
 	}
 
-	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+	return lipgloss.JoinVertical(lipgloss.Left, header, status, m.table.View())
 }
 
-type servicesMsg struct {
-	services []system.ServiceInfo
-	err      error
+// control runs action on the unit under the cursor in the background, so
+// that a remote source does not block the UI, and refreshes the list once
+// it succeeds.
+func (m Model) control(action, done string, run func(name string) error) tea.Cmd {
+	if m.table.Cursor() >= len(m.services) {
+		return nil
+	}
+	source, name := m.source, m.services[m.table.Cursor()].Name
+	return func() tea.Msg {
+		if err := run(name); err != nil {
+			return controlMsg{source: source, message: fmt.Sprintf("%s %s: %v", action, name, err), err: true}
+		}
+		source.Refresh(system.GroupServices)
+		return controlMsg{source: source, message: done + " " + name}
+	}
 }
 
-func fetchServices() tea.Msg {
-	services, err := system.GetServices()
-	return servicesMsg{
-		services: services,
-		err:      err,
+func serviceRows(services []system.ServiceInfo) []table.Row {
+	rows := make([]table.Row, len(services))
+	for i, svc := range services {
+		rows[i] = table.Row{svc.Name, svc.State, svc.Description}
 	}
+	return rows
 }
@@ -0,0 +1,287 @@
+package table
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/tui/theme"
+)
+
+// Column describes one table column. Cells wider than Width are truncated.
+// A Width of 0 makes the column take whatever space the others leave.
+type Column struct {
+	Title string
+	Width int
+	Right bool
+}
+
+type Row []string
+
+// Model is a scrollable table that only renders the rows that fit in its
+// height, keeping the cursor row in view.
+type Model struct {
+	columns   []Column
+	rows      []Row
+	styleFunc func(row int) lipgloss.Style
+	cursor    int
+	offset    int
+	width     int
+	height    int
+	blurred   bool
+
+	// highlight marks its matches in the cells of column highlightCol.
+	highlight    *regexp.Regexp
+	highlightCol int
+}
+
+const minFlexWidth = 10
+
+func New(columns ...Column) Model {
+	return Model{columns: columns, width: 80, height: 20}
+}
+
+func (m *Model) SetColumns(columns ...Column) {
+	m.columns = columns
+}
+
+// SetRows replaces the rows, keeping the cursor position when possible.
+func (m *Model) SetRows(rows []Row) {
+	m.rows = rows
+	m.SetCursor(m.cursor)
+}
+
+// SetSize sets the space available to the table, including its header and
+// scroll indicator lines.
+func (m *Model) SetSize(width, height int) {
+	m.width = width
+	m.height = height
+	m.SetCursor(m.cursor)
+}
+
+// SetStyleFunc sets the style of rows other than the cursor row.
+func (m *Model) SetStyleFunc(f func(row int) lipgloss.Style) {
+	m.styleFunc = f
+}
+
+// SetHighlight marks the matches of re in column col, as shown after
+// truncation. A nil re removes the marks.
+func (m *Model) SetHighlight(col int, re *regexp.Regexp) {
+	m.highlightCol = col
+	m.highlight = re
+}
+
+func (m *Model) Focus() {
+	m.blurred = false
+}
+
+// Blur hides the cursor highlight, for tables that share a view with a
+// focused one.
+func (m *Model) Blur() {
+	m.blurred = true
+}
+
+func (m Model) Len() int {
+	return len(m.rows)
+}
+
+func (m Model) Cursor() int {
+	return m.cursor
+}
+
+// SetCursor moves the cursor to row i, clamped to the table, and scrolls
+// the minimum needed to show it.
+func (m *Model) SetCursor(i int) {
+	if i >= len(m.rows) {
+		i = len(m.rows) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	m.cursor = i
+
+	visible := m.visibleRows()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+visible {
+		m.offset = m.cursor - visible + 1
+	}
+	if last := len(m.rows) - visible; m.offset > last {
+		m.offset = last
+	}
+	if m.offset < 0 {
+		m.offset = 0
+	}
+}
+
+func (m Model) visibleRows() int {
+	// One line for the header and one for the scroll indicator.
+	if n := m.height - 2; n > 1 {
+		return n
+	}
+	return 1
+}
+
+// ColumnAt returns the index of the column under x, counted from the left
+// edge of the table, or -1 when x falls outside every column.
+func (m Model) ColumnAt(x int) int {
+	start := 0
+	for i, width := range m.columnWidths() {
+		if x >= start && x < start+width {
+			return i
+		}
+		start += width + 1
+	}
+	return -1
+}
+
+func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.MouseMsg:
+		switch msg.Button {
+		case tea.MouseButtonWheelDown:
+			m.SetCursor(m.cursor + 1)
+		case tea.MouseButtonWheelUp:
+			m.SetCursor(m.cursor - 1)
+		}
+	case tea.KeyMsg:
+		page := m.visibleRows()
+		switch msg.String() {
+		case "j", "down":
+			m.SetCursor(m.cursor + 1)
+		case "k", "up":
+			m.SetCursor(m.cursor - 1)
+		case "pgdown", "ctrl+f":
+			m.SetCursor(m.cursor + page)
+		case "pgup", "ctrl+b":
+			m.SetCursor(m.cursor - page)
+		case "ctrl+d":
+			m.SetCursor(m.cursor + page/2)
+		case "ctrl+u":
+			m.SetCursor(m.cursor - page/2)
+		case "home", "g":
+			m.SetCursor(0)
+		case "end", "G":
+			m.SetCursor(len(m.rows) - 1)
+		}
+	}
+	return m, nil
+}
+
+func (m Model) View() string {
+	t := theme.Current()
+	widths := m.columnWidths()
+
+	lines := make([]string, 0, m.visibleRows()+2)
+	titles := make(Row, len(m.columns))
+	for i, col := range m.columns {
+		titles[i] = col.Title
+	}
+	lines = append(lines, t.Title.Render(m.renderRow(titles, widths, nil)))
+
+	end := m.offset + m.visibleRows()
+	if end > len(m.rows) {
+		end = len(m.rows)
+	}
+	for i := m.offset; i < end; i++ {
+		style := lipgloss.NewStyle()
+		if m.styleFunc != nil {
+			style = m.styleFunc(i)
+		}
+		if i == m.cursor && !m.blurred {
+			style = t.Selection
+		}
+		lines = append(lines, m.renderRow(m.rows[i], widths, &style))
+	}
+
+	if len(m.rows) > m.visibleRows() {
+		lines = append(lines, t.Muted.Render(fmt.Sprintf("%d-%d of %d", m.offset+1, end, len(m.rows))))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m Model) columnWidths() []int {
+	widths := make([]int, len(m.columns))
+	used, flex := -1, 0
+	for i, col := range m.columns {
+		widths[i] = col.Width
+		used += col.Width + 1
+		if col.Width == 0 {
+			flex++
+		}
+	}
+	if flex == 0 {
+		return widths
+	}
+
+	share := (m.width - used) / flex
+	if share < minFlexWidth {
+		share = minFlexWidth
+	}
+	for i, col := range m.columns {
+		if col.Width == 0 {
+			widths[i] = share
+		}
+	}
+	return widths
+}
+
+// renderRow lays out row in the column widths. With a style, the row is
+// rendered in it and the matches of the highlight are marked; without one
+// it is returned as plain text.
+func (m Model) renderRow(row Row, widths []int, style *lipgloss.Style) string {
+	var out, b strings.Builder
+	for i, col := range m.columns {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		cell := ""
+		if i < len(row) {
+			cell = Truncate(row[i], widths[i])
+		}
+		pad := strings.Repeat(" ", widths[i]-len([]rune(cell)))
+		if col.Right {
+			b.WriteString(pad)
+			pad = ""
+		}
+		if style != nil && m.highlight != nil && i == m.highlightCol {
+			last := 0
+			for _, match := range m.highlight.FindAllStringIndex(cell, -1) {
+				if match[0] == match[1] {
+					continue
+				}
+				b.WriteString(cell[last:match[0]])
+				out.WriteString(style.Render(b.String()))
+				b.Reset()
+				out.WriteString(style.Copy().Reverse(true).Render(cell[match[0]:match[1]]))
+				last = match[1]
+			}
+			cell = cell[last:]
+		}
+		b.WriteString(cell)
+		if i < len(m.columns)-1 {
+			b.WriteString(pad)
+		}
+	}
+	if style == nil {
+		return b.String()
+	}
+	out.WriteString(style.Render(b.String()))
+	return out.String()
+}
+
+// Truncate shortens s to width runes, marking the cut with "...".
+func Truncate(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 3 {
+		return string(r[:width])
+	}
+	return string(r[:width-3]) + "..."
+}
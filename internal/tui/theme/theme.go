@@ -0,0 +1,175 @@
+package theme
+
+import (
+	"os"
+	"reflect"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guicybercode/systui/internal/config"
+	"github.com/muesli/termenv"
+)
+
+// builtins are the palettes of the built-in themes. A palette sets every
+// colour slot of config.Colors; an empty slot leaves the terminal default.
+var builtins = map[string]config.Colors{
+	"default": {
+		Accent:      "63",
+		SelectionFg: "230",
+		SelectionBg: "63",
+		Muted:       "241",
+		Info:        "39",
+		Warning:     "220",
+		Critical:    "196",
+		OK:          "42",
+	},
+	"dark": {
+		Accent:      "#7aa2f7",
+		SelectionFg: "#1a1b26",
+		SelectionBg: "#7aa2f7",
+		Border:      "#3b4261",
+		Muted:       "#565f89",
+		Info:        "#7dcfff",
+		Warning:     "#e0af68",
+		Critical:    "#f7768e",
+		OK:          "#9ece6a",
+	},
+	"light": {
+		Accent:      "25",
+		SelectionFg: "231",
+		SelectionBg: "25",
+		Border:      "250",
+		Muted:       "244",
+		Info:        "31",
+		Warning:     "130",
+		Critical:    "160",
+		OK:          "28",
+	},
+	"high-contrast": {
+		Accent:      "15",
+		SelectionFg: "0",
+		SelectionBg: "11",
+		Border:      "15",
+		Muted:       "7",
+		Info:        "14",
+		Warning:     "11",
+		Critical:    "9",
+		OK:          "10",
+	},
+}
+
+// Theme holds the semantic styles shared by every view.
+type Theme struct {
+	Name string
+
+	Header    lipgloss.Style
+	Title     lipgloss.Style
+	Selection lipgloss.Style
+	Active    lipgloss.Style
+	Box       lipgloss.Style
+	Muted     lipgloss.Style
+	Info      lipgloss.Style
+	Warning   lipgloss.Style
+	Critical  lipgloss.Style
+	OK        lipgloss.Style
+}
+
+func New(name string, p config.Colors) *Theme {
+	fg := func(color string) lipgloss.Style {
+		style := lipgloss.NewStyle()
+		if color != "" {
+			style = style.Foreground(lipgloss.Color(color))
+		}
+		return style
+	}
+
+	t := &Theme{
+		Name:      name,
+		Header:    fg(p.Accent).Bold(true).Padding(0, 1),
+		Title:     lipgloss.NewStyle().Bold(true),
+		Selection: fg(p.SelectionFg),
+		Active:    fg(p.Accent).Bold(true),
+		Box:       lipgloss.NewStyle().Border(lipgloss.RoundedBorder()),
+		Muted:     fg(p.Muted),
+		Info:      fg(p.Info),
+		Warning:   fg(p.Warning),
+		Critical:  fg(p.Critical),
+		OK:        fg(p.OK),
+	}
+	if p.SelectionBg != "" {
+		t.Selection = t.Selection.Background(lipgloss.Color(p.SelectionBg))
+	}
+	if p.Border != "" {
+		t.Box = t.Box.BorderForeground(lipgloss.Color(p.Border))
+	}
+	if name == "high-contrast" {
+		t.Header = t.Header.Underline(true)
+		t.Selection = t.Selection.Bold(true)
+	}
+	return t
+}
+
+// Mono is used when colour is unavailable or disabled. It relies on bold,
+// faint and reverse video only.
+func Mono() *Theme {
+	return &Theme{
+		Name:      "mono",
+		Header:    lipgloss.NewStyle().Bold(true).Underline(true).Padding(0, 1),
+		Title:     lipgloss.NewStyle().Bold(true),
+		Selection: lipgloss.NewStyle().Reverse(true),
+		Active:    lipgloss.NewStyle().Bold(true).Underline(true),
+		Box:       lipgloss.NewStyle().Border(lipgloss.RoundedBorder()),
+		Muted:     lipgloss.NewStyle().Faint(true),
+		Info:      lipgloss.NewStyle(),
+		Warning:   lipgloss.NewStyle().Bold(true),
+		Critical:  lipgloss.NewStyle().Bold(true).Underline(true),
+		OK:        lipgloss.NewStyle(),
+	}
+}
+
+// NoColor reports whether colours must not be used, either because NO_COLOR
+// is set (https://no-color.org) or the terminal cannot show them.
+func NoColor() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	return lipgloss.ColorProfile() == termenv.Ascii
+}
+
+// FromConfig builds the theme named in cfg, a built-in or a user-defined one,
+// with theme.colors applied on top. It falls back to Mono when NoColor.
+func FromConfig(cfg *config.Config) *Theme {
+	if NoColor() {
+		return Mono()
+	}
+
+	name := cfg.Theme.Name
+	palette, ok := builtins[name]
+	if custom, isCustom := cfg.Themes[name]; isCustom {
+		palette = override(builtins[custom.Base], custom.Colors)
+	} else if !ok {
+		palette = builtins["default"]
+	}
+	return New(name, override(palette, cfg.Theme.Colors))
+}
+
+// override returns p with every non-empty colour in c.
+func override(p, c config.Colors) config.Colors {
+	dst, src := reflect.ValueOf(&p).Elem(), reflect.ValueOf(c)
+	for i := 0; i < src.NumField(); i++ {
+		if color := src.Field(i).String(); color != "" {
+			dst.Field(i).SetString(color)
+		}
+	}
+	return p
+}
+
+var current = New("default", builtins["default"])
+
+// Current returns the theme views render with.
+func Current() *Theme {
+	return current
+}
+
+func Set(t *Theme) {
+	current = t
+}
@@ -1,17 +1,28 @@
+use std::collections::HashMap;
 use std::ffi::{CStr, CString};
 use std::os::raw::{c_char, c_int};
 use nom::bytes::complete::take_until;
 use nom::character::complete::{char, multispace0, space1};
+use nom::combinator::rest;
 use nom::sequence::preceded;
 use nom::IResult;
 use regex::Regex;
-use chrono::{DateTime, NaiveDateTime, Utc};
+use chrono::{Datelike, Local, NaiveDate, NaiveDateTime, TimeZone};
+
+// The formats and filter semantics are listed with the conformance tests in
+// internal/logparser/conformance_test.go, which run against this parser and
+// the pure-Go one in internal/logparser/parse.go. The Go parser implements
+// the same rules step by step.
 
 #[repr(C)]
 pub struct LogEntry {
     timestamp: *mut c_char,
     severity: *mut c_char,
     message: *mut c_char,
+    source: *mut c_char,
+    unit: *mut c_char,
+    pid: c_int,
+    offset: u64,
 }
 
 #[repr(C)]
@@ -29,7 +40,7 @@ fn parse_syslog_line(input: &str) -> IResult<&str, (&str, &str, &str)> {
     let (input, service) = take_until(":")(input)?;
     let (input, _) = char(':')(input)?;
     let (input, _) = multispace0(input)?;
-    let (input, message) = take_until("\n")(input)?;
+    let (input, message) = rest(input)?;
     Ok((input, (date_part, service, message)))
 }
 
@@ -43,135 +54,422 @@ fn parse_journalctl_line(input: &str) -> IResult<&str, (&str, &str, &str)> {
     let (input, severity) = take_until("]")(input)?;
     let (input, _) = char(']')(input)?;
     let (input, _) = multispace0(input)?;
-    let (input, message) = take_until("\n")(input)?;
+    let (input, message) = rest(input)?;
     Ok((input, (timestamp, severity, message)))
 }
 
-fn extract_severity(service: &str, message: &str) -> String {
-    let severity_patterns = vec![
+fn extract_severity(patterns: &[(&'static str, Regex)], service: &str, message: &str) -> &'static str {
+    for (severity, re) in patterns {
+        if re.is_match(message) || re.is_match(service) {
+            return *severity;
+        }
+    }
+    "INFO"
+}
+
+fn severity_patterns() -> Vec<(&'static str, Regex)> {
+    vec![
         ("ERROR", r"(?i)error|err|failed|failure"),
         ("WARN", r"(?i)warn|warning"),
         ("INFO", r"(?i)info|information"),
         ("DEBUG", r"(?i)debug|trace"),
-    ];
+    ]
+    .into_iter()
+    .map(|(severity, pattern)| (severity, Regex::new(pattern).unwrap()))
+    .collect()
+}
 
-    for (severity, pattern) in severity_patterns {
-        let re = Regex::new(pattern).unwrap();
-        if re.is_match(message) || re.is_match(service) {
-            return severity.to_string();
-        }
+/// Maps a journal PRIORITY to a severity, as the Go journal reader does.
+fn priority_severity(priority: i64) -> &'static str {
+    match priority {
+        p if p <= 3 => "ERROR",
+        4 => "WARN",
+        p if p <= 6 => "INFO",
+        _ => "DEBUG",
     }
-    "INFO".to_string()
 }
 
-#[no_mangle]
-pub extern "C" fn parse_logs(
-    log_path: *const c_char,
-    pattern: *const c_char,
-    start_date: *const c_char,
-    end_date: *const c_char,
-    severity_filter: *const c_char,
-) -> *mut LogResult {
-    let log_path_str = unsafe {
-        match CStr::from_ptr(log_path).to_str() {
-            Ok(s) => s,
-            Err(_) => return std::ptr::null_mut(),
-        }
-    };
+/// Reads an optional C string argument; null and empty strings are `None`.
+unsafe fn optional_str<'a>(ptr: *const c_char) -> Option<&'a str> {
+    if ptr.is_null() {
+        return None;
+    }
+    match CStr::from_ptr(ptr).to_str() {
+        Ok(s) => if s.is_empty() { None } else { Some(s) },
+        Err(_) => None,
+    }
+}
 
-    let pattern_str = unsafe {
-        match CStr::from_ptr(pattern).to_str() {
-            Ok(s) => if s.is_empty() { None } else { Some(s) },
-            Err(_) => None,
-        }
-    };
+/// Converts a field for C, dropping NUL bytes rather than failing on them.
+fn c_string(s: &str) -> *mut c_char {
+    let bytes: Vec<u8> = s.bytes().filter(|&b| b != 0).collect();
+    CString::new(bytes).unwrap().into_raw()
+}
 
-    let start_date_str = unsafe {
-        match CStr::from_ptr(start_date).to_str() {
-            Ok(s) => if s.is_empty() { None } else { Some(s) },
-            Err(_) => None,
-        }
-    };
+/// Splits a severity filter such as "error,warn" into its severities.
+fn severity_list(s: &str) -> Vec<String> {
+    s.split(',')
+        .map(str::trim)
+        .filter(|s| !s.is_empty())
+        .map(str::to_string)
+        .collect()
+}
 
-    let end_date_str = unsafe {
-        match CStr::from_ptr(end_date).to_str() {
-            Ok(s) => if s.is_empty() { None } else { Some(s) },
-            Err(_) => None,
-        }
-    };
+fn parse_date(d: &str) -> Option<NaiveDateTime> {
+    NaiveDateTime::parse_from_str(d, "%Y-%m-%d %H:%M:%S").ok()
+}
 
-    let severity_filter_str = unsafe {
-        match CStr::from_ptr(severity_filter).to_str() {
-            Ok(s) => if s.is_empty() { None } else { Some(s) },
-            Err(_) => None,
+const MONTHS: [&str; 12] = ["Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"];
+
+/// Reads one or more ASCII digits.
+fn number(b: &[u8]) -> Option<u32> {
+    if b.is_empty() || !b.iter().all(|c| c.is_ascii_digit()) {
+        return None;
+    }
+    std::str::from_utf8(b).ok()?.parse().ok()
+}
+
+/// Returns the local time, to the second, of a Unix time.
+fn local_time(secs: i64) -> Option<NaiveDateTime> {
+    Local.timestamp_opt(secs, 0).single().map(|t| t.naive_local())
+}
+
+/// Parses an RFC 3339 timestamp such as rsyslog and journalctl -o
+/// short-iso write, "YYYY-MM-DDTHH:MM:SS[.frac](Z|+HH:MM|+HHMM)", into
+/// local time. The fraction is dropped.
+fn parse_rfc3339(s: &str) -> Option<NaiveDateTime> {
+    let b = s.as_bytes();
+    if b.len() < 20 || b[4] != b'-' || b[7] != b'-' || b[10] != b'T' || b[13] != b':' || b[16] != b':' {
+        return None;
+    }
+    let date = NaiveDate::from_ymd_opt(number(&b[0..4])? as i32, number(&b[5..7])?, number(&b[8..10])?)?;
+    let time = date.and_hms_opt(number(&b[11..13])?, number(&b[14..16])?, number(&b[17..19])?)?;
+    let mut rest = &b[19..];
+    if rest[0] == b'.' {
+        let n = rest[1..].iter().take_while(|c| c.is_ascii_digit()).count();
+        if n == 0 {
+            return None;
         }
+        rest = &rest[1 + n..];
+    }
+    let offset = match rest {
+        b"Z" => 0,
+        [sign @ (b'+' | b'-'), h1, h2, b':', m1, m2] | [sign @ (b'+' | b'-'), h1, h2, m1, m2] => {
+            let (hours, minutes) = (number(&[*h1, *h2])?, number(&[*m1, *m2])?);
+            if hours > 23 || minutes > 59 {
+                return None;
+            }
+            let secs = i64::from(hours * 3600 + minutes * 60);
+            if *sign == b'-' { -secs } else { secs }
+        }
+        _ => return None,
     };
+    local_time(time.and_utc().timestamp() - offset)
+}
 
-    let content = match std::fs::read_to_string(log_path_str) {
-        Ok(c) => c,
-        Err(_) => return std::ptr::null_mut(),
-    };
+/// Parsed is an entry that passed the filter.
+struct Parsed {
+    timestamp: String,
+    severity: &'static str,
+    message: String,
+    source: String,
+    unit: String,
+    pid: i32,
+}
 
-    let regex = pattern_str.and_then(|p| Regex::new(p).ok());
+/// LogFilter holds the compiled filters of a query, so that a file read in
+/// many chunks compiles its regular expressions once.
+pub struct LogFilter {
+    regex: Option<Regex>,
+    start: Option<NaiveDateTime>,
+    end: Option<NaiveDateTime>,
+    severities: Vec<String>,
+    severity_patterns: Vec<(&'static str, Regex)>,
+    /// The year and month BSD syslog dates are taken to be at most.
+    year: i32,
+    month: u32,
+}
 
-    let start_date_parsed = start_date_str.and_then(|d| {
-        NaiveDateTime::parse_from_str(d, "%Y-%m-%d %H:%M:%S")
-            .ok()
-            .map(|ndt| DateTime::from_naive_utc_and_offset(ndt, Utc))
-    });
+impl LogFilter {
+    /// Reads the time at the start of a line: an RFC 3339 timestamp as the
+    /// first field, or a BSD syslog date, "Mon D HH:MM:SS", in the current
+    /// year unless the month is still to come.
+    fn line_time(&self, line: &str) -> Option<NaiveDateTime> {
+        let mut fields = line.split(|c| c == ' ' || c == '\t').filter(|f| !f.is_empty());
+        let first = fields.next()?;
+        if let Some(time) = parse_rfc3339(first) {
+            return Some(time);
+        }
+        let month = MONTHS.iter().position(|&m| m == first)? as u32 + 1;
+        let day = fields.next()?;
+        let time = fields.next()?.as_bytes();
+        if day.len() > 2 || time.len() != 8 || time[2] != b':' || time[5] != b':' {
+            return None;
+        }
+        let year = if month > self.month { self.year - 1 } else { self.year };
+        NaiveDate::from_ymd_opt(year, month, number(day.as_bytes())?)?
+            .and_hms_opt(number(&time[0..2])?, number(&time[3..5])?, number(&time[6..8])?)
+    }
 
-    let end_date_parsed = end_date_str.and_then(|d| {
-        NaiveDateTime::parse_from_str(d, "%Y-%m-%d %H:%M:%S")
-            .ok()
-            .map(|ndt| DateTime::from_naive_utc_and_offset(ndt, Utc))
-    });
+    /// Applies the pattern, severity and date filters to an entry whose
+    /// pattern and severity are matched against service and message and
+    /// whose time is read only when the date range applies.
+    fn keep(&self, when: impl FnOnce() -> Option<NaiveDateTime>, severity: &str, service: &str, message: &str) -> bool {
+        if let Some(ref re) = self.regex {
+            if !re.is_match(message) && !re.is_match(service) {
+                return false;
+            }
+        }
 
-    let mut entries = Vec::new();
+        if !self.severities.is_empty()
+            && !self.severities.iter().any(|s| severity.eq_ignore_ascii_case(s))
+        {
+            return false;
+        }
 
-    for line in content.lines() {
-        if line.trim().is_empty() {
-            continue;
+        if let (Some(start), Some(end)) = (self.start, self.end) {
+            match when() {
+                Some(t) if t >= start && t <= end => {}
+                _ => return false,
+            }
         }
+        true
+    }
 
+    fn parse_line(&self, line: &str) -> Option<Parsed> {
         let (timestamp, service, message) = match parse_syslog_line(line) {
             Ok((_, result)) => result,
             Err(_) => match parse_journalctl_line(line) {
                 Ok((_, result)) => result,
-                Err(_) => continue,
+                Err(_) => return None,
             },
         };
 
-        if let Some(ref re) = regex {
-            if !re.is_match(message) && !re.is_match(service) {
-                continue;
-            }
+        let severity = extract_severity(&self.severity_patterns, service, message);
+        if !self.keep(|| self.line_time(line), severity, service, message) {
+            return None;
         }
 
-        let severity = extract_severity(service, message);
+        Some(Parsed {
+            timestamp: timestamp.to_string(),
+            severity,
+            message: message.to_string(),
+            source: String::new(),
+            unit: String::new(),
+            pid: 0,
+        })
+    }
 
-        if let Some(ref filter) = severity_filter_str {
-            if !severity.eq_ignore_ascii_case(filter) {
-                continue;
+    /// Builds the entry of a journal export block, or returns `Err(())` when
+    /// the block has no valid __REALTIME_TIMESTAMP and so is not one.
+    fn parse_export(&self, fields: &HashMap<String, String>) -> Result<Option<Parsed>, ()> {
+        let realtime = fields.get("__REALTIME_TIMESTAMP").ok_or(())?;
+        if realtime.is_empty() || !realtime.bytes().all(|c| c.is_ascii_digit()) {
+            return Err(());
+        }
+        let micros: i64 = realtime.parse().map_err(|_| ())?;
+        if micros >= MAX_REALTIME {
+            return Err(());
+        }
+        let time = local_time(micros / 1_000_000).ok_or(())?;
+        let timestamp = time.format("%Y-%m-%d %H:%M:%S").to_string();
+
+        // The second field is used when the first is missing or empty.
+        let field = |first: &str, second: &str| -> String {
+            match fields.get(first).filter(|v| !v.is_empty()).or_else(|| fields.get(second)) {
+                Some(v) => v.clone(),
+                None => String::new(),
             }
+        };
+        let digits = |s: &str| !s.is_empty() && s.bytes().all(|c| c.is_ascii_digit());
+
+        let priority = field("PRIORITY", "PRIORITY");
+        let severity = if digits(&priority) {
+            priority_severity(priority.parse().unwrap_or(i64::MAX))
+        } else {
+            priority_severity(6)
+        };
+        let message = field("MESSAGE", "MESSAGE").trim_end_matches('\n').replace('\n', " ");
+        let source = field("SYSLOG_IDENTIFIER", "_COMM");
+        let unit = field("_SYSTEMD_UNIT", "UNIT");
+        let pid = field("_PID", "SYSLOG_PID");
+        let pid = if digits(&pid) { pid.parse().unwrap_or(0) } else { 0 };
+
+        if !self.keep(|| Some(time), severity, &source, &message) {
+            return Ok(None);
         }
+        Ok(Some(Parsed { timestamp, severity, message, source, unit, pid }))
+    }
+}
+
+/// Realtime timestamps from year 10000 on are not accepted.
+const MAX_REALTIME: i64 = 253_402_300_800_000_000;
+
+/// Reports whether name is a journal field name: capital letters, digits and
+/// underscores, not starting with a digit.
+fn field_name(name: &[u8]) -> bool {
+    !name.is_empty()
+        && !name[0].is_ascii_digit()
+        && name.iter().all(|&c| c.is_ascii_uppercase() || c.is_ascii_digit() || c == b'_')
+}
+
+/// Line is a line of a chunk together with its offset.
+struct Line<'a> {
+    offset: usize,
+    raw: &'a [u8],
+}
 
-        if let (Some(ref start), Some(ref end)) = (start_date_parsed, end_date_parsed) {
-            if let Ok(naive_dt) = NaiveDateTime::parse_from_str(timestamp, "%Y-%m-%d %H:%M:%S") {
-                let dt = DateTime::from_naive_utc_and_offset(naive_dt, Utc);
-                if dt < *start || dt > *end {
-                    continue;
+/// Block collects the consecutive field lines of a journal export entry.
+#[derive(Default)]
+struct Block<'a> {
+    lines: Vec<Line<'a>>,
+    fields: HashMap<String, String>,
+}
+
+struct Chunk<'a> {
+    filter: &'a LogFilter,
+    entries: Vec<LogEntry>,
+}
+
+impl<'a> Chunk<'a> {
+    fn push(&mut self, parsed: Parsed, offset: usize) {
+        self.entries.push(LogEntry {
+            timestamp: c_string(&parsed.timestamp),
+            severity: c_string(parsed.severity),
+            message: c_string(&parsed.message),
+            source: c_string(&parsed.source),
+            unit: c_string(&parsed.unit),
+            pid: parsed.pid,
+            offset: offset as u64,
+        });
+    }
+
+    fn line(&mut self, line: &Line) {
+        let text = String::from_utf8_lossy(line.raw);
+        let text = text.trim_end_matches('\r');
+        if text.trim().is_empty() {
+            return;
+        }
+        if let Some(parsed) = self.filter.parse_line(text) {
+            self.push(parsed, line.offset);
+        }
+    }
+
+    /// Emits a block as one entry, or when it is not an export entry parses
+    /// its lines one by one.
+    fn block(&mut self, block: Block) {
+        if block.lines.is_empty() {
+            return;
+        }
+        match self.filter.parse_export(&block.fields) {
+            Ok(Some(parsed)) => self.push(parsed, block.lines[0].offset),
+            Ok(None) => {}
+            Err(()) => {
+                for line in &block.lines {
+                    self.line(line);
                 }
             }
         }
+    }
+}
 
-        let entry = LogEntry {
-            timestamp: CString::new(timestamp).unwrap().into_raw(),
-            severity: CString::new(severity).unwrap().into_raw(),
-            message: CString::new(message).unwrap().into_raw(),
+/// Compiles the filters of a query. Returns null when the pattern is not a
+/// valid regular expression.
+#[no_mangle]
+pub extern "C" fn log_filter_new(
+    pattern: *const c_char,
+    start_date: *const c_char,
+    end_date: *const c_char,
+    severity_filter: *const c_char,
+) -> *mut LogFilter {
+    let regex = match unsafe { optional_str(pattern) } {
+        Some(p) => match Regex::new(p) {
+            Ok(re) => Some(re),
+            Err(_) => return std::ptr::null_mut(),
+        },
+        None => None,
+    };
+
+    let now = Local::now();
+    let filter = unsafe {
+        LogFilter {
+            regex,
+            start: optional_str(start_date).and_then(parse_date),
+            end: optional_str(end_date).and_then(parse_date),
+            severities: optional_str(severity_filter).map(severity_list).unwrap_or_default(),
+            severity_patterns: severity_patterns(),
+            year: now.year(),
+            month: now.month(),
+        }
+    };
+    Box::into_raw(Box::new(filter))
+}
+
+#[no_mangle]
+pub extern "C" fn log_filter_free(filter: *mut LogFilter) {
+    if !filter.is_null() {
+        unsafe {
+            let _ = Box::from_raw(filter);
+        }
+    }
+}
+
+/// Parses the lines in data[..len] and returns the entries that pass the
+/// filter, each with the offset of its line in data. The caller reads the
+/// file and passes it in chunks of whole lines, and of whole journal export
+/// entries, so memory use is bounded by the chunk size rather than the file
+/// size. Invalid UTF-8 is replaced instead of failing the chunk.
+#[no_mangle]
+pub extern "C" fn parse_log_chunk(filter: *const LogFilter, data: *const u8, len: usize) -> *mut LogResult {
+    if filter.is_null() || (data.is_null() && len > 0) {
+        return std::ptr::null_mut();
+    }
+    let filter = unsafe { &*filter };
+    let data = if len == 0 { &[][..] } else { unsafe { std::slice::from_raw_parts(data, len) } };
+
+    let mut chunk = Chunk { filter, entries: Vec::new() };
+    let mut block = Block::default();
+    let mut pos = 0usize;
+    while pos < data.len() {
+        let end = match data[pos..].iter().position(|&b| b == b'\n') {
+            Some(i) => pos + i,
+            None => data.len(),
         };
-        entries.push(entry);
+        let line = Line { offset: pos, raw: &data[pos..end] };
+        pos = end + 1;
+
+        // A field is NAME=value on one line, or NAME on its own line
+        // followed by the length of the value as 64-bit little endian, the
+        // value and a newline.
+        if let Some(eq) = line.raw.iter().position(|&b| b == b'=') {
+            if field_name(&line.raw[..eq]) {
+                let name = String::from_utf8_lossy(&line.raw[..eq]).into_owned();
+                let value = String::from_utf8_lossy(&line.raw[eq + 1..]).into_owned();
+                block.fields.insert(name, value);
+                block.lines.push(line);
+                continue;
+            }
+        } else if field_name(line.raw) && end < data.len() && data.len() - (end + 1) >= 8 {
+            let mut size = [0u8; 8];
+            size.copy_from_slice(&data[end + 1..end + 9]);
+            let size = u64::from_le_bytes(size);
+            let start = end + 9;
+            if size < (data.len() - start) as u64 && data[start + size as usize] == b'\n' {
+                let value = &data[start..start + size as usize];
+                let name = String::from_utf8_lossy(line.raw).into_owned();
+                block.fields.insert(name, String::from_utf8_lossy(value).into_owned());
+                block.lines.push(line);
+                pos = start + size as usize + 1;
+                continue;
+            }
+        }
+
+        chunk.block(std::mem::take(&mut block));
+        chunk.line(&line);
     }
+    chunk.block(block);
 
+    let entries = chunk.entries;
     let count = entries.len() as c_int;
     let entries_box = entries.into_boxed_slice();
     let entries_ptr = Box::into_raw(entries_box) as *mut LogEntry;
@@ -204,6 +502,12 @@ pub extern "C" fn free_log_result(result: *mut LogResult) {
                 if !entry.message.is_null() {
                     let _ = CString::from_raw(entry.message);
                 }
+                if !entry.source.is_null() {
+                    let _ = CString::from_raw(entry.source);
+                }
+                if !entry.unit.is_null() {
+                    let _ = CString::from_raw(entry.unit);
+                }
             }
             let _ = Box::from_raw(std::slice::from_raw_parts_mut(result_box.entries, result_box.count as usize) as *mut [LogEntry]);
         }